@@ -0,0 +1,137 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Grantee is the recipient of an ACL grant.
+type Grantee struct {
+	// Type is one of CanonicalUser, Group or AmazonCustomerByEmail.
+	Type         string
+	ID           string
+	DisplayName  string
+	URI          string
+	EmailAddress string
+}
+
+// Grant is a single ACL grant.
+type Grant struct {
+	Grantee    Grantee
+	Permission string
+}
+
+type objectACLClient interface {
+	GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error)
+	PutObjectAcl(ctx context.Context, params *s3.PutObjectAclInput, optFns ...func(*s3.Options)) (*s3.PutObjectAclOutput, error)
+}
+
+// GetObjectACLGrants returns the ACL grants of the object name.
+//
+// ACLs are disabled by default for new buckets and AWS recommends using
+// bucket policies instead. These methods exist for legacy systems that
+// still rely on per-object ACLs.
+func (f *S3FS) GetObjectACLGrants(ctx context.Context, name string) ([]Grant, error) {
+	out, err := f.getObjectACL(ctx, name)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "getacl",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	grants := make([]Grant, 0, len(out.Grants))
+	for _, g := range out.Grants {
+		grant := Grant{Permission: string(g.Permission)}
+		if g.Grantee != nil {
+			grant.Grantee = Grantee{
+				Type:         string(g.Grantee.Type),
+				ID:           derefString(g.Grantee.ID),
+				DisplayName:  derefString(g.Grantee.DisplayName),
+				URI:          derefString(g.Grantee.URI),
+				EmailAddress: derefString(g.Grantee.EmailAddress),
+			}
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// PutObjectACLGrants replaces the ACL grants of the object name. The owner
+// of the object is preserved.
+func (f *S3FS) PutObjectACLGrants(ctx context.Context, name string, grants []Grant) error {
+	if err := f.putObjectACL(ctx, name, grants); err != nil {
+		return &fs.PathError{
+			Op:   "putacl",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+func (f *S3FS) getObjectACL(ctx context.Context, name string) (*s3.GetObjectAclOutput, error) {
+	if !f.validPath(name) || name == "." {
+		return nil, fs.ErrInvalid
+	}
+
+	cl, ok := f.cl.(objectACLClient)
+	if !ok {
+		return nil, errUnsupported("GetObjectAcl")
+	}
+
+	out, err := call(f, ctx, cl.GetObjectAcl, &s3.GetObjectAclInput{
+		Bucket: &f.bucket,
+		Key:    ptr(f.key(name)),
+	})
+	if err != nil {
+		if f.isNotFoundErr(err) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (f *S3FS) putObjectACL(ctx context.Context, name string, grants []Grant) error {
+	current, err := f.getObjectACL(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	policy := &types.AccessControlPolicy{
+		Owner:  current.Owner,
+		Grants: make([]types.Grant, 0, len(grants)),
+	}
+	for _, g := range grants {
+		grantee := &types.Grantee{Type: types.Type(g.Grantee.Type)}
+		if g.Grantee.ID != "" {
+			grantee.ID = ptr(g.Grantee.ID)
+		}
+		if g.Grantee.DisplayName != "" {
+			grantee.DisplayName = ptr(g.Grantee.DisplayName)
+		}
+		if g.Grantee.URI != "" {
+			grantee.URI = ptr(g.Grantee.URI)
+		}
+		if g.Grantee.EmailAddress != "" {
+			grantee.EmailAddress = ptr(g.Grantee.EmailAddress)
+		}
+
+		policy.Grants = append(policy.Grants, types.Grant{
+			Grantee:    grantee,
+			Permission: types.Permission(g.Permission),
+		})
+	}
+
+	_, err = call(f, ctx, f.cl.(objectACLClient).PutObjectAcl, &s3.PutObjectAclInput{
+		Bucket:              &f.bucket,
+		Key:                 ptr(f.key(name)),
+		AccessControlPolicy: policy,
+	})
+	return err
+}
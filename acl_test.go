@@ -0,0 +1,215 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestGetObjectACLGrants(t *testing.T) {
+	cl := &aclClient{
+		memClient: newMemClient("file.txt"),
+		acls: map[string]*s3.GetObjectAclOutput{
+			"file.txt": {
+				Owner: &types.Owner{ID: ptr("owner")},
+				Grants: []types.Grant{
+					{
+						Grantee: &types.Grantee{
+							Type:        types.TypeCanonicalUser,
+							ID:          ptr("user"),
+							DisplayName: ptr("name"),
+						},
+						Permission: types.PermissionFullControl,
+					},
+					{
+						Grantee: &types.Grantee{
+							Type: types.TypeGroup,
+							URI:  ptr("http://acs.amazonaws.com/groups/global/AllUsers"),
+						},
+						Permission: types.PermissionRead,
+					},
+					{
+						Grantee: &types.Grantee{
+							Type:         types.TypeAmazonCustomerByEmail,
+							EmailAddress: ptr("user@example.com"),
+						},
+						Permission: types.PermissionWriteAcp,
+					},
+				},
+			},
+		},
+	}
+	fsys := s3fs.New(cl, "test")
+
+	grants, err := fsys.GetObjectACLGrants(context.Background(), "file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	expected := []s3fs.Grant{
+		{
+			Grantee:    s3fs.Grantee{Type: "CanonicalUser", ID: "user", DisplayName: "name"},
+			Permission: "FULL_CONTROL",
+		},
+		{
+			Grantee:    s3fs.Grantee{Type: "Group", URI: "http://acs.amazonaws.com/groups/global/AllUsers"},
+			Permission: "READ",
+		},
+		{
+			Grantee:    s3fs.Grantee{Type: "AmazonCustomerByEmail", EmailAddress: "user@example.com"},
+			Permission: "WRITE_ACP",
+		},
+	}
+
+	if !reflect.DeepEqual(expected, grants) {
+		t.Errorf("want %v; got %v", expected, grants)
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := fsys.GetObjectACLGrants(context.Background(), "missing.txt")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Op != "getacl" || pathErr.Path != "missing.txt" {
+			t.Errorf("want getacl PathError for missing.txt; got %#v", err)
+		}
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		_, err := s3fs.New(newMemClient("file.txt"), "test").
+			GetObjectACLGrants(context.Background(), "file.txt")
+		if !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("want %v; got %v", errors.ErrUnsupported, err)
+		}
+	})
+}
+
+func TestPutObjectACLGrants(t *testing.T) {
+	owner := &types.Owner{ID: ptr("owner"), DisplayName: ptr("owner-name")}
+	cl := &aclClient{
+		memClient: newMemClient("file.txt"),
+		acls: map[string]*s3.GetObjectAclOutput{
+			"file.txt": {
+				Owner: owner,
+				Grants: []types.Grant{
+					{
+						Grantee:    &types.Grantee{Type: types.TypeCanonicalUser, ID: ptr("old")},
+						Permission: types.PermissionRead,
+					},
+				},
+			},
+		},
+	}
+	fsys := s3fs.New(cl, "test")
+
+	err := fsys.PutObjectACLGrants(context.Background(), "file.txt", []s3fs.Grant{
+		{
+			Grantee:    s3fs.Grantee{Type: "CanonicalUser", ID: "user", DisplayName: "name"},
+			Permission: "FULL_CONTROL",
+		},
+		{
+			Grantee:    s3fs.Grantee{Type: "Group", URI: "http://acs.amazonaws.com/groups/global/AllUsers"},
+			Permission: "READ",
+		},
+		{
+			Grantee:    s3fs.Grantee{Type: "AmazonCustomerByEmail", EmailAddress: "user@example.com"},
+			Permission: "WRITE_ACP",
+		},
+	})
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(cl.puts) != 1 {
+		t.Fatalf("want 1 PutObjectAcl call; got %d", len(cl.puts))
+	}
+
+	in := cl.puts[0]
+	if aws.ToString(in.Bucket) != "test" || aws.ToString(in.Key) != "file.txt" {
+		t.Errorf("want test/file.txt; got %s/%s", aws.ToString(in.Bucket), aws.ToString(in.Key))
+	}
+
+	expected := &types.AccessControlPolicy{
+		Owner: owner,
+		Grants: []types.Grant{
+			{
+				Grantee: &types.Grantee{
+					Type:        types.TypeCanonicalUser,
+					ID:          ptr("user"),
+					DisplayName: ptr("name"),
+				},
+				Permission: types.PermissionFullControl,
+			},
+			{
+				Grantee: &types.Grantee{
+					Type: types.TypeGroup,
+					URI:  ptr("http://acs.amazonaws.com/groups/global/AllUsers"),
+				},
+				Permission: types.PermissionRead,
+			},
+			{
+				Grantee: &types.Grantee{
+					Type:         types.TypeAmazonCustomerByEmail,
+					EmailAddress: ptr("user@example.com"),
+				},
+				Permission: types.PermissionWriteAcp,
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, in.AccessControlPolicy) {
+		t.Errorf("want %v; got %v", expected, in.AccessControlPolicy)
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		err := fsys.PutObjectACLGrants(context.Background(), "missing.txt", nil)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Op != "putacl" || pathErr.Path != "missing.txt" {
+			t.Errorf("want putacl PathError for missing.txt; got %#v", err)
+		}
+
+		if len(cl.puts) != 1 {
+			t.Errorf("expected no PutObjectAcl call; got %d", len(cl.puts)-1)
+		}
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		err := s3fs.New(newMemClient("file.txt"), "test").
+			PutObjectACLGrants(context.Background(), "file.txt", nil)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("want %v; got %v", errors.ErrUnsupported, err)
+		}
+	})
+}
+
+type aclClient struct {
+	*memClient
+	acls map[string]*s3.GetObjectAclOutput
+	puts []*s3.PutObjectAclInput
+}
+
+func (c *aclClient) GetObjectAcl(ctx context.Context, in *s3.GetObjectAclInput, _ ...func(*s3.Options)) (*s3.GetObjectAclOutput, error) {
+	out, ok := c.acls[aws.ToString(in.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return out, nil
+}
+
+func (c *aclClient) PutObjectAcl(ctx context.Context, in *s3.PutObjectAclInput, _ ...func(*s3.Options)) (*s3.PutObjectAclOutput, error) {
+	c.puts = append(c.puts, in)
+	return &s3.PutObjectAclOutput{}, nil
+}
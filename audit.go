@@ -0,0 +1,256 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+var (
+	_ fs.FS         = (*AuditFS)(nil)
+	_ fs.StatFS     = (*AuditFS)(nil)
+	_ fs.ReadDirFS  = (*AuditFS)(nil)
+	_ fs.ReadFileFS = (*AuditFS)(nil)
+	_ ContextFS     = (*AuditFS)(nil)
+	_ WriteFS       = (*AuditFS)(nil)
+	_ DeleteFS      = (*AuditFS)(nil)
+)
+
+// Auditor records data access for compliance purposes. Methods are called
+// after an operation completes, so err reports its actual outcome.
+type Auditor interface {
+	AuditRead(ctx context.Context, user, key string, bytes int64, err error)
+	AuditWrite(ctx context.Context, user, key string, bytes int64, err error)
+	AuditDelete(ctx context.Context, user, key string, err error)
+}
+
+type ctxUserKey struct{}
+
+// WithUser returns a copy of ctx carrying the user reported to Auditor.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, ctxUserKey{}, user)
+}
+
+func userFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(ctxUserKey{}).(string)
+	return user
+}
+
+// AuditFS reports every file read, write and delete to an Auditor.
+//
+// A read is reported when the file is closed, with the number of bytes
+// read from it. Writes are reported once the object is uploaded. The
+// Auditor receives the S3 key of the file, including the prefix of inner.
+// The user is taken from the context set with WithContext; see WithUser.
+type AuditFS struct {
+	inner   *S3FS
+	auditor Auditor
+	ctx     context.Context
+}
+
+// NewAuditFS returns a new filesystem that audits access to inner.
+func NewAuditFS(inner *S3FS, auditor Auditor) *AuditFS {
+	return &AuditFS{
+		inner:   inner,
+		auditor: auditor,
+		ctx:     context.Background(),
+	}
+}
+
+// WithContext returns a shallow copy of a that uses ctx for S3 calls and
+// passes it to the Auditor.
+func (a *AuditFS) WithContext(ctx context.Context) *AuditFS {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	a2 := *a
+	a2.inner = a.inner.WithContext(ctx)
+	a2.ctx = ctx
+	return &a2
+}
+
+// OpenContext implements ContextFS.
+func (a *AuditFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	return a.WithContext(ctx).Open(name)
+}
+
+// Open implements fs.FS.
+func (a *AuditFS) Open(name string) (fs.File, error) {
+	f, err := a.inner.Open(name)
+	if err != nil {
+		a.auditor.AuditRead(a.ctx, userFromContext(a.ctx), a.inner.key(name), 0, err)
+		return nil, err
+	}
+
+	if _, ok := f.(fs.ReadDirFile); ok {
+		return f, nil
+	}
+
+	return &auditFile{
+		File: f,
+		fsys: a,
+		name: name,
+		key:  a.inner.key(name),
+	}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (a *AuditFS) ReadFile(name string) ([]byte, error) {
+	data, err := fs.ReadFile(a.inner, name)
+	a.auditor.AuditRead(a.ctx, userFromContext(a.ctx), a.inner.key(name), int64(len(data)), err)
+	return data, err
+}
+
+// WriteFile implements WriteFS.
+func (a *AuditFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	err := a.inner.WriteFile(name, data, perm)
+	a.auditor.AuditWrite(a.ctx, userFromContext(a.ctx), a.inner.key(name), int64(len(data)), err)
+	return err
+}
+
+// OpenForWrite implements WriteFS.
+func (a *AuditFS) OpenForWrite(name string) (io.WriteCloser, error) {
+	w, err := a.inner.OpenForWrite(name)
+	if err != nil {
+		a.auditor.AuditWrite(a.ctx, userFromContext(a.ctx), a.inner.key(name), 0, err)
+		return nil, err
+	}
+
+	return &auditWriter{
+		WriteCloser: w,
+		fsys:        a,
+		key:         a.inner.key(name),
+	}, nil
+}
+
+// Remove implements DeleteFS.
+func (a *AuditFS) Remove(name string) error {
+	err := a.inner.Remove(name)
+	a.auditor.AuditDelete(a.ctx, userFromContext(a.ctx), a.inner.key(name), err)
+	return err
+}
+
+// RemoveAll implements DeleteFS. It is reported as a single delete of name.
+func (a *AuditFS) RemoveAll(name string) error {
+	err := a.inner.RemoveAll(name)
+	a.auditor.AuditDelete(a.ctx, userFromContext(a.ctx), a.inner.key(name), err)
+	return err
+}
+
+// Stat implements fs.StatFS.
+func (a *AuditFS) Stat(name string) (fs.FileInfo, error) {
+	return a.inner.Stat(name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (a *AuditFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return a.inner.ReadDir(name)
+}
+
+// auditFile forwards the optional methods of the files of S3FS, so that
+// files opened through AuditFS can be used the same way.
+type auditFile struct {
+	fs.File
+	fsys   *AuditFS
+	name   string
+	key    string
+	n      int64
+	err    error
+	closed bool
+}
+
+func (f *auditFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.n += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) && f.err == nil {
+		f.err = err
+	}
+	return n, err
+}
+
+func (f *auditFile) ReadAt(p []byte, off int64) (int, error) {
+	ra, ok := f.File.(io.ReaderAt)
+	if !ok {
+		return 0, f.unsupported("readat")
+	}
+
+	n, err := ra.ReadAt(p, off)
+	f.n += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) && f.err == nil {
+		f.err = err
+	}
+	return n, err
+}
+
+func (f *auditFile) WriteTo(w io.Writer) (int64, error) {
+	wt, ok := f.File.(io.WriterTo)
+	if !ok {
+		// io.Copy would call WriteTo again.
+		return io.Copy(w, struct{ io.Reader }{f})
+	}
+
+	n, err := wt.WriteTo(w)
+	f.n += n
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return n, err
+}
+
+func (f *auditFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, f.unsupported("seek")
+	}
+	return s.Seek(offset, whence)
+}
+
+// ETag returns the ETag of the file, or "" if it is unknown.
+func (f *auditFile) ETag() string {
+	if e, ok := f.File.(interface{ ETag() string }); ok {
+		return e.ETag()
+	}
+	return ""
+}
+
+func (f *auditFile) unsupported(op string) error {
+	return &fs.PathError{
+		Op:   op,
+		Path: f.name,
+		Err:  errors.ErrUnsupported,
+	}
+}
+
+func (f *auditFile) Close() error {
+	err := f.File.Close()
+	if !f.closed {
+		f.closed = true
+		f.fsys.auditor.AuditRead(f.fsys.ctx, userFromContext(f.fsys.ctx), f.key, f.n, f.err)
+	}
+	return err
+}
+
+type auditWriter struct {
+	io.WriteCloser
+	fsys   *AuditFS
+	key    string
+	n      int64
+	closed bool
+}
+
+func (w *auditWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *auditWriter) Close() error {
+	err := w.WriteCloser.Close()
+	if !w.closed {
+		w.closed = true
+		w.fsys.auditor.AuditWrite(w.fsys.ctx, userFromContext(w.fsys.ctx), w.key, w.n, err)
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package s3fs_test
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestAuditFS(t *testing.T) {
+	var auditor recordingAuditor
+
+	afs := s3fs.NewAuditFS(s3fs.New(newMemClient("dir/file.txt"), "test"), &auditor).
+		WithContext(s3fs.WithUser(context.Background(), "alice"))
+
+	t.Run("open", func(t *testing.T) {
+		auditor.events = nil
+
+		f, err := afs.Open("dir/file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := io.ReadAll(f); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(auditor.events) != 0 {
+			t.Fatalf("expected no events before Close; got %v", auditor.events)
+		}
+
+		f.Close()
+
+		expected := auditEvent{user: "alice", key: "dir/file.txt", bytes: int64(len("content"))}
+		if len(auditor.events) != 1 || auditor.events[0] != expected {
+			t.Errorf("want [%v]; got %v", expected, auditor.events)
+		}
+	})
+
+	t.Run("readfile not exist", func(t *testing.T) {
+		auditor.events = nil
+
+		_, err := fs.ReadFile(afs, "not-exist")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if len(auditor.events) != 1 || auditor.events[0].err == nil {
+			t.Errorf("expected a single failed read event; got %v", auditor.events)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		auditor.events = nil
+
+		w, err := afs.OpenForWrite("new.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := io.WriteString(w, "data"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		expected := auditEvent{user: "alice", key: "new.txt", bytes: int64(len("data"))}
+		if len(auditor.events) != 1 || auditor.events[0] != expected {
+			t.Errorf("want [%v]; got %v", expected, auditor.events)
+		}
+	})
+}
+
+func TestAuditFSFile(t *testing.T) {
+	var auditor recordingAuditor
+
+	fsys, err := s3fs.New(newMemClient("dir/file.txt"), "test", s3fs.WithReadSeeker).Sub("dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	afs := s3fs.NewAuditFS(fsys.(*s3fs.S3FS), &auditor)
+
+	f, err := afs.Open("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if _, err := f.(io.Seeker).Seek(2, io.SeekStart); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	p := make([]byte, 2)
+	if _, err := f.(io.ReaderAt).ReadAt(p, 0); err != nil || string(p) != "co" {
+		t.Fatalf("expected ReadAt to read %q; got %q %v", "co", p, err)
+	}
+
+	var buf strings.Builder
+	if _, err := f.(io.WriterTo).WriteTo(&buf); err != nil || buf.String() != "ntent" {
+		t.Fatalf("expected WriteTo to write %q; got %q %v", "ntent", buf.String(), err)
+	}
+
+	if etag := f.(interface{ ETag() string }).ETag(); etag == "" {
+		t.Error("expected an ETag")
+	}
+
+	f.Close()
+
+	expected := auditEvent{key: "dir/file.txt", bytes: int64(len("content"))}
+	if len(auditor.events) != 1 || auditor.events[0] != expected {
+		t.Errorf("want [%v]; got %v", expected, auditor.events)
+	}
+
+	if err := afs.Remove("file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if key := auditor.events[len(auditor.events)-1].key; key != "dir/file.txt" {
+		t.Errorf("want key %q; got %q", "dir/file.txt", key)
+	}
+}
+
+type auditEvent struct {
+	user  string
+	key   string
+	bytes int64
+	err   error
+}
+
+type recordingAuditor struct {
+	events []auditEvent
+}
+
+func (a *recordingAuditor) AuditRead(ctx context.Context, user, key string, bytes int64, err error) {
+	a.events = append(a.events, auditEvent{user, key, bytes, err})
+}
+
+func (a *recordingAuditor) AuditWrite(ctx context.Context, user, key string, bytes int64, err error) {
+	a.events = append(a.events, auditEvent{user, key, bytes, err})
+}
+
+func (a *recordingAuditor) AuditDelete(ctx context.Context, user, key string, err error) {
+	a.events = append(a.events, auditEvent{user, key, 0, err})
+}
@@ -0,0 +1,95 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+// WithReadBandwidthLimit limits the rate at which the content of files is
+// read from S3 to bytesPerSecond, shared by all files opened with the fs.
+// Reads wait for their turn until the context of the fs is done.
+func WithReadBandwidthLimit(bytesPerSecond int64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.readLimit = newBandwidthLimiter(bytesPerSecond)
+	})
+}
+
+// WithWriteBandwidthLimit limits the rate at which files are uploaded to
+// S3 to bytesPerSecond, shared by all uploads of the fs, including the
+// parts of multipart uploads.
+func WithWriteBandwidthLimit(bytesPerSecond int64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.writeLimit = newBandwidthLimiter(bytesPerSecond)
+	})
+}
+
+// newBandwidthLimiter returns a limiter of bytesPerSecond that allows
+// bursts of a second.
+func newBandwidthLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := bytesPerSecond
+	if burst > math.MaxInt32 {
+		burst = math.MaxInt32
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+}
+
+// limitRead limits the rate at which body is read to the limit set with
+// WithReadBandwidthLimit.
+func (f *S3FS) limitRead(body io.ReadCloser) io.ReadCloser {
+	if f.readLimit == nil {
+		return body
+	}
+	return &limitedReadCloser{
+		limitedReader: limitedReader{r: body, ctx: f.ctx, lim: f.readLimit},
+		Closer:        body,
+	}
+}
+
+// limitWrite limits the rate at which body is sent to the limit set with
+// WithWriteBandwidthLimit. The returned reader can be rewound for retries.
+func (f *S3FS) limitWrite(ctx context.Context, body io.ReadSeeker) io.ReadSeeker {
+	if f.writeLimit == nil {
+		return body
+	}
+	return &limitedReadSeeker{
+		limitedReader: limitedReader{r: body, ctx: ctx, lim: f.writeLimit},
+		Seeker:        body,
+	}
+}
+
+type limitedReader struct {
+	r   io.Reader
+	ctx context.Context
+	lim *rate.Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	// WaitN fails for more bytes than the burst.
+	if burst := r.lim.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if err := r.lim.WaitN(r.ctx, n); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+type limitedReadCloser struct {
+	limitedReader
+	io.Closer
+}
+
+type limitedReadSeeker struct {
+	limitedReader
+	io.Seeker
+}
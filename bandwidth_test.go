@@ -0,0 +1,88 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestBandwidthLimit(t *testing.T) {
+	data := strings.Repeat("a", 300)
+
+	t.Run("read", func(t *testing.T) {
+		cl := newMemClient()
+		cl.objects["file.txt"] = data
+		fsys := s3fs.New(cl, "test", s3fs.WithReadBandwidthLimit(200))
+
+		start := time.Now()
+		got, err := fsys.ReadFile("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(got) != data {
+			t.Error("read data does not match")
+		}
+
+		// 200 bytes are available immediately, the rest takes 0.5s.
+		if d := time.Since(start); d < 400*time.Millisecond {
+			t.Errorf("expected the read to be throttled; took %v", d)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithWriteBandwidthLimit(200))
+
+		start := time.Now()
+		if err := fsys.WriteFile("file.txt", []byte(data), 0644); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if cl.objects["file.txt"] != data {
+			t.Error("written data does not match")
+		}
+
+		if d := time.Since(start); d < 400*time.Millisecond {
+			t.Errorf("expected the write to be throttled; took %v", d)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		cl := newMemClient()
+		cl.objects["file.txt"] = data
+		fsys := s3fs.New(cl, "test", s3fs.WithReadBandwidthLimit(10))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		f, err := fsys.WithContext(ctx).Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		start := time.Now()
+		if _, err := io.Copy(io.Discard, f); err == nil {
+			t.Error("expected an error")
+		}
+
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("expected the read to stop with the context; took %v", d)
+		}
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithWriteBandwidthLimit(0))
+
+		if err := fsys.WriteFile("file.txt", bytes.Repeat([]byte("a"), 1<<20), 0644); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+)
+
+// defaultBatchStatWorkers is the number of concurrent Stat calls made by
+// BatchStat when WithBatchStatWorkers is not used.
+const defaultBatchStatWorkers = 8
+
+// WithBatchStatWorkers sets the number of files BatchStat stats
+// concurrently. The default is 8.
+func WithBatchStatWorkers(n int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.batchStatWorkers = n
+	})
+}
+
+// BatchStat calls Stat for every path concurrently. The returned slices are
+// aligned with paths: infos[i] and errs[i] are the results of Stat(paths[i]).
+// A failure of one path does not stop the others.
+func (f *S3FS) BatchStat(ctx context.Context, paths []string) ([]fs.FileInfo, []error) {
+	fsys := f.WithContext(ctx)
+
+	workers := f.batchStatWorkers
+	if workers <= 0 {
+		workers = defaultBatchStatWorkers
+	}
+	workers = min(workers, len(paths))
+
+	var (
+		infos = make([]fs.FileInfo, len(paths))
+		errs  = make([]error, len(paths))
+		jobs  = make(chan int)
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				infos[i], errs[i] = fsys.Stat(paths[i])
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return infos, errs
+}
@@ -0,0 +1,55 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestBatchStat(t *testing.T) {
+	var (
+		files []string
+		paths []string
+	)
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("dir/%03d.txt", i)
+		if i%10 != 0 {
+			files = append(files, name)
+		}
+		paths = append(paths, name)
+	}
+
+	fsys := s3fs.New(newMemClient(files...), "test", s3fs.WithBatchStatWorkers(4))
+
+	infos, errs := fsys.BatchStat(context.Background(), paths)
+	if len(infos) != len(paths) || len(errs) != len(paths) {
+		t.Fatalf("expected %d results; got %d %d", len(paths), len(infos), len(errs))
+	}
+
+	for i, p := range paths {
+		if i%10 == 0 {
+			if !errors.Is(errs[i], fs.ErrNotExist) || infos[i] != nil {
+				t.Errorf("%s: want %v; got %v %v", p, fs.ErrNotExist, infos[i], errs[i])
+			}
+			continue
+		}
+
+		if errs[i] != nil || infos[i].Name() != fmt.Sprintf("%03d.txt", i) {
+			t.Errorf("%s: unexpected result %v %v", p, infos[i], errs[i])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, errs = fsys.BatchStat(ctx, paths[:3])
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v; got %v", context.Canceled, err)
+		}
+	}
+}
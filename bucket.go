@@ -0,0 +1,58 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type headBucketClient interface {
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+}
+
+// WithBucketValidation makes New check that the bucket exists and is
+// accessible with a HeadBucket call, instead of failing on first use with
+// a less obvious error.
+//
+// New has no error result, so if the check fails, the error is returned by
+// Err, and every operation of the filesystem fails with it without calling
+// S3. NewS3FSFromIAMRole returns the error instead.
+func WithBucketValidation() Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.validateBucket = true
+	})
+}
+
+// Err returns the error of the bucket validation enabled with
+// WithBucketValidation, or nil if the bucket exists or it was not
+// validated.
+func (f *S3FS) Err() error {
+	return f.err
+}
+
+// checkBucket calls HeadBucket if WithBucketValidation was used.
+func (f *S3FS) checkBucket(ctx context.Context) error {
+	if !f.validateBucket {
+		return nil
+	}
+
+	cl, ok := f.cl.(headBucketClient)
+	if !ok {
+		return errUnsupported("HeadBucket")
+	}
+
+	_, err := call(f, ctx, cl.HeadBucket, &s3.HeadBucketInput{
+		Bucket: &f.bucket,
+	})
+	switch {
+	case err == nil:
+		return nil
+	case f.isNotFoundErr(err):
+		return fmt.Errorf("s3fs: bucket %s does not exist: %w", f.bucket, fs.ErrNotExist)
+	case f.isPermissionErr(err):
+		return fmt.Errorf("s3fs: access to bucket %s denied: %w", f.bucket, fs.ErrPermission)
+	}
+	return fmt.Errorf("s3fs: bucket %s: %w", f.bucket, err)
+}
@@ -0,0 +1,111 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestNoSuchBucket(t *testing.T) {
+	fsys := s3fs.New(noBucketClient{}, "test")
+
+	if _, err := fsys.Stat("file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat: want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	if _, err := fsys.Open("file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open: want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	if _, err := fs.ReadDir(fsys, "."); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir: want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestWithBucketValidation(t *testing.T) {
+	t.Run("exists", func(t *testing.T) {
+		cl := &headBucketClient{memClient: newMemClient("file.txt"), exists: true}
+		fsys := s3fs.New(cl, "test", s3fs.WithBucketValidation())
+
+		if err := fsys.Err(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if cl.headBucket != 1 {
+			t.Errorf("expected 1 HeadBucket call; got %d", cl.headBucket)
+		}
+
+		if _, err := fs.ReadFile(fsys, "file.txt"); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+	})
+
+	t.Run("not exists", func(t *testing.T) {
+		cl := &headBucketClient{memClient: newMemClient("file.txt")}
+		fsys := s3fs.New(cl, "test", s3fs.WithBucketValidation())
+
+		if err := fsys.Err(); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		if _, err := fs.ReadFile(fsys, "file.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		if n := cl.count("GetObject"); n != 0 {
+			t.Errorf("expected no GetObject calls; got %d", n)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cl := &headBucketClient{memClient: newMemClient("file.txt")}
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.Err(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if cl.headBucket != 0 {
+			t.Errorf("expected no HeadBucket calls; got %d", cl.headBucket)
+		}
+	})
+}
+
+// noBucketClient fails every call like S3 does for a bucket that does not
+// exist.
+type noBucketClient struct{ s3fs.Client }
+
+func (noBucketClient) err() error {
+	return &smithy.GenericAPIError{Code: "NoSuchBucket", Message: "The specified bucket does not exist"}
+}
+
+func (c noBucketClient) GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, c.err()
+}
+
+func (c noBucketClient) HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, c.err()
+}
+
+func (c noBucketClient) ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return nil, c.err()
+}
+
+type headBucketClient struct {
+	*memClient
+	exists     bool
+	headBucket int
+}
+
+func (c *headBucketClient) HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	c.headBucket++
+	if !c.exists {
+		return nil, responseError(404)
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
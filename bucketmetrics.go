@@ -0,0 +1,202 @@
+package s3fs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// BucketMetrics holds aggregate storage metrics of a bucket.
+type BucketMetrics struct {
+	TotalObjects          int64
+	TotalBytes            int64
+	BytesByStorageClass   map[string]int64
+	ObjectsByStorageClass map[string]int64
+
+	// LastUpdated is the time the metrics were computed at. For inventory
+	// based metrics it is the creation time of the inventory report.
+	LastUpdated time.Time
+}
+
+// WithMetricsViaInventory makes ListBucketMetrics read an S3 Inventory
+// report instead of listing the whole bucket. key is the key of the
+// manifest.json file in manifestBucket. Only CSV inventory reports are
+// supported.
+func WithMetricsViaInventory(manifestBucket, key string) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.inventoryBucket = manifestBucket
+		fsys.inventoryKey = key
+	})
+}
+
+// ListBucketMetrics returns the number of objects and bytes stored in the
+// bucket, in total and per storage class. The prefix of f is ignored.
+//
+// By default it sums the sizes returned by ListObjectsV2, which requires one
+// request per 1000 objects. For large buckets configure an S3 Inventory
+// report and use WithMetricsViaInventory.
+func (f *S3FS) ListBucketMetrics(ctx context.Context) (*BucketMetrics, error) {
+	if f.inventoryKey != "" {
+		return f.inventoryMetrics(ctx)
+	}
+	return f.listMetrics(ctx)
+}
+
+func (f *S3FS) listMetrics(ctx context.Context) (*BucketMetrics, error) {
+	m := newBucketMetrics()
+
+	var token *string
+	for {
+		out, err := call(f, ctx, f.cl.ListObjectsV2, &s3.ListObjectsV2Input{
+			Bucket:            &f.bucket,
+			ContinuationToken: token,
+			MaxKeys:           f.maxKeys(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range out.Contents {
+			m.add(string(o.StorageClass), derefInt64(o.Size))
+		}
+
+		if out.IsTruncated == nil || !*out.IsTruncated || out.NextContinuationToken == nil {
+			break
+		}
+		token = out.NextContinuationToken
+	}
+
+	m.LastUpdated = time.Now()
+	return m, nil
+}
+
+type inventoryManifest struct {
+	CreationTimestamp string `json:"creationTimestamp"`
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	Files             []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+func (f *S3FS) inventoryMetrics(ctx context.Context) (*BucketMetrics, error) {
+	body, err := f.getInventoryObject(ctx, f.inventoryKey)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var manifest inventoryManifest
+	if err := json.NewDecoder(body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("s3fs: invalid inventory manifest: %w", err)
+	}
+
+	if !strings.EqualFold(manifest.FileFormat, "CSV") {
+		return nil, fmt.Errorf("s3fs: unsupported inventory format %q", manifest.FileFormat)
+	}
+
+	columns := make(map[string]int)
+	for i, c := range strings.Split(manifest.FileSchema, ",") {
+		columns[strings.TrimSpace(c)] = i
+	}
+
+	if _, ok := columns["Size"]; !ok {
+		return nil, errors.New("s3fs: inventory report does not include object sizes")
+	}
+
+	m := newBucketMetrics()
+	for _, file := range manifest.Files {
+		if err := f.readInventoryFile(ctx, file.Key, columns, m); err != nil {
+			return nil, err
+		}
+	}
+
+	if ms, err := strconv.ParseInt(manifest.CreationTimestamp, 10, 64); err == nil {
+		m.LastUpdated = time.UnixMilli(ms)
+	}
+
+	return m, nil
+}
+
+func (f *S3FS) readInventoryFile(ctx context.Context, key string, columns map[string]int, m *BucketMetrics) error {
+	body, err := f.getInventoryObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var r io.Reader = body
+	if strings.HasSuffix(key, ".gz") {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("s3fs: invalid inventory file %s: %w", key, err)
+		}
+
+		if column(record, "IsDeleteMarker") == "true" {
+			continue
+		}
+
+		size, _ := strconv.ParseInt(column(record, "Size"), 10, 64)
+		m.add(column(record, "StorageClass"), size)
+	}
+}
+
+func (f *S3FS) getInventoryObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := call(f, ctx, f.cl.GetObject, &s3.GetObjectInput{
+		Bucket: &f.inventoryBucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func newBucketMetrics() *BucketMetrics {
+	return &BucketMetrics{
+		BytesByStorageClass:   make(map[string]int64),
+		ObjectsByStorageClass: make(map[string]int64),
+	}
+}
+
+func (m *BucketMetrics) add(storageClass string, size int64) {
+	if storageClass == "" {
+		storageClass = string(types.ObjectStorageClassStandard)
+	}
+
+	m.TotalObjects++
+	m.TotalBytes += size
+	m.BytesByStorageClass[storageClass] += size
+	m.ObjectsByStorageClass[storageClass]++
+}
@@ -0,0 +1,93 @@
+package s3fs_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestListBucketMetrics(t *testing.T) {
+	t.Run("listing", func(t *testing.T) {
+		cl := newMemClient("a.txt", "dir/b.txt", "dir/sub/c.txt")
+
+		m, err := s3fs.New(cl, "test", s3fs.WithPageSize(1)).
+			ListBucketMetrics(context.Background())
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if m.TotalObjects != 3 {
+			t.Errorf("want %d objects; got %d", 3, m.TotalObjects)
+		}
+
+		if m.TotalBytes != 3*int64(len("content")) {
+			t.Errorf("want %d bytes; got %d", 3*len("content"), m.TotalBytes)
+		}
+
+		if m.ObjectsByStorageClass["STANDARD"] != 3 {
+			t.Errorf("want %d STANDARD objects; got %v", 3, m.ObjectsByStorageClass)
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var files []string
+		for i := 0; i < 5; i++ {
+			files = append(files, fmt.Sprintf("dir/%d.txt", i))
+		}
+		cl := newMemClient(append(files, "other.txt")...)
+
+		sub, err := s3fs.New(cl, "test", s3fs.WithPageSize(2)).Sub("dir")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		m, err := sub.(*s3fs.S3FS).ListBucketMetrics(context.Background())
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		// The metrics cover the whole bucket, not only the prefix.
+		if m.TotalObjects != 6 {
+			t.Errorf("want %d objects; got %d", 6, m.TotalObjects)
+		}
+
+		if n := cl.count("ListObjectsV2"); n != 3 {
+			t.Errorf("expected 3 pages; got %d ListObjectsV2 calls", n)
+		}
+	})
+
+	t.Run("inventory", func(t *testing.T) {
+		cl := newMemClient()
+		cl.objects["inventory/manifest.json"] = `{
+			"creationTimestamp": "1704067200000",
+			"fileFormat": "CSV",
+			"fileSchema": "Bucket, Key, Size, StorageClass, IsDeleteMarker",
+			"files": [{"key": "inventory/data.csv"}]
+		}`
+		cl.objects["inventory/data.csv"] = "test,a.txt,10,STANDARD,false\n" +
+			"test,b.txt,20,GLACIER,false\n" +
+			"test,c.txt,30,GLACIER,false\n" +
+			"test,c.txt,,,true\n"
+
+		m, err := s3fs.New(cl, "test", s3fs.WithMetricsViaInventory("inventory-bucket", "inventory/manifest.json")).
+			ListBucketMetrics(context.Background())
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		expected := &s3fs.BucketMetrics{
+			TotalObjects:          3,
+			TotalBytes:            60,
+			BytesByStorageClass:   map[string]int64{"STANDARD": 10, "GLACIER": 50},
+			ObjectsByStorageClass: map[string]int64{"STANDARD": 1, "GLACIER": 2},
+			LastUpdated:           time.UnixMilli(1704067200000),
+		}
+		if !reflect.DeepEqual(m, expected) {
+			t.Errorf("want %+v; got %+v", expected, m)
+		}
+	})
+}
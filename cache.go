@@ -0,0 +1,242 @@
+package s3fs
+
+import (
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithStatCache caches the results of Stat for ttl. The cache is also
+// used by Open and ReadDir to check whether a directory exists. At most
+// maxEntries results are kept; maxEntries <= 0 means no limit.
+//
+// Entries of files written or removed through the same S3FS, including
+// its copies returned by WithContext and Sub, are evicted immediately.
+// Changes made by other clients are only visible once the entry expires.
+// A ttl of zero disables the cache.
+func WithStatCache(ttl time.Duration, maxEntries int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		if ttl <= 0 {
+			fsys.statCache = nil
+			return
+		}
+		fsys.statCache = newTTLCache[fileInfo](ttl, maxEntries)
+	})
+}
+
+// WithDirCache caches the results of ReadDir for ttl. Reading a cached
+// directory does not make any S3 calls.
+//
+// Only complete listings are cached, so reading a directory in parts with
+// ReadDir(n) does not populate the cache. Listings of directories changed
+// through the same S3FS are evicted immediately. A ttl of zero disables
+// the cache.
+func WithDirCache(ttl time.Duration) Option {
+	return optionFunc(func(fsys *S3FS) {
+		if ttl <= 0 {
+			fsys.dirCache = nil
+			return
+		}
+		fsys.dirCache = newTTLCache[[]fs.DirEntry](ttl, 0)
+	})
+}
+
+// WithNegativeCache remembers for ttl that a file does not exist, so
+// repeated Stat and Open calls of a missing file fail with fs.ErrNotExist
+// without calling S3. Writing the file through the same S3FS evicts its
+// entry immediately. A ttl of zero disables the cache.
+func WithNegativeCache(ttl time.Duration) Option {
+	return optionFunc(func(fsys *S3FS) {
+		if ttl <= 0 {
+			fsys.negCache = nil
+			return
+		}
+		fsys.negCache = newTTLCache[struct{}](ttl, 0)
+	})
+}
+
+type cacheEntry[V any] struct {
+	v       V
+	expires time.Time
+}
+
+// ttlCache is a map safe for concurrent use whose entries expire after ttl.
+type ttlCache[V any] struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.RWMutex
+	entries map[string]*cacheEntry[V]
+}
+
+func newTTLCache[V any](ttl time.Duration, maxEntries int) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:     ttl,
+		max:     maxEntries,
+		entries: make(map[string]*cacheEntry[V]),
+	}
+}
+
+// fresh returns an empty cache with the same configuration as c, or nil
+// if c is nil.
+func (c *ttlCache[V]) fresh() *ttlCache[V] {
+	if c == nil {
+		return nil
+	}
+	return newTTLCache[V](c.ttl, c.max)
+}
+
+func (c *ttlCache[V]) get(key string) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.v, true
+}
+
+func (c *ttlCache[V]) put(key string, v V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && c.max > 0 && len(c.entries) >= c.max {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		// Still full, evict any entry.
+		for k := range c.entries {
+			if len(c.entries) < c.max {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = &cacheEntry[V]{v: v, expires: now.Add(c.ttl)}
+}
+
+func (c *ttlCache[V]) delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, k := range keys {
+		delete(c.entries, k)
+	}
+}
+
+func (c *ttlCache[V]) deletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+func (f *S3FS) cachedStat(name string) (fileInfo, bool) {
+	if f.statCache == nil {
+		return fileInfo{}, false
+	}
+
+	fi, ok := f.statCache.get(f.key(name))
+	fi.name = name
+	return fi, ok
+}
+
+func (f *S3FS) cacheStat(name string, fi fileInfo) {
+	if f.statCache != nil {
+		f.statCache.put(f.key(name), fi)
+	}
+}
+
+func (f *S3FS) cachedNotExist(name string) bool {
+	if f.negCache == nil {
+		return false
+	}
+
+	_, ok := f.negCache.get(f.key(name))
+	return ok
+}
+
+func (f *S3FS) cacheNotExist(name string) {
+	if f.negCache != nil {
+		f.negCache.put(f.key(name), struct{}{})
+	}
+}
+
+func (f *S3FS) cachedDir(name string) ([]fs.DirEntry, bool) {
+	if f.dirCache == nil {
+		return nil, false
+	}
+
+	des, ok := f.dirCache.get(f.dirPrefix(name))
+	if !ok {
+		return nil, false
+	}
+	return append([]fs.DirEntry{}, des...), true
+}
+
+func (f *S3FS) cacheDir(name string, des []fs.DirEntry) {
+	if f.dirCache != nil {
+		f.dirCache.put(f.dirPrefix(name), append([]fs.DirEntry{}, des...))
+	}
+}
+
+// invalidate evicts cached data about name and all of its parent
+// directories, whose existence and listings may depend on name. If tree
+// is true, everything under the directory name is evicted as well.
+func (f *S3FS) invalidate(name string, tree bool) {
+	var (
+		key      = f.key(name)
+		keys     = []string{key}
+		prefixes = []string{""}
+		dir      = key
+	)
+	for {
+		i := strings.LastIndex(dir, f.delim)
+		if i < 0 {
+			break
+		}
+		dir = dir[:i]
+		keys = append(keys, dir)
+		prefixes = append(prefixes, dir+f.delim)
+	}
+
+	if f.statCache != nil {
+		f.statCache.delete(keys...)
+		if tree {
+			f.statCache.deletePrefix(key + f.delim)
+		}
+	}
+
+	if f.negCache != nil {
+		f.negCache.delete(keys...)
+		if tree {
+			f.negCache.deletePrefix(key + f.delim)
+		}
+	}
+
+	if f.contentCache != nil {
+		f.contentCache.delete(key)
+		if tree {
+			f.contentCache.deletePrefix(key + f.delim)
+		}
+	}
+
+	if f.dirCache != nil {
+		f.dirCache.delete(prefixes...)
+		if tree {
+			f.dirCache.deletePrefix(key + f.delim)
+		}
+	}
+}
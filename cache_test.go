@@ -0,0 +1,207 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestStatCache(t *testing.T) {
+	cl := newMemClient("dir/file.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Minute, 10))
+
+	for i := 0; i < 3; i++ {
+		if _, err := fsys.Stat("dir/file.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		fi, err := fsys.Stat("dir")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if !fi.IsDir() || fi.Name() != "dir" {
+			t.Errorf("expected dir to be a directory; got %v", fi)
+		}
+	}
+
+	if n := cl.count("HeadObject"); n != 2 {
+		t.Errorf("expected 2 HeadObject calls; got %d", n)
+	}
+
+	if _, err := fsys.ReadDir("dir"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	// one call to check dir exists and one from ReadDir.
+	if n := cl.count("ListObjectsV2"); n != 2 {
+		t.Errorf("expected 2 ListObjectsV2 calls; got %d", n)
+	}
+
+	t.Run("invalidate", func(t *testing.T) {
+		if err := fsys.Remove("dir/file.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		for _, name := range []string{"dir/file.txt", "dir"} {
+			if _, err := fsys.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s: want %v; got %v", name, fs.ErrNotExist, err)
+			}
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		cl := newMemClient("file.txt")
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(10*time.Millisecond, 0))
+
+		fsys.Stat("file.txt")
+		time.Sleep(20 * time.Millisecond)
+		fsys.Stat("file.txt")
+
+		if n := cl.count("HeadObject"); n != 2 {
+			t.Errorf("expected 2 HeadObject calls; got %d", n)
+		}
+	})
+}
+
+func TestDirCache(t *testing.T) {
+	cl := newMemClient("dir/a.txt", "dir/b.txt", "dir/sub/c.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithDirCache(time.Minute))
+
+	t.Run("partial listing is not cached", func(t *testing.T) {
+		f, err := fsys.Open("dir")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		des, err := f.(fs.ReadDirFile).ReadDir(1)
+		if err != nil || len(des) != 1 {
+			t.Fatalf("expected 1 entry; got %d %v", len(des), err)
+		}
+	})
+
+	want, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(want) != 3 {
+		t.Fatalf("expected 3 entries; got %d", len(want))
+	}
+
+	calls := cl.count("ListObjectsV2") + cl.count("HeadObject")
+
+	got, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if n := cl.count("ListObjectsV2") + cl.count("HeadObject"); n != calls {
+		t.Errorf("expected no S3 calls; got %d", n-calls)
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	if err := fsys.WriteFile("dir/d.txt", []byte("data"), 0644); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	des, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(des) != 4 {
+		t.Errorf("expected the listing to be invalidated after write; got %d entries", len(des))
+	}
+}
+
+func TestFileContentCache(t *testing.T) {
+	cl := newMemClient("a.txt", "b.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithFileContentCache(10))
+
+	read := func(name string) string {
+		t.Helper()
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		return string(data)
+	}
+
+	read("a.txt")
+	if data := read("a.txt"); data != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	if n := cl.count("GetObject"); n != 1 {
+		t.Errorf("expected 1 GetObject call; got %d", n)
+	}
+
+	cl.objects["a.txt"] = "changed"
+
+	if data := read("a.txt"); data != "changed" {
+		t.Errorf("want %q; got %q", "changed", data)
+	}
+
+	if n := cl.count("GetObject"); n != 2 {
+		t.Errorf("expected the changed file to be downloaded; got %d GetObject calls", n)
+	}
+
+	// a.txt is evicted, because both files do not fit in the cache.
+	read("b.txt")
+	read("a.txt")
+
+	if n := cl.count("GetObject"); n != 4 {
+		t.Errorf("expected a.txt to be evicted; got %d GetObject calls", n)
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	cl := newMemClient()
+	fsys := s3fs.New(cl, "test", s3fs.WithNegativeCache(time.Minute))
+
+	for _, name := range []string{"dir", "dir/config.json"} {
+		for i := 0; i < 3; i++ {
+			if _, err := fsys.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("want %v; got %v", fs.ErrNotExist, err)
+			}
+
+			if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("want %v; got %v", fs.ErrNotExist, err)
+			}
+		}
+	}
+
+	if n := cl.count("HeadObject"); n != 2 {
+		t.Errorf("expected 2 HeadObject calls; got %d", n)
+	}
+
+	if n := cl.count("GetObject"); n != 0 {
+		t.Errorf("expected no GetObject calls; got %d", n)
+	}
+
+	if err := fsys.WriteFile("dir/config.json", []byte("{}"), 0644); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	for _, name := range []string{"dir", "dir/config.json"} {
+		if _, err := fsys.Stat(name); err != nil {
+			t.Errorf("%s: expected err to be nil after write; got %v", name, err)
+		}
+	}
+}
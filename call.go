@@ -0,0 +1,126 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// call calls the S3 operation fn with in. Every S3 call made by S3FS goes
+// through call, so that options such as WithRetry, WithRateLimit,
+// WithTracer, WithMetrics and WithLogger apply to all of them. optFns are
+// passed to fn.
+func call[I, O any](f *S3FS, ctx context.Context, fn func(context.Context, I, ...func(*s3.Options)) (O, error), in I, optFns ...func(*s3.Options)) (O, error) {
+	if f.err != nil {
+		var zero O
+		return zero, f.err
+	}
+
+	op, bucket, key := operation(in)
+
+	ctx, span := f.startSpan(ctx, op, bucket, key)
+	start := time.Now()
+
+	out, err := callWithRetry(f, ctx, fn, in, optFns, op, bucket, key)
+
+	d := time.Since(start)
+	f.metrics.RecordOperation(op, bucket, key, d, err)
+	f.logCall(ctx, op, bucket, key, d, err)
+	endSpan(span, err)
+	return out, err
+}
+
+// callWithRetry calls fn with in until it succeeds or the error is not
+// retryable.
+func callWithRetry[I, O any](f *S3FS, ctx context.Context, fn func(context.Context, I, ...func(*s3.Options)) (O, error), in I, optFns []func(*s3.Options), op, bucket, key string) (O, error) {
+	if f.retry.maxAttempts > 0 {
+		optFns = append(optFns[:len(optFns):len(optFns)], disableSDKRetries)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := f.limits.wait(ctx, in); err != nil {
+			var zero O
+			return zero, err
+		}
+
+		actx, cancel := f.withTimeout(ctx, op)
+		out, err := fn(actx, in, optFns...)
+		if cancel != nil {
+			if err == nil {
+				releaseOnClose(out, cancel)
+			} else {
+				cancel()
+			}
+		}
+
+		if err == nil || !f.retry.retryable(attempt, err) || !rewind(in) {
+			return out, err
+		}
+
+		f.logger.Log(ctx, LevelWarn, "s3fs: retrying S3 call",
+			"operation", op,
+			"bucket", bucket,
+			"key", key,
+			"attempt", attempt+1,
+			"error", err,
+		)
+
+		if err := f.retry.wait(ctx, attempt); err != nil {
+			var zero O
+			return zero, err
+		}
+	}
+}
+
+// disableSDKRetries turns off the retries of the SDK, which would
+// otherwise be multiplied by the ones of WithRetry.
+func disableSDKRetries(o *s3.Options) {
+	o.Retryer = aws.NopRetryer{}
+}
+
+// operation describes the S3 call made with in. It returns the name of the
+// operation, e.g. "GetObject", the bucket and the key of the object, or
+// the prefix for listings.
+func operation(in any) (op, bucket, key string) {
+	op = strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", in), "*s3."), "Input")
+
+	switch in := in.(type) {
+	case *s3.GetObjectInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.HeadObjectInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.PutObjectInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.CopyObjectInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.DeleteObjectInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.CreateMultipartUploadInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.UploadPartInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.CompleteMultipartUploadInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.AbortMultipartUploadInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.DeleteObjectsInput:
+		return op, derefString(in.Bucket), ""
+	case *s3.GetObjectAclInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.PutObjectAclInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.HeadBucketInput:
+		return op, derefString(in.Bucket), ""
+	case *s3.SelectObjectContentInput:
+		return op, derefString(in.Bucket), derefString(in.Key)
+	case *s3.ListObjectsV2Input:
+		return op, derefString(in.Bucket), derefString(in.Prefix)
+	case *s3.ListObjectVersionsInput:
+		return op, derefString(in.Bucket), derefString(in.Prefix)
+	}
+	return op, "", ""
+}
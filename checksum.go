@@ -0,0 +1,150 @@
+package s3fs
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrChecksumMismatch is wrapped by ChecksumMismatchError.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumMismatchError is returned when the content of a file does not
+// match the checksum stored by S3. See WithChecksumValidation.
+type ChecksumMismatchError struct {
+	Path      string
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return "s3fs: " + e.Algorithm + " checksum mismatch of " + e.Path + ": expected " + e.Expected + ", got " + e.Actual
+}
+
+func (e *ChecksumMismatchError) Unwrap() error { return ErrChecksumMismatch }
+
+// WithChecksumValidation makes files opened with Open, OpenVersion,
+// OpenIfModifiedSince and ReadFile verify their content against the
+// checksum S3 stores with the object, to detect corrupted data. The
+// CRC32C, CRC32, SHA256 and SHA1 checksums are supported.
+//
+// The checksum is verified once the whole file was read: the last Read
+// and Close return a ChecksumMismatchError if it does not match. Files
+// that are not read to the end, objects uploaded without a checksum and
+// objects uploaded with multipart uploads, which only have checksums of
+// their parts, are not verified.
+func WithChecksumValidation() Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.checksums = true
+	})
+}
+
+// checksumMode returns the ChecksumMode of GetObject requests.
+func (f *S3FS) checksumMode() types.ChecksumMode {
+	if !f.checksums {
+		return ""
+	}
+	return types.ChecksumModeEnabled
+}
+
+// checksumBody wraps the body of out to verify its checksum.
+func (f *S3FS) checksumBody(name string, out *s3.GetObjectOutput) io.ReadCloser {
+	if !f.checksums {
+		return out.Body
+	}
+
+	var (
+		algorithm string
+		expected  *string
+		h         hash.Hash
+	)
+	switch {
+	case out.ChecksumCRC32C != nil:
+		algorithm, expected, h = "CRC32C", out.ChecksumCRC32C, crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case out.ChecksumCRC32 != nil:
+		algorithm, expected, h = "CRC32", out.ChecksumCRC32, crc32.NewIEEE()
+	case out.ChecksumSHA256 != nil:
+		algorithm, expected, h = "SHA256", out.ChecksumSHA256, sha256.New()
+	case out.ChecksumSHA1 != nil:
+		algorithm, expected, h = "SHA1", out.ChecksumSHA1, sha1.New()
+	default:
+		return out.Body
+	}
+
+	// checksums of multipart uploads end with the number of parts.
+	if strings.Contains(*expected, "-") {
+		return out.Body
+	}
+
+	size := int64(-1)
+	if out.ContentLength != nil {
+		size = *out.ContentLength
+	}
+
+	return &checksumReader{
+		ReadCloser: out.Body,
+		name:       name,
+		algorithm:  algorithm,
+		expected:   *expected,
+		hash:       h,
+		size:       size,
+	}
+}
+
+type checksumReader struct {
+	io.ReadCloser
+	name      string
+	algorithm string
+	expected  string
+	hash      hash.Hash
+	size      int64
+	n         int64
+	done      bool
+	err       error
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	r.n += int64(n)
+
+	if !r.done && (r.n == r.size || (r.size < 0 && errors.Is(err, io.EOF))) {
+		r.done = true
+		r.err = r.verify()
+	}
+
+	if r.err != nil {
+		return n, r.err
+	}
+	return n, err
+}
+
+func (r *checksumReader) verify() error {
+	actual := base64.StdEncoding.EncodeToString(r.hash.Sum(nil))
+	if actual == r.expected {
+		return nil
+	}
+
+	return &ChecksumMismatchError{
+		Path:      r.name,
+		Algorithm: r.algorithm,
+		Expected:  r.expected,
+		Actual:    actual,
+	}
+}
+
+func (r *checksumReader) Close() error {
+	if err := r.ReadCloser.Close(); err != nil {
+		return err
+	}
+	return r.err
+}
@@ -0,0 +1,138 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWithChecksumValidation(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		cl := &checksumClient{memClient: newMemClient("file.txt")}
+		fsys := s3fs.New(cl, "test", s3fs.WithChecksumValidation())
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := io.ReadAll(f); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+
+		if _, err := fsys.ReadFile("file.txt"); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+
+		if !cl.enabled {
+			t.Error("expected checksum mode to be enabled")
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		cl := &checksumClient{memClient: newMemClient("file.txt"), corrupt: true}
+		fsys := s3fs.New(cl, "test", s3fs.WithChecksumValidation())
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := io.ReadAll(f); !errors.Is(err, s3fs.ErrChecksumMismatch) {
+			t.Errorf("Read: want %v; got %v", s3fs.ErrChecksumMismatch, err)
+		}
+
+		err = f.Close()
+		if !errors.Is(err, s3fs.ErrChecksumMismatch) {
+			t.Errorf("Close: want %v; got %v", s3fs.ErrChecksumMismatch, err)
+		}
+
+		var mismatch *s3fs.ChecksumMismatchError
+		if !errors.As(err, &mismatch) || mismatch.Algorithm != "CRC32C" || mismatch.Path != "file.txt" {
+			t.Errorf("unexpected error: %#v", err)
+		}
+
+		if _, err := fsys.ReadFile("file.txt"); !errors.Is(err, s3fs.ErrChecksumMismatch) {
+			t.Errorf("ReadFile: want %v; got %v", s3fs.ErrChecksumMismatch, err)
+		}
+	})
+
+	t.Run("not read to the end", func(t *testing.T) {
+		cl := &checksumClient{memClient: newMemClient("file.txt"), corrupt: true}
+		fsys := s3fs.New(cl, "test", s3fs.WithChecksumValidation())
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := f.Read(make([]byte, 2)); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cl := &checksumClient{memClient: newMemClient("file.txt"), corrupt: true}
+		fsys := s3fs.New(cl, "test")
+
+		if _, err := fsys.ReadFile("file.txt"); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+
+		if cl.enabled {
+			t.Error("expected checksum mode to be disabled")
+		}
+	})
+}
+
+// checksumClient returns the CRC32C checksum of objects if it is
+// requested. If corrupt is set, the content of the response does not
+// match it.
+type checksumClient struct {
+	*memClient
+	corrupt bool
+	enabled bool
+}
+
+func (c *checksumClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.ChecksumMode == types.ChecksumModeEnabled {
+		c.enabled = true
+
+		h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+		h.Write(data)
+		out.ChecksumCRC32C = ptr(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	}
+
+	if c.corrupt {
+		data[len(data)-1] ^= 1
+	}
+
+	out.Body = io.NopCloser(bytes.NewReader(data))
+	return out, nil
+}
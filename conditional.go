@@ -0,0 +1,71 @@
+package s3fs
+
+import (
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// OpenIfModifiedSince opens the named file only if it was modified after
+// since, like a conditional HTTP GET request. If the file was not
+// modified, it returns a nil file and false, without transferring the
+// content of the file. Otherwise it returns the file and true.
+//
+// Only files can be opened; a name of a directory fails with
+// fs.ErrNotExist.
+func (f *S3FS) OpenIfModifiedSince(name string, since time.Time) (fs.File, bool, error) {
+	if !f.validPath(name) || name == "." {
+		return nil, false, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	out, err := call(f, f.ctx, f.cl.GetObject, &s3.GetObjectInput{
+		Bucket:          &f.bucket,
+		Key:             ptr(f.key(name)),
+		IfModifiedSince: &since,
+		ChecksumMode:    f.checksumMode(),
+	})
+	if err != nil {
+		switch {
+		case hasStatusCode(err, http.StatusNotModified):
+			return nil, false, nil
+		case f.isNotFoundErr(err):
+			err = fs.ErrNotExist
+		case f.isPermissionErr(err):
+			err = fs.ErrPermission
+		}
+		return nil, false, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	file := &file{
+		fsys:       f,
+		name:       name,
+		ReadCloser: f.progressBody(f.limitRead(f.checksumBody(name, out)), out.ContentLength),
+		stat:       f.getStatFunc(name, *out),
+		size:       derefInt64(out.ContentLength),
+		eTag:       derefString(out.ETag),
+		modTime:    derefTime(out.LastModified),
+	}
+
+	if err := f.decompressFile(file, out.ContentEncoding); err != nil {
+		return nil, false, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if !f.readSeeker {
+		return fileNoSeek{file}, true, nil
+	}
+	return file, true, nil
+}
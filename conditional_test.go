@@ -0,0 +1,95 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestOpenIfModifiedSince(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cl := &modifiedClient{memClient: newMemClient("file.txt"), modTime: modTime}
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("modified", func(t *testing.T) {
+		f, ok, err := fsys.OpenIfModifiedSince("file.txt", modTime.Add(-time.Second))
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		if !ok {
+			t.Fatal("expected the file to be modified")
+		}
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != "content" {
+			t.Errorf("want %q; got %q", "content", data)
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if !fi.ModTime().Equal(modTime) {
+			t.Errorf("want %v; got %v", modTime, fi.ModTime())
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		f, ok, err := fsys.OpenIfModifiedSince("file.txt", modTime)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if ok || f != nil {
+			t.Errorf("expected no file; got %v %v", f, ok)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		if _, _, err := fsys.OpenIfModifiedSince("not-exist", modTime); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, _, err := fsys.OpenIfModifiedSince("/file.txt", modTime); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	})
+}
+
+// modifiedClient responds to conditional GetObject requests like S3 for
+// objects last modified at modTime.
+type modifiedClient struct {
+	*memClient
+	modTime time.Time
+}
+
+func (c *modifiedClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.IfModifiedSince != nil && !c.modTime.After(*in.IfModifiedSince) {
+		out.Body.Close()
+		return nil, responseError(http.StatusNotModified)
+	}
+
+	out.LastModified = &c.modTime
+	return out, nil
+}
@@ -0,0 +1,144 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of a S3FS created with NewFromConfig. It
+// can be shared by many filesystems, e.g. one per request, instead of
+// repeating the same options. Zero values use the defaults of the
+// corresponding options.
+type Config struct {
+	// Bucket is the name of the bucket. It is required.
+	Bucket string
+
+	// Prefix is the directory of the bucket the fs is rooted at; see
+	// WithPrefix.
+	Prefix string
+
+	// Delimiter is the delimiter used to simulate directories; see
+	// WithDelimiter. The default is "/".
+	Delimiter string
+
+	// PageSize is the maximum number of keys of a listing page; see
+	// WithPageSize. It must not be larger than 1000.
+	PageSize int
+
+	// ReadSeeker enables Seek; see WithReadSeeker.
+	ReadSeeker bool
+
+	// StatCacheTTL and StatCacheMaxEntries configure WithStatCache.
+	StatCacheTTL        time.Duration
+	StatCacheMaxEntries int
+
+	// DirCacheTTL configures WithDirCache.
+	DirCacheTTL time.Duration
+
+	// NegativeCacheTTL configures WithNegativeCache.
+	NegativeCacheTTL time.Duration
+
+	// RetryAttempts and RetryBaseDelay configure WithRetry. Calls are not
+	// retried if RetryAttempts is zero.
+	RetryAttempts  int
+	RetryBaseDelay time.Duration
+
+	// ListingConcurrency configures WithListingConcurrency.
+	ListingConcurrency int
+
+	// Options are applied after the fields above.
+	Options []Option
+}
+
+// NewFromConfig is like New, but it is configured with cfg. It returns an
+// error if a field of cfg is invalid, or if the bucket validation enabled
+// with WithBucketValidation fails.
+func NewFromConfig(cl Client, cfg Config) (*S3FS, error) {
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := New(cl, cfg.Bucket, opts...)
+	if err := fsys.Err(); err != nil {
+		return nil, err
+	}
+	return fsys, nil
+}
+
+func (cfg Config) options() ([]Option, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3fs: invalid config: empty Bucket")
+	}
+
+	for _, f := range []struct {
+		name  string
+		value int64
+	}{
+		{"PageSize", int64(cfg.PageSize)},
+		{"StatCacheTTL", int64(cfg.StatCacheTTL)},
+		{"StatCacheMaxEntries", int64(cfg.StatCacheMaxEntries)},
+		{"DirCacheTTL", int64(cfg.DirCacheTTL)},
+		{"NegativeCacheTTL", int64(cfg.NegativeCacheTTL)},
+		{"RetryAttempts", int64(cfg.RetryAttempts)},
+		{"RetryBaseDelay", int64(cfg.RetryBaseDelay)},
+		{"ListingConcurrency", int64(cfg.ListingConcurrency)},
+	} {
+		if f.value < 0 {
+			return nil, fmt.Errorf("s3fs: invalid config: negative %s", f.name)
+		}
+	}
+
+	if cfg.PageSize > 1000 {
+		return nil, fmt.Errorf("s3fs: invalid config: PageSize %d is larger than 1000", cfg.PageSize)
+	}
+
+	var opts []Option
+
+	delim := "/"
+	if cfg.Delimiter != "" {
+		delim = cfg.Delimiter
+		opts = append(opts, WithDelimiter(delim))
+	}
+
+	if cfg.Prefix != "" {
+		p := strings.TrimSuffix(cfg.Prefix, delim)
+		if !(&S3FS{delim: delim}).validPath(p) {
+			return nil, fmt.Errorf("s3fs: invalid config: invalid Prefix %s", strconv.Quote(cfg.Prefix))
+		}
+		opts = append(opts, WithPrefix(cfg.Prefix))
+	}
+
+	if cfg.PageSize > 0 {
+		opts = append(opts, WithPageSize(cfg.PageSize))
+	}
+
+	if cfg.ReadSeeker {
+		opts = append(opts, WithReadSeeker)
+	}
+
+	if cfg.StatCacheTTL > 0 {
+		opts = append(opts, WithStatCache(cfg.StatCacheTTL, cfg.StatCacheMaxEntries))
+	}
+
+	if cfg.DirCacheTTL > 0 {
+		opts = append(opts, WithDirCache(cfg.DirCacheTTL))
+	}
+
+	if cfg.NegativeCacheTTL > 0 {
+		opts = append(opts, WithNegativeCache(cfg.NegativeCacheTTL))
+	}
+
+	if cfg.RetryAttempts > 0 {
+		opts = append(opts, WithRetry(cfg.RetryAttempts, cfg.RetryBaseDelay))
+	}
+
+	if cfg.ListingConcurrency > 0 {
+		opts = append(opts, WithListingConcurrency(cfg.ListingConcurrency))
+	}
+
+	return append(opts, cfg.Options...), nil
+}
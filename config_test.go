@@ -0,0 +1,84 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	cl := newMemClient("staging/a.txt", "staging/dir/b.txt")
+
+	fsys, err := s3fs.NewFromConfig(cl, s3fs.Config{
+		Bucket:       "test",
+		Prefix:       "staging/",
+		PageSize:     1,
+		ReadSeeker:   true,
+		StatCacheTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	f, err := fsys.Open("a.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	if _, ok := f.(io.Seeker); !ok {
+		t.Error("expected the file to implement io.Seeker")
+	}
+
+	if _, err := fs.Stat(fsys, "dir/b.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	n := cl.count("HeadObject")
+	if _, err := fs.Stat(fsys, "dir/b.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if cl.count("HeadObject") != n {
+		t.Error("expected the stat cache to be used")
+	}
+
+	fixtures := []struct {
+		desc string
+		cfg  s3fs.Config
+		err  string
+	}{
+		{desc: "no bucket", cfg: s3fs.Config{}, err: "empty Bucket"},
+		{desc: "invalid prefix", cfg: s3fs.Config{Bucket: "test", Prefix: "../a"}, err: "invalid Prefix"},
+		{desc: "negative ttl", cfg: s3fs.Config{Bucket: "test", DirCacheTTL: -1}, err: "negative DirCacheTTL"},
+		{desc: "page size", cfg: s3fs.Config{Bucket: "test", PageSize: 1001}, err: "PageSize 1001"},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.desc, func(t *testing.T) {
+			fsys, err := s3fs.NewFromConfig(cl, f.cfg)
+			if err == nil || !strings.Contains(err.Error(), f.err) {
+				t.Errorf("expected an error containing %q; got %v", f.err, err)
+			}
+
+			if fsys != nil {
+				t.Error("expected fsys to be nil")
+			}
+		})
+	}
+
+	t.Run("bucket validation", func(t *testing.T) {
+		_, err := s3fs.NewFromConfig(&headBucketClient{memClient: cl}, s3fs.Config{
+			Bucket:  "test",
+			Options: []s3fs.Option{s3fs.WithBucketValidation()},
+		})
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected ErrNotExist; got %v", err)
+		}
+	})
+}
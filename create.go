@@ -0,0 +1,58 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// CreateFile writes data to the named file only if it does not exist yet.
+// If it exists, CreateFile fails with fs.ErrExist. When several callers
+// create the same file concurrently, only one of them succeeds, so it can
+// be used e.g. to elect a worker with a sentinel file.
+//
+// The object is written with a conditional PutObject request
+// (If-None-Match: *). S3 compatible stores that respond to it with 501 Not
+// Implemented are handled with a HeadObject call followed by PutObject,
+// which is not atomic: a file created between both calls is overwritten.
+// Stores that ignore the header always overwrite the file.
+func (f *S3FS) CreateFile(ctx context.Context, name string, data []byte) error {
+	if err := f.createFile(ctx, name, data); err != nil {
+		return &fs.PathError{
+			Op:   "create",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+func (f *S3FS) createFile(ctx context.Context, name string, data []byte) error {
+	err := f.putObject(ctx, name, data, ifNoneMatch)
+	switch {
+	case err == nil:
+		return nil
+	case hasStatusCode(err, http.StatusPreconditionFailed):
+		return fs.ErrExist
+	case !hasStatusCode(err, http.StatusNotImplemented):
+		return err
+	}
+
+	switch _, err := f.WithContext(ctx).statObject(name); {
+	case err == nil:
+		return fs.ErrExist
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	return f.putObject(ctx, name, data)
+}
+
+// ifNoneMatch makes PutObject fail with 412 Precondition Failed if the
+// object exists.
+func ifNoneMatch(o *s3.Options) {
+	o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue("If-None-Match", "*"))
+}
@@ -0,0 +1,125 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestCreateFile(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		cl := &conditionalPutClient{memClient: newMemClient("exists.txt"), supported: supported}
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.CreateFile(context.Background(), "new.txt", []byte("data")); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if cl.objects["new.txt"] != "data" {
+			t.Errorf("want %q; got %q", "data", cl.objects["new.txt"])
+		}
+
+		if err := fsys.CreateFile(context.Background(), "exists.txt", []byte("data")); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("want %v; got %v", fs.ErrExist, err)
+		}
+
+		if cl.objects["exists.txt"] != "content" {
+			t.Error("expected the existing file not to be overwritten")
+		}
+
+		if err := fsys.CreateFile(context.Background(), "/invalid", nil); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	}
+}
+
+func TestCreateFileConcurrent(t *testing.T) {
+	cl := &conditionalPutClient{memClient: newMemClient(), supported: true}
+	fsys := s3fs.New(cl, "test")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := fsys.CreateFile(context.Background(), "lock", nil)
+			if err != nil && !errors.Is(err, fs.ErrExist) {
+				t.Error("unexpected error: ", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				created++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("expected 1 worker to create the file; got %d", created)
+	}
+}
+
+// conditionalPutClient handles the If-None-Match header of PutObject
+// requests like S3, or responds with 501 if it's not supported.
+type conditionalPutClient struct {
+	*memClient
+	supported bool
+
+	putMu sync.Mutex
+}
+
+func (c *conditionalPutClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.putMu.Lock()
+	defer c.putMu.Unlock()
+
+	if header(optFns, "If-None-Match") == "*" {
+		if !c.supported {
+			return nil, responseError(http.StatusNotImplemented)
+		}
+
+		c.mu.Lock()
+		_, ok := c.objects[*in.Key]
+		c.mu.Unlock()
+		if ok {
+			return nil, responseError(http.StatusPreconditionFailed)
+		}
+	}
+	return c.memClient.PutObject(ctx, in, optFns...)
+}
+
+// header returns the value of the HTTP header key set by optFns.
+func header(optFns []func(*s3.Options), key string) string {
+	var o s3.Options
+	for _, fn := range optFns {
+		fn(&o)
+	}
+
+	stack := middleware.NewStack("test", smithyhttp.NewStackRequest)
+	for _, fn := range o.APIOptions {
+		if err := fn(stack); err != nil {
+			panic(err)
+		}
+	}
+
+	var value string
+	h := middleware.DecorateHandler(middleware.HandlerFunc(func(ctx context.Context, in any) (any, middleware.Metadata, error) {
+		value = in.(*smithyhttp.Request).Header.Get(key)
+		return nil, middleware.Metadata{}, nil
+	}), stack)
+	h.Handle(context.Background(), nil)
+	return value
+}
@@ -0,0 +1,109 @@
+package s3fs
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+var errDecompressed = errors.New("file is decompressed")
+
+// DecompressFunc returns a reader of the decompressed content of r.
+type DecompressFunc func(r io.Reader) (io.Reader, error)
+
+// WithDecompressContent decompresses the content of files stored with
+// Content-Encoding gzip when they are opened or read with ReadFile. Other
+// encodings, e.g. br or zstd, can be added with WithDecompressor; files
+// with an encoding that has no decompressor are read as they are stored.
+//
+// The size of a decompressed file is unknown, so Stat of the file returns
+// a size of -1, and Seek and ReadAt fail, because S3 can only read ranges
+// of the compressed content.
+func WithDecompressContent() Option {
+	return optionFunc(func(fsys *S3FS) {
+		if fsys.decompressors == nil {
+			fsys.decompressors = make(map[string]DecompressFunc)
+		}
+		if _, ok := fsys.decompressors["gzip"]; !ok {
+			fsys.decompressors["gzip"] = func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			}
+		}
+	})
+}
+
+// WithDecompressor decompresses files stored with the Content-Encoding
+// encoding using fn. It enables WithDecompressContent.
+func WithDecompressor(encoding string, fn DecompressFunc) Option {
+	return optionFunc(func(fsys *S3FS) {
+		WithDecompressContent().apply(fsys)
+		fsys.decompressors[strings.ToLower(encoding)] = fn
+	})
+}
+
+// decompressor returns the decompressor of the Content-Encoding encoding,
+// or nil if the content is read as it is.
+func (f *S3FS) decompressor(encoding *string) DecompressFunc {
+	if f.decompressors == nil || encoding == nil {
+		return nil
+	}
+	return f.decompressors[strings.ToLower(strings.TrimSpace(*encoding))]
+}
+
+// decompressFile replaces the content of file with its decompressed
+// content if f has a decompressor for encoding.
+func (f *S3FS) decompressFile(file *file, encoding *string) error {
+	fn := f.decompressor(encoding)
+	if fn == nil {
+		return nil
+	}
+
+	body, err := decompress(fn, file.ReadCloser)
+	if err != nil {
+		return err
+	}
+
+	stat := file.stat
+	file.ReadCloser = body
+	file.size = -1
+	file.decompressed = true
+	file.stat = func() (fs.FileInfo, error) {
+		fi, err := stat()
+		if fi, ok := fi.(*fileInfo); ok && err == nil {
+			fi2 := *fi
+			fi2.size = -1
+			return &fi2, nil
+		}
+		return fi, err
+	}
+	return nil
+}
+
+// decompress returns the decompressed content of body. Closing it closes
+// body. body is closed if fn fails.
+func decompress(fn DecompressFunc, body io.ReadCloser) (io.ReadCloser, error) {
+	r, err := fn(body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &decompressReader{Reader: r, body: body}, nil
+}
+
+type decompressReader struct {
+	io.Reader
+	body io.Closer
+}
+
+func (r *decompressReader) Close() error {
+	var err error
+	if c, ok := r.Reader.(io.Closer); ok {
+		err = c.Close()
+	}
+	if cerr := r.body.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
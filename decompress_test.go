@@ -0,0 +1,152 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestDecompressContent(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("decompressed content"))
+	zw.Close()
+
+	cl := &encodingClient{
+		memClient: newMemClient(),
+		encodings: map[string]string{
+			"a.txt.gz":  "gzip",
+			"b.txt.rev": "rev",
+			"c.txt.br":  "br",
+		},
+	}
+	cl.objects["a.txt.gz"] = buf.String()
+	cl.objects["b.txt.rev"] = "tnetnoc"
+	cl.objects["c.txt.br"] = "brotli"
+
+	reverse := func(r io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		return bytes.NewReader(data), nil
+	}
+
+	fsys := s3fs.New(cl, "test",
+		s3fs.WithReadSeeker,
+		s3fs.WithDecompressor("rev", reverse),
+		s3fs.WithDecompressContent(),
+	)
+
+	fixtures := []struct {
+		name string
+		want string
+	}{
+		{name: "a.txt.gz", want: "decompressed content"},
+		{name: "b.txt.rev", want: "content"},
+		{name: "c.txt.br", want: "brotli"},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.name, func(t *testing.T) {
+			data, err := fsys.ReadFile(f.name)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if string(data) != f.want {
+				t.Errorf("want %q; got %q", f.want, data)
+			}
+
+			file, err := fsys.Open(f.name)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+			defer file.Close()
+
+			data, err = io.ReadAll(file)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if string(data) != f.want {
+				t.Errorf("want %q; got %q", f.want, data)
+			}
+
+			fi, err := file.Stat()
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			_, seekErr := file.(io.Seeker).Seek(0, io.SeekStart)
+
+			if f.name == "c.txt.br" {
+				// no decompressor for br; the file is read as it is.
+				if fi.Size() != int64(len(f.want)) || seekErr != nil {
+					t.Errorf("expected size %d and no seek error; got %d %v", len(f.want), fi.Size(), seekErr)
+				}
+				return
+			}
+
+			if fi.Size() != -1 {
+				t.Errorf("expected size -1; got %d", fi.Size())
+			}
+
+			if seekErr == nil {
+				t.Error("expected Seek to fail")
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		data, err := fs.ReadFile(s3fs.New(cl, "test"), "a.txt.gz")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != buf.String() {
+			t.Errorf("expected the compressed content; got %q", data)
+		}
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		cl.objects["a.txt.gz"] = "not gzip"
+		defer func() { cl.objects["a.txt.gz"] = buf.String() }()
+
+		if _, err := fsys.Open("a.txt.gz"); err == nil {
+			t.Error("expected an error")
+		}
+
+		if _, err := fsys.ReadFile("a.txt.gz"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+// encodingClient sets the Content-Encoding of objects.
+type encodingClient struct {
+	*memClient
+	encodings map[string]string
+}
+
+func (c *encodingClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if enc, ok := c.encodings[aws.ToString(in.Key)]; ok {
+		out.ContentEncoding = &enc
+	}
+	return out, nil
+}
@@ -0,0 +1,293 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+var _ DeleteFS = (*S3FS)(nil)
+
+// maxDeleteObjects is the maximum number of keys accepted by a single
+// DeleteObjects call.
+const maxDeleteObjects = 1000
+
+// defaultDeleteWorkers is the number of concurrent DeleteObjects calls
+// made by BulkDelete when WithDeleteWorkers is not used.
+const defaultDeleteWorkers = 4
+
+// DeleteFS is a filesystem that can remove files.
+type DeleteFS interface {
+	fs.FS
+
+	// Remove removes the named file.
+	Remove(name string) error
+
+	// RemoveAll removes name and everything it contains.
+	RemoveAll(name string) error
+}
+
+type deleteObjectClient interface {
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+type deleteObjectsClient interface {
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// WithDeleteWorkers sets the number of concurrent DeleteObjects calls made
+// by BulkDelete and RemoveAll. The default is 4.
+func WithDeleteWorkers(n int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.deleteWorkers = n
+	})
+}
+
+// BulkDeleteError is a file that BulkDelete could not delete.
+type BulkDeleteError struct {
+	Path string
+	Err  error
+}
+
+func (e BulkDeleteError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e BulkDeleteError) Unwrap() error { return e.Err }
+
+// Remove implements DeleteFS by calling DeleteObject.
+//
+// AWS S3 does not fail when deleting a key that does not exist, so
+// fs.ErrNotExist is only returned by servers that report it.
+func (f *S3FS) Remove(name string) error {
+	if err := f.deleteObject(f.ctx, name); err != nil {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// RemoveAll implements DeleteFS. It removes the file name and every file
+// in the directory name, deleting them with DeleteObjects in batches of
+// 1000 keys. The batches are deleted concurrently while the directory is
+// listed; see WithDeleteWorkers. If some of the keys could not be deleted,
+// the returned error wraps an error for each one of them.
+//
+// RemoveAll returns nil if name does not exist.
+func (f *S3FS) RemoveAll(name string) error {
+	if err := f.removeAll(f.ctx, name); err != nil {
+		return &fs.PathError{
+			Op:   "removeall",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// BulkDelete deletes the named files with DeleteObjects in batches of
+// 1000 keys. The batches are deleted concurrently; see WithDeleteWorkers.
+//
+// A failure does not stop the other deletions. BulkDelete returns an entry
+// for every file that could not be deleted, and an error joining all of
+// them.
+func (f *S3FS) BulkDelete(ctx context.Context, paths []string) ([]BulkDeleteError, error) {
+	if _, ok := f.cl.(deleteObjectsClient); !ok {
+		return nil, errUnsupported("DeleteObjects")
+	}
+
+	var (
+		failed  []BulkDeleteError
+		batches = make(chan []types.ObjectIdentifier, len(paths)/maxDeleteObjects+1)
+		batch   []types.ObjectIdentifier
+	)
+	for _, name := range paths {
+		if !f.validPath(name) || name == "." {
+			failed = append(failed, BulkDeleteError{Path: name, Err: fs.ErrInvalid})
+			continue
+		}
+
+		batch = append(batch, types.ObjectIdentifier{Key: ptr(f.key(name))})
+		if len(batch) == maxDeleteObjects {
+			batches <- batch
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		batches <- batch
+	}
+	close(batches)
+
+	f.deleteBatches(ctx, batches, func(batch []types.ObjectIdentifier, errs []types.Error, err error) {
+		if err != nil {
+			for _, o := range batch {
+				failed = append(failed, BulkDeleteError{
+					Path: strings.TrimPrefix(derefString(o.Key), f.prefix),
+					Err:  err,
+				})
+			}
+			return
+		}
+
+		for _, e := range errs {
+			failed = append(failed, BulkDeleteError{
+				Path: strings.TrimPrefix(derefString(e.Key), f.prefix),
+				Err: &smithy.GenericAPIError{
+					Code:    derefString(e.Code),
+					Message: derefString(e.Message),
+				},
+			})
+		}
+	})
+
+	for _, name := range paths {
+		if f.validPath(name) && name != "." {
+			f.invalidate(name, false)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil, nil
+	}
+
+	errs := make([]error, len(failed))
+	for i, e := range failed {
+		errs[i] = e
+	}
+	return failed, errors.Join(errs...)
+}
+
+// deleteBatches deletes the batches received from batches with
+// f.deleteWorkers concurrent DeleteObjects calls, until batches is closed.
+// fn is called with the result of every batch: the keys that could not be
+// deleted, or the error of the call. Calls of fn are serialized.
+func (f *S3FS) deleteBatches(ctx context.Context, batches <-chan []types.ObjectIdentifier, fn func(batch []types.ObjectIdentifier, failed []types.Error, err error)) {
+	workers := f.deleteWorkers
+	if workers <= 0 {
+		workers = defaultDeleteWorkers
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				failed, err := f.deleteObjects(ctx, batch)
+
+				mu.Lock()
+				fn(batch, failed, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (f *S3FS) deleteObject(ctx context.Context, name string) error {
+	if !f.validPath(name) || name == "." {
+		return fs.ErrInvalid
+	}
+
+	cl, ok := f.cl.(deleteObjectClient)
+	if !ok {
+		return errUnsupported("DeleteObject")
+	}
+
+	defer f.invalidate(name, false)
+
+	_, err := call(f, ctx, cl.DeleteObject, &s3.DeleteObjectInput{
+		Bucket: &f.bucket,
+		Key:    ptr(f.key(name)),
+	})
+	if f.isNotFoundErr(err) {
+		return fs.ErrNotExist
+	}
+	return err
+}
+
+func (f *S3FS) removeAll(ctx context.Context, name string) error {
+	if !f.validPath(name) || name == "." {
+		return fs.ErrInvalid
+	}
+
+	if _, ok := f.cl.(deleteObjectsClient); !ok {
+		return errUnsupported("DeleteObjects")
+	}
+
+	defer f.invalidate(name, true)
+
+	var (
+		errs    []error
+		batches = make(chan []types.ObjectIdentifier)
+		done    = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		f.deleteBatches(ctx, batches, func(_ []types.ObjectIdentifier, failed []types.Error, err error) {
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			for _, e := range failed {
+				errs = append(errs, f.deleteError(e))
+			}
+		})
+	}()
+
+	batch := []types.ObjectIdentifier{{Key: ptr(f.key(name))}}
+	err := f.listAll(ctx, name+f.delim, func(_ string, o types.Object) error {
+		batch = append(batch, types.ObjectIdentifier{Key: o.Key})
+		if len(batch) == maxDeleteObjects {
+			batches <- batch
+			batch = nil
+		}
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		batches <- batch
+	}
+	close(batches)
+	<-done
+
+	if err != nil {
+		errs = append([]error{err}, errs...)
+	}
+	return errors.Join(errs...)
+}
+
+// deleteObjects deletes objects with a single DeleteObjects call and
+// returns the keys that could not be deleted.
+func (f *S3FS) deleteObjects(ctx context.Context, objects []types.ObjectIdentifier) ([]types.Error, error) {
+	out, err := call(f, ctx, f.cl.(deleteObjectsClient).DeleteObjects, &s3.DeleteObjectsInput{
+		Bucket: &f.bucket,
+		Delete: &types.Delete{
+			Objects: objects,
+			Quiet:   ptr(true),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Errors, nil
+}
+
+func (f *S3FS) deleteError(e types.Error) error {
+	return fmt.Errorf("%s: %s: %s",
+		strings.TrimPrefix(derefString(e.Key), f.prefix), derefString(e.Code), derefString(e.Message))
+}
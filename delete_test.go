@@ -0,0 +1,234 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestRemove(t *testing.T) {
+	cl := newMemClient("dir/a.txt", "dir/b.txt")
+	fsys := s3fs.New(cl, "test")
+
+	if err := fsys.Remove("dir/a.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if _, err := fs.Stat(fsys, "dir/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	if _, err := fs.Stat(fsys, "dir/b.txt"); err != nil {
+		t.Errorf("expected dir/b.txt to exist; got %v", err)
+	}
+
+	if err := fsys.Remove("."); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	files := []string{"dir", "dir.txt", "other/file.txt"}
+	for i := 0; i < 2500; i++ {
+		files = append(files, fmt.Sprintf("dir/sub/%04d.txt", i))
+	}
+
+	cl := newMemClient(files...)
+	fsys := s3fs.New(cl, "test")
+
+	if err := fsys.RemoveAll("dir"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if n := cl.count("DeleteObjects"); n != 3 {
+		t.Errorf("expected 3 DeleteObjects calls; got %d", n)
+	}
+
+	if len(cl.objects) != 2 || cl.objects["dir.txt"] == "" || cl.objects["other/file.txt"] == "" {
+		t.Errorf("expected dir.txt and other/file.txt to remain; got %d objects", len(cl.objects))
+	}
+
+	if err := fsys.RemoveAll("not-exist"); err != nil {
+		t.Errorf("expected err to be nil; got %v", err)
+	}
+
+	t.Run("workers", func(t *testing.T) {
+		var files []string
+		for i := 0; i < 4000; i++ {
+			files = append(files, fmt.Sprintf("dir/%04d.txt", i))
+		}
+
+		cl := &concurrentDeleteClient{memClient: newMemClient(files...), wait: 2}
+		fsys := s3fs.New(cl, "test", s3fs.WithDeleteWorkers(2))
+
+		if err := fsys.RemoveAll("dir"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(cl.objects) != 0 {
+			t.Errorf("expected all objects to be deleted; got %d", len(cl.objects))
+		}
+
+		if cl.max != 2 {
+			t.Errorf("expected 2 concurrent DeleteObjects calls; got %d", cl.max)
+		}
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		cl := &failingDeleteClient{memClient: newMemClient("dir/a.txt", "dir/b.txt", "dir/c.txt")}
+		fsys := s3fs.New(cl, "test")
+
+		err := fsys.RemoveAll("dir")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		for _, key := range []string{"dir/a.txt", "dir/c.txt"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("expected %q to be reported; got %v", key, err)
+			}
+		}
+
+		if _, ok := cl.objects["dir/b.txt"]; ok {
+			t.Error("expected dir/b.txt to be deleted")
+		}
+	})
+}
+
+func TestBulkDelete(t *testing.T) {
+	var (
+		files []string
+		paths []string
+	)
+	for i := 0; i < 2500; i++ {
+		files = append(files, fmt.Sprintf("dir/%04d.txt", i))
+		if i%10 != 0 {
+			paths = append(paths, files[i])
+		}
+	}
+
+	cl := newMemClient(files...)
+	fsys := s3fs.New(cl, "test", s3fs.WithDeleteWorkers(2))
+
+	failed, err := fsys.BulkDelete(context.Background(), paths)
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("expected no errors; got %v %v", failed, err)
+	}
+
+	if n := cl.count("DeleteObjects"); n != 3 {
+		t.Errorf("expected 3 DeleteObjects calls; got %d", n)
+	}
+
+	if len(cl.objects) != 250 {
+		t.Errorf("expected 250 objects to remain; got %d", len(cl.objects))
+	}
+
+	t.Run("partial failure", func(t *testing.T) {
+		cl := &failingDeleteClient{memClient: newMemClient("dir/a.txt", "dir/b.txt", "dir/c.txt")}
+		fsys := s3fs.New(cl, "test")
+
+		failed, err := fsys.BulkDelete(context.Background(), []string{"dir/a.txt", "dir/b.txt", "dir/c.txt", "/invalid"})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Path < failed[j].Path })
+
+		if len(failed) != 3 ||
+			failed[0].Path != "/invalid" || !errors.Is(failed[0].Err, fs.ErrInvalid) ||
+			failed[1].Path != "dir/a.txt" || failed[2].Path != "dir/c.txt" {
+			t.Fatalf("unexpected failures: %v", failed)
+		}
+
+		var apiErr smithy.APIError
+		if !errors.As(failed[1].Err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+			t.Errorf("expected AccessDenied; got %v", failed[1].Err)
+		}
+
+		if !errors.Is(err, fs.ErrInvalid) || !strings.Contains(err.Error(), "dir/c.txt") {
+			t.Errorf("expected err to join all failures; got %v", err)
+		}
+
+		if _, ok := cl.objects["dir/b.txt"]; ok {
+			t.Error("expected dir/b.txt to be deleted")
+		}
+	})
+}
+
+// concurrentDeleteClient blocks every DeleteObjects call until wait calls
+// are in progress, or the previous calls ended, and records the maximum
+// number of concurrent calls.
+type concurrentDeleteClient struct {
+	*memClient
+	wait int
+
+	mu       sync.Mutex
+	inFlight int
+	max      int
+	ready    chan struct{}
+}
+
+func (c *concurrentDeleteClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	c.mu.Lock()
+	if c.ready == nil {
+		c.ready = make(chan struct{})
+	}
+	ready := c.ready
+	c.inFlight++
+	c.max = max(c.max, c.inFlight)
+	if c.inFlight == c.wait {
+		close(c.ready)
+		c.ready = nil
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+
+	return c.memClient.DeleteObjects(ctx, in, optFns...)
+}
+
+// failingDeleteClient fails to delete keys ending with a.txt or c.txt.
+type failingDeleteClient struct {
+	*memClient
+}
+
+func (c *failingDeleteClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	var (
+		keep   []types.ObjectIdentifier
+		failed []types.Error
+	)
+	for _, o := range in.Delete.Objects {
+		if strings.HasSuffix(*o.Key, "a.txt") || strings.HasSuffix(*o.Key, "c.txt") {
+			failed = append(failed, types.Error{Key: o.Key, Code: ptr("AccessDenied"), Message: ptr("Access Denied")})
+			continue
+		}
+		keep = append(keep, o)
+	}
+
+	if _, err := c.memClient.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: in.Bucket,
+		Delete: &types.Delete{Objects: keep},
+	}, optFns...); err != nil {
+		return nil, err
+	}
+
+	return &s3.DeleteObjectsOutput{Errors: failed}, nil
+}
@@ -5,28 +5,64 @@ import (
 	"errors"
 	"io"
 	"io/fs"
-	"path"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 var _ fs.ReadDirFile = (*dir)(nil)
 
+// maxEmptyPages is the number of consecutive empty truncated pages after
+// which reading a directory fails with errEmptyPages.
+const maxEmptyPages = 3
+
+var errEmptyPages = errors.New("too many empty pages")
+
 type dir struct {
 	fileInfo
-	s3cl   Client
-	bucket string
-	marker *string
-	done   bool
-	buf    []fs.DirEntry
-	dirs   map[dirEntry]bool
+	fsys *S3FS
+
+	// mu guards the fields below. It is released while a page is requested
+	// from S3; fetching is set in the meantime, and other goroutines wait
+	// on fetched for the page instead of requesting it again.
+	mu                sync.Mutex
+	fetched           *sync.Cond
+	fetching          bool
+	continuationToken *string
+	done              bool
+	buf               []fs.DirEntry
+	dirs              map[string]bool
+	emptyPages        int
+
+	// pages is used instead of calling ListObjectsV2 if the listing is
+	// prefetched; see WithListingConcurrency. It is only used by the
+	// goroutine that set fetching.
+	pages  chan listResult
+	cancel context.CancelFunc
+}
+
+type listResult struct {
+	out *s3.ListObjectsV2Output
+	err error
 }
 
 func (d *dir) Stat() (fs.FileInfo, error) {
-	return &d.fileInfo, nil
+	fi := d.fileInfo
+	fi.name = d.Name()
+	return &fi, nil
+}
+
+// Name returns the base name of the directory. The root directory of
+// a filesystem with a prefix is named after the last prefix element.
+func (d *dir) Name() string {
+	if d.name == "." && d.fsys.prefix != "" {
+		return baseName(d.fsys.prefix, d.fsys.delim)
+	}
+	return d.fileInfo.Name()
 }
 
 func (d *dir) Read([]byte) (int, error) {
@@ -38,10 +74,25 @@ func (d *dir) Read([]byte) (int, error) {
 }
 
 func (d *dir) Close() error {
+	if d.cancel != nil {
+		d.cancel()
+	}
 	return nil
 }
 
+// ReadDir is safe for concurrent use. Every entry is only returned to one
+// of the callers.
 func (d *dir) ReadDir(n int) (des []fs.DirEntry, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fresh := d.continuationToken == nil && !d.done && d.dirs == nil
+	if fresh {
+		if des, ok := d.fsys.cachedDir(d.name); ok {
+			d.buf, d.done, d.dirs = des, true, map[string]bool{}
+		}
+	}
+
 	if n <= 0 {
 		switch err := d.readAll(); {
 		case err == nil:
@@ -52,6 +103,9 @@ func (d *dir) ReadDir(n int) (des []fs.DirEntry, err error) {
 		}
 
 		des, d.buf = d.buf, nil
+		if fresh {
+			d.fsys.cacheDir(d.name, des)
+		}
 		return des, nil
 	}
 
@@ -91,44 +145,83 @@ func (d *dir) readAll() error {
 	return io.EOF
 }
 
+// readNext reads the next page of the listing into d.buf. d.mu must be
+// held; it is released while the page is requested. If another goroutine
+// is already requesting a page, readNext waits for it and returns nil, so
+// that the caller checks d.buf again.
 func (d *dir) readNext() error {
+	if d.fetched == nil {
+		d.fetched = sync.NewCond(&d.mu)
+	}
+
+	if d.fetching {
+		for d.fetching {
+			d.fetched.Wait()
+		}
+		return nil
+	}
+
 	if d.done {
 		return io.EOF
 	}
 
-	name := strings.TrimRight(d.name, "/")
-	switch {
-	case name == ".":
-		name = ""
-	default:
-		name += "/"
-	}
+	d.fetching = true
+	d.mu.Unlock()
+	out, err := d.list()
+	d.mu.Lock()
+	d.fetching = false
+	d.fetched.Broadcast()
 
-	out, err := d.s3cl.ListObjects(
-		context.Background(),
-		&s3.ListObjectsInput{
-			Bucket:    &d.bucket,
-			Delimiter: ptr("/"),
-			Prefix:    &name,
-			Marker:    d.marker,
-		})
 	if err != nil {
-		return err
+		switch {
+		case d.fsys.isNotFoundErr(err):
+			// the bucket does not exist.
+			err = fs.ErrNotExist
+		case d.fsys.isPermissionErr(err):
+			err = fs.ErrPermission
+		}
+		return &fs.PathError{
+			Op:   "readdir",
+			Path: d.name,
+			Err:  err,
+		}
 	}
 
-	if d.name != "." && len(out.CommonPrefixes)+len(out.Contents) == 0 {
+	empty := len(out.CommonPrefixes)+len(out.Contents) == 0
+	if d.name != "." && empty && d.continuationToken == nil {
 		return &fs.PathError{
 			Op:   "readdir",
-			Path: strings.TrimSuffix(name, "/"),
+			Path: d.name,
 			Err:  fs.ErrNotExist,
 		}
 	}
 
-	d.marker = out.NextMarker
+	d.continuationToken = out.NextContinuationToken
 	d.done = out.IsTruncated != nil && !(*out.IsTruncated)
 
+	// S3 does not return empty truncated pages, but some S3 compatible
+	// stores do, and they may keep doing so forever.
+	if !empty || d.done {
+		d.emptyPages = 0
+	} else {
+		d.emptyPages++
+	}
+
+	if d.emptyPages >= maxEmptyPages {
+		d.fsys.logger.Log(d.fsys.ctx, LevelWarn, "s3fs: listing returned empty truncated pages",
+			"bucket", d.fsys.bucket,
+			"prefix", d.fsys.dirPrefix(d.name),
+			"pages", d.emptyPages,
+		)
+		return &fs.PathError{
+			Op:   "readdir",
+			Path: d.name,
+			Err:  errEmptyPages,
+		}
+	}
+
 	if d.dirs == nil {
-		d.dirs = make(map[dirEntry]bool)
+		d.dirs = make(map[string]bool)
 	}
 
 	for _, p := range out.CommonPrefixes {
@@ -136,15 +229,11 @@ func (d *dir) readNext() error {
 			continue
 		}
 
-		de := dirEntry{
-			fileInfo: fileInfo{
-				name: path.Base(*p.Prefix),
-				mode: fs.ModeDir,
-			},
-		}
-
-		if _, ok := d.dirs[de]; !ok {
-			d.dirs[de] = false
+		// the same prefix may be returned on more than one page; it is
+		// only listed once.
+		name := baseName(*p.Prefix, d.fsys.delim)
+		if _, ok := d.dirs[name]; !ok {
+			d.dirs[name] = false
 		}
 	}
 
@@ -153,11 +242,23 @@ func (d *dir) readNext() error {
 			continue
 		}
 
+		if d.isDirMarker(o) {
+			if *o.Key != d.fsys.dirPrefix(d.name) {
+				name := baseName(*o.Key, d.fsys.delim)
+				if _, ok := d.dirs[name]; !ok {
+					d.dirs[name] = false
+				}
+			}
+			continue
+		}
+
 		d.buf = append(d.buf, dirEntry{
 			fileInfo: fileInfo{
-				name:    path.Base(*o.Key),
+				name:    baseName(*o.Key, d.fsys.delim),
 				size:    derefInt64(o.Size),
 				modTime: derefTime(o.LastModified),
+				delim:   d.fsys.delim,
+				sys:     metaFromObject(o),
 			},
 		})
 	}
@@ -170,6 +271,97 @@ func (d *dir) readNext() error {
 	return nil
 }
 
+// isDirMarker reports whether o is an empty object marking a directory;
+// see WithDirMarkers.
+func (d *dir) isDirMarker(o types.Object) bool {
+	return d.fsys.dirMarkers &&
+		strings.HasSuffix(*o.Key, d.fsys.delim) &&
+		derefInt64(o.Size) == 0
+}
+
+// nextPage reads the next page of the listing and returns its entries,
+// without keeping them in d.buf. It returns io.EOF with the entries of the
+// last page.
+func (d *dir) nextPage() ([]fs.DirEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := d.readNext()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	des := d.buf
+	d.buf = nil
+	return des, err
+}
+
+func (d *dir) list() (*s3.ListObjectsV2Output, error) {
+	if d.fsys.listingConcurrency <= 0 {
+		return d.fsys.listDir(d.fsys.ctx, d.name, d.continuationToken)
+	}
+
+	if d.pages == nil {
+		ctx, cancel := context.WithCancel(d.fsys.ctx)
+		d.pages, d.cancel = make(chan listResult, d.fsys.listingConcurrency), cancel
+		go d.prefetch(ctx, d.pages, d.continuationToken)
+	}
+
+	r, ok := <-d.pages
+	if !ok {
+		return nil, fs.ErrClosed
+	}
+
+	if r.err != nil {
+		// prefetch stopped; the next call starts it again from the last
+		// page that was read.
+		d.cancel()
+		d.pages, d.cancel = nil, nil
+	}
+	return r.out, r.err
+}
+
+// prefetch lists the directory in the background and sends the pages to
+// the buffered channel pages, until it is full. Every page depends on the
+// continuation token returned with the previous one, so pages are still fetched one
+// by one, but ahead of the caller.
+func (d *dir) prefetch(ctx context.Context, pages chan<- listResult, token *string) {
+	defer close(pages)
+
+	for {
+		out, err := d.fsys.listDir(ctx, d.name, token)
+
+		select {
+		case pages <- listResult{out: out, err: err}:
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil || (out.IsTruncated != nil && !*out.IsTruncated) {
+			return
+		}
+		token = out.NextContinuationToken
+	}
+}
+
+func (f *S3FS) listDir(ctx context.Context, name string, token *string) (*s3.ListObjectsV2Output, error) {
+	return call(f, ctx, f.cl.ListObjectsV2, &s3.ListObjectsV2Input{
+		Bucket:            &f.bucket,
+		Delimiter:         &f.delim,
+		Prefix:            ptr(f.dirPrefix(name)),
+		ContinuationToken: token,
+		MaxKeys:           f.maxKeys(),
+
+		OptionalObjectAttributes: listAttributes,
+	})
+}
+
+// mergeDirFiles adds the pending directories to the files in d.buf. Both
+// are sorted, so they are merged in a single pass.
+//
+// A directory is only added once a file that sorts after it was listed, or
+// when the listing is done; otherwise a later page could still contain
+// files that sort before it.
 func (d *dir) mergeDirFiles() {
 	if d.buf == nil {
 		// according to fs docs ReadDir should never return nil slice,
@@ -177,28 +369,45 @@ func (d *dir) mergeDirFiles() {
 		d.buf = []fs.DirEntry{}
 	}
 
-	// we need a current len for sort.Search that doesn't change; otherwise
-	// we could not append to the same slice.
-	l := len(d.buf)
-	for de, used := range d.dirs {
-		if used {
+	var last string
+	if len(d.buf) > 0 {
+		last = d.buf[len(d.buf)-1].Name()
+	}
+
+	var dirs []string
+	for name, used := range d.dirs {
+		if used || (!d.done && (len(d.buf) == 0 || name > last)) {
 			continue
 		}
+		dirs = append(dirs, name)
+		d.dirs[name] = true
+	}
 
-		i := sort.Search(l, func(i int) bool {
-			return d.buf[i].Name() >= de.Name()
-		})
+	if len(dirs) == 0 {
+		return
+	}
+	sort.Strings(dirs)
 
-		if i == l && !d.done {
+	files := d.buf
+	d.buf = make([]fs.DirEntry, 0, len(files)+len(dirs))
+	for len(files) > 0 || len(dirs) > 0 {
+		if len(dirs) == 0 || (len(files) > 0 && files[0].Name() < dirs[0]) {
+			d.buf, files = append(d.buf, files[0]), files[1:]
 			continue
 		}
-		d.buf = append(d.buf, de)
-		d.dirs[de] = true
+		d.buf, dirs = append(d.buf, d.dirEntry(dirs[0])), dirs[1:]
 	}
+}
 
-	sort.Slice(d.buf, func(i, j int) bool {
-		return d.buf[i].Name() < d.buf[j].Name()
-	})
+// dirEntry returns the entry of the sub-directory name.
+func (d *dir) dirEntry(name string) dirEntry {
+	return dirEntry{
+		fileInfo: fileInfo{
+			name:  name,
+			mode:  fs.ModeDir,
+			delim: d.fsys.delim,
+		},
+	}
 }
 
 type dirEntry struct {
@@ -228,3 +437,17 @@ func derefTime(t *time.Time) time.Time {
 	}
 	return time.Time{}
 }
+
+func derefString(s *string) string {
+	if s != nil {
+		return *s
+	}
+	return ""
+}
+
+func derefBool(b *bool) bool {
+	if b != nil {
+		return *b
+	}
+	return false
+}
@@ -7,7 +7,10 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
-	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
@@ -18,45 +21,108 @@ var (
 	_ fs.File     = (*file)(nil)
 	_ fs.FileInfo = (*fileInfo)(nil)
 	_ io.Seeker   = (*file)(nil)
+	_ io.ReaderAt = (*file)(nil)
+	_ io.WriterTo = (*file)(nil)
+	_ ETagFile    = (*file)(nil)
 )
 
+// ETagFile is a file that knows the ETag of its S3 object. Files opened
+// with S3FS implement it.
+type ETagFile interface {
+	fs.File
+	ETag() string
+}
+
 type file struct {
-	cl     Client
-	bucket string
-	name   string
+	fsys *S3FS
+	name string
 
 	io.ReadCloser
-	stat   func() (fs.FileInfo, error)
-	offset int64
-	eTag   string
+	stat      func() (fs.FileInfo, error)
+	offset    int64
+	size      int64
+	eTag      string
+	modTime   time.Time
+	versionID *string
+
+	// decompressed is set if the content is decompressed; see
+	// WithDecompressContent.
+	decompressed bool
+
+	closeOnce sync.Once
+	closed    atomic.Bool
 }
 
-func openFile(cl Client, bucket string, name string) (fs.File, error) {
-	out, err := cl.GetObject(context.Background(), &s3.GetObjectInput{
-		Key:    &name,
-		Bucket: &bucket,
+func (f *S3FS) openFile(name string) (fs.File, error) {
+	if file, ok, err := f.openCachedFile(name); ok || err != nil {
+		return file, err
+	}
+
+	out, err := call(f, f.ctx, f.cl.GetObject, &s3.GetObjectInput{
+		Key:          ptr(f.key(name)),
+		Bucket:       &f.bucket,
+		ChecksumMode: f.checksumMode(),
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	statFunc := getStatFunc(cl, bucket, name, *out)
+	statFunc := f.getStatFunc(name, *out)
 
-	return &file{
-		cl:         cl,
-		bucket:     bucket,
+	body := f.progressBody(f.limitRead(f.checksumBody(name, out)), out.ContentLength)
+	if f.contentCache != nil && out.ContentLength != nil && *out.ContentLength <= f.contentCache.max && f.decompressor(out.ContentEncoding) == nil {
+		body = &cachingReader{
+			ReadCloser: body,
+			fsys:       f,
+			key:        f.key(name),
+			eTag:       derefString(out.ETag),
+			size:       *out.ContentLength,
+		}
+	}
+
+	file := &file{
+		fsys:       f,
 		name:       name,
-		ReadCloser: out.Body,
+		ReadCloser: body,
 		stat:       statFunc,
 		offset:     0,
+		size:       derefInt64(out.ContentLength),
 		eTag:       *out.ETag,
-	}, nil
+		modTime:    derefTime(out.LastModified),
+	}
+
+	if err := f.decompressFile(file, out.ContentEncoding); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// ctxReader stops reading from r once ctx is done. file.Read makes the
+// same check itself, since its body is replaced by Seek.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	default:
+	}
+	return r.r.Read(p)
 }
 
-func getStatFunc(cl Client, bucket string, name string, s3ObjOutput s3.GetObjectOutput) func() (fs.FileInfo, error) {
+// getStatFunc returns the Stat function of the file name opened with
+// s3ObjOutput. The file info is built from the GetObject response, so Stat
+// makes no S3 call. It only falls back to stat if the response has no
+// Content-Length, which happens if it is sent with chunked transfer
+// encoding, or no Last-Modified header; S3 always sends both, but some
+// S3 compatible stores and proxies do not.
+func (f *S3FS) getStatFunc(name string, s3ObjOutput s3.GetObjectOutput) func() (fs.FileInfo, error) {
 	statFunc := func() (fs.FileInfo, error) {
-		return stat(cl, bucket, name)
+		return f.stat(name)
 	}
 
 	if s3ObjOutput.ContentLength != nil && s3ObjOutput.LastModified != nil {
@@ -65,9 +131,11 @@ func getStatFunc(cl Client, bucket string, name string, s3ObjOutput s3.GetObject
 		// another call in case Stat is called.
 		statFunc = func() (fs.FileInfo, error) {
 			return &fileInfo{
-				name:    path.Base(name),
+				name:    baseName(name, f.delim),
 				size:    *s3ObjOutput.ContentLength,
 				modTime: *s3ObjOutput.LastModified,
+				delim:   f.delim,
+				sys:     metaFromGetObject(&s3ObjOutput),
 			}, nil
 		}
 	}
@@ -76,19 +144,56 @@ func getStatFunc(cl Client, bucket string, name string, s3ObjOutput s3.GetObject
 }
 
 func (f *file) Read(p []byte) (int, error) {
+	if f.closed.Load() {
+		return 0, f.closedErr("read")
+	}
+
+	select {
+	case <-f.fsys.ctx.Done():
+		return 0, f.pathErr("read", f.fsys.ctx.Err())
+	default:
+	}
+
 	n, err := f.ReadCloser.Read(p)
 	f.offset += int64(n)
+	if err != nil && err != io.EOF {
+		err = f.pathErr("read", err)
+	}
+	return n, err
+}
+
+// WriteTo implements io.WriterTo. It copies the body of the object to w
+// without an intermediate buffer if w implements io.ReaderFrom, e.g. an
+// http.ResponseWriter.
+func (f *file) WriteTo(w io.Writer) (int64, error) {
+	if f.closed.Load() {
+		return 0, f.closedErr("read")
+	}
+
+	n, err := io.Copy(w, &ctxReader{ctx: f.fsys.ctx, r: f.ReadCloser})
+	f.offset += n
 	return n, err
 }
 
 func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.closed.Load() {
+		return 0, f.closedErr("seek")
+	}
+
+	if f.decompressed {
+		return 0, f.pathErr("seek", errDecompressed)
+	}
+
 	newOffset := f.offset
 
-	stat, err := f.Stat()
-	if err != nil {
-		return 0, err
+	size := f.size
+	if size <= 0 {
+		stat, err := f.Stat()
+		if err != nil {
+			return 0, f.pathErr("seek", err)
+		}
+		size = stat.Size()
 	}
-	size := stat.Size()
 
 	switch whence {
 	case io.SeekStart:
@@ -98,7 +203,7 @@ func (f *file) Seek(offset int64, whence int) (int64, error) {
 	case io.SeekEnd:
 		newOffset = size + offset
 	default:
-		return 0, errors.New("s3fs.file.Seek: invalid whence")
+		return 0, f.pathErr("seek", fmt.Errorf("invalid whence %d", whence))
 	}
 
 	// If the position has not moved, there is no need to make a new query
@@ -107,15 +212,24 @@ func (f *file) Seek(offset int64, whence int) (int64, error) {
 	}
 
 	if newOffset < 0 {
-		return 0, errors.New("s3fs.file.Seek: seeked to a negative position")
+		return 0, f.pathErr("seek", fmt.Errorf("seeked to negative position %d", newOffset))
+	}
+
+	if delta := newOffset - f.offset; delta > 0 && delta <= f.fsys.seekForwardThreshold && newOffset < size {
+		n, err := io.CopyN(io.Discard, f.ReadCloser, delta)
+		f.offset += n
+		if err == nil {
+			return f.offset, nil
+		}
+		// the body could not be read; open the file again at newOffset.
 	}
 
 	if f.eTag == "" {
-		return 0, errors.New("s3fs.file.Seek: cannot seek. remote file has no etag")
+		return 0, f.pathErr("seek", errors.New("remote file has no etag"))
 	}
 
-	if err := f.Close(); err != nil {
-		return f.offset, err
+	if err := f.ReadCloser.Close(); err != nil {
+		return f.offset, f.pathErr("seek", err)
 	}
 
 	if newOffset >= size {
@@ -124,50 +238,186 @@ func (f *file) Seek(offset int64, whence int) (int64, error) {
 		return f.offset, nil
 	}
 
-	rawObject, err := f.cl.GetObject(
-		context.Background(),
-		&s3.GetObjectInput{
-			Bucket:  &f.bucket,
-			Key:     &f.name,
-			Range:   ptr(fmt.Sprintf("bytes=%d-", newOffset)),
-			IfMatch: &f.eTag,
-		})
+	in := &s3.GetObjectInput{
+		Bucket:    &f.fsys.bucket,
+		Key:       ptr(f.fsys.key(f.name)),
+		Range:     ptr(fmt.Sprintf("bytes=%d-", newOffset)),
+		IfMatch:   &f.eTag,
+		VersionId: f.versionID,
+	}
+
+	detectReplace := f.fsys.seekDetectReplace && !f.modTime.IsZero()
+	if detectReplace {
+		in.IfUnmodifiedSince = &f.modTime
+	}
+
+	rawObject, err := call(f.fsys, f.fsys.ctx, f.fsys.cl.GetObject, in)
 
 	if err != nil {
-		if e := new(awshttp.ResponseError); errors.As(err, &e) {
-			if e.HTTPStatusCode() == http.StatusPreconditionFailed {
-				return 0, fmt.Errorf("s3fs.file.Seek: file has changed while seeking: %w", fs.ErrNotExist)
-			}
+		switch {
+		case hasStatusCode(err, http.StatusPreconditionFailed):
+			return 0, f.pathErr("seek", fmt.Errorf("file has changed while seeking: %w", fs.ErrNotExist))
+		case f.fsys.isNotFoundErr(err):
+			return 0, f.pathErr("seek", fmt.Errorf("file deleted during seek session: %w", fs.ErrNotExist))
 		}
-		return 0, err
+		return 0, f.pathErr("seek", err)
+	}
+
+	if detectReplace && rawObject.LastModified != nil && !rawObject.LastModified.Equal(f.modTime) {
+		rawObject.Body.Close()
+		return 0, f.pathErr("seek", fmt.Errorf("file was replaced while seeking: %w", fs.ErrNotExist))
 	}
 
 	f.offset = newOffset
-	f.ReadCloser = rawObject.Body
+	f.ReadCloser = f.fsys.limitRead(rawObject.Body)
+
+	if n := objectSize(rawObject.ContentRange); n > 0 {
+		f.size = n
+	}
 
 	return f.offset, nil
 }
 
-func (f file) Stat() (fs.FileInfo, error) { return f.stat() }
+// ReadAt implements io.ReaderAt.
+//
+// Every call issues its own ranged GetObject request, so ReadAt does not
+// change the offset used by Read and Seek and is safe for concurrent use.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed.Load() {
+		return 0, f.closedErr("read")
+	}
+
+	if f.decompressed {
+		return 0, fmt.Errorf("s3fs.file.ReadAt: cannot read: %w", errDecompressed)
+	}
+
+	return f.fsys.readAt("s3fs.file.ReadAt", f.name, f.eTag, f.versionID, p, off)
+}
+
+// readAt reads len(p) bytes of the object name starting at off with a ranged
+// GetObject request. It fails if the ETag of the object is no longer eTag.
+// op prefixes the returned errors.
+func (f *S3FS) readAt(op, name, eTag string, versionID *string, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New(op + ": negative offset")
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if eTag == "" {
+		return 0, errors.New(op + ": cannot read. remote file has no etag")
+	}
+
+	rawObject, err := call(f, f.ctx, f.cl.GetObject, &s3.GetObjectInput{
+		Bucket:    &f.bucket,
+		Key:       ptr(f.key(name)),
+		Range:     ptr(fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1)),
+		IfMatch:   &eTag,
+		VersionId: versionID,
+	})
+
+	if err != nil {
+		switch {
+		case hasStatusCode(err, http.StatusPreconditionFailed):
+			return 0, fmt.Errorf("%s: file has changed: %w", op, fs.ErrNotExist)
+		case hasStatusCode(err, http.StatusRequestedRangeNotSatisfiable):
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	defer rawObject.Body.Close()
+
+	n, err := io.ReadFull(f.limitRead(rawObject.Body), p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// objectSize returns the size of the object from the Content-Range header
+// of a ranged GetObject response, e.g. "bytes 100-199/1000", or 0 if it is
+// unknown. The Content-Length of such a response is only the size of the
+// range.
+func objectSize(contentRange *string) int64 {
+	_, total, ok := strings.Cut(derefString(contentRange), "/")
+	if !ok {
+		return 0
+	}
+
+	n, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	if f.closed.Load() {
+		return nil, f.closedErr("stat")
+	}
+	return f.stat()
+}
+
+// Close closes the file. Calling Close more than once returns nil.
+func (f *file) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		f.closed.Store(true)
+		err = f.ReadCloser.Close()
+	})
+	return err
+}
+
+func (f *file) closedErr(op string) error {
+	return f.pathErr(op, fs.ErrClosed)
+}
+
+func (f *file) pathErr(op string, err error) error {
+	return &fs.PathError{
+		Op:   op,
+		Path: f.name,
+		Err:  err,
+	}
+}
+
+// ETag implements ETagFile. It returns the ETag the file was opened with.
+func (f *file) ETag() string { return f.eTag }
 
 type fileInfo struct {
 	name    string
 	size    int64
 	mode    fs.FileMode
 	modTime time.Time
+	delim   string
+	sys     *S3ObjectMeta
 }
 
-func (fi fileInfo) Name() string       { return path.Base(fi.name) }
+func (fi fileInfo) Name() string       { return baseName(fi.name, fi.delim) }
 func (fi fileInfo) Size() int64        { return fi.size }
 func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
 func (fi fileInfo) ModTime() time.Time { return fi.modTime }
 func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
-func (fi fileInfo) Sys() interface{}   { return nil }
+
+// Sys returns *S3ObjectMeta if the metadata of the file was fetched,
+// otherwise nil.
+func (fi fileInfo) Sys() interface{} {
+	if fi.sys == nil {
+		return nil
+	}
+	return fi.sys
+}
 
 type eofReader struct{}
 
 func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
 
+func hasStatusCode(err error, code int) bool {
+	e := new(awshttp.ResponseError)
+	return errors.As(err, &e) && e.HTTPStatusCode() == code
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
@@ -0,0 +1,190 @@
+package s3fs
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithFileContentCache caches the content of files that were read to the
+// end, as long as they are not larger than maxBytes. The least recently
+// used files are evicted to keep the total size of the cache under
+// maxBytes.
+//
+// Opening a cached file makes a single HeadObject call to check its ETag,
+// and the content is only transferred again if the file has changed. It is
+// meant for small and frequently read files, e.g. configuration files.
+func WithFileContentCache(maxBytes int64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		if maxBytes <= 0 {
+			fsys.contentCache = nil
+			return
+		}
+		fsys.contentCache = newContentCache(maxBytes)
+	})
+}
+
+type contentEntry struct {
+	key  string
+	eTag string
+	data []byte
+}
+
+// contentCache is a LRU cache of file contents safe for concurrent use.
+type contentCache struct {
+	max int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func newContentCache(maxBytes int64) *contentCache {
+	return &contentCache{
+		max:     maxBytes,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// fresh returns an empty cache with the same size as c, or nil if c is
+// nil.
+func (c *contentCache) fresh() *contentCache {
+	if c == nil {
+		return nil
+	}
+	return newContentCache(c.max)
+}
+
+func (c *contentCache) get(key string) (contentEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return contentEntry{}, false
+	}
+	c.lru.MoveToFront(el)
+	return *el.Value.(*contentEntry), true
+}
+
+func (c *contentCache) put(key, eTag string, data []byte) {
+	if int64(len(data)) > c.max {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+
+	c.entries[key] = c.lru.PushFront(&contentEntry{key: key, eTag: eTag, data: data})
+	c.size += int64(len(data))
+
+	for c.size > c.max {
+		c.remove(c.lru.Back().Value.(*contentEntry).key)
+	}
+}
+
+func (c *contentCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+func (c *contentCache) deletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(key)
+		}
+	}
+}
+
+func (c *contentCache) remove(key string) {
+	el, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(el)
+	delete(c.entries, key)
+	c.size -= int64(len(el.Value.(*contentEntry).data))
+}
+
+// openCachedFile returns the cached content of name if its ETag did not
+// change.
+func (f *S3FS) openCachedFile(name string) (*file, bool, error) {
+	if f.contentCache == nil {
+		return nil, false, nil
+	}
+
+	entry, ok := f.contentCache.get(f.key(name))
+	if !ok {
+		return nil, false, nil
+	}
+
+	head, err := call(f, f.ctx, f.cl.HeadObject, &s3.HeadObjectInput{
+		Bucket: &f.bucket,
+		Key:    ptr(f.key(name)),
+	})
+	if err != nil {
+		if f.isNotFoundErr(err) {
+			f.contentCache.delete(f.key(name))
+		}
+		return nil, false, err
+	}
+
+	if derefString(head.ETag) != entry.eTag {
+		f.contentCache.delete(f.key(name))
+		return nil, false, nil
+	}
+
+	fi := &fileInfo{
+		name:    name,
+		size:    derefInt64(head.ContentLength),
+		modTime: derefTime(head.LastModified),
+		delim:   f.delim,
+		sys:     metaFromHeadObject(head),
+	}
+
+	return &file{
+		fsys:       f,
+		name:       name,
+		ReadCloser: io.NopCloser(bytes.NewReader(entry.data)),
+		stat:       func() (fs.FileInfo, error) { return fi, nil },
+		size:       int64(len(entry.data)),
+		eTag:       entry.eTag,
+	}, true, nil
+}
+
+// cachingReader stores the content of a file in the content cache once it
+// was read to the end.
+type cachingReader struct {
+	io.ReadCloser
+	fsys *S3FS
+	key  string
+	eTag string
+	buf  bytes.Buffer
+	size int64
+	done bool
+}
+
+func (r *cachingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.buf.Write(p[:n])
+
+	if errors.Is(err, io.EOF) && !r.done && int64(r.buf.Len()) == r.size {
+		r.done = true
+		r.fsys.contentCache.put(r.key, r.eTag, bytes.Clone(r.buf.Bytes()))
+	}
+	return n, err
+}
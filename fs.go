@@ -4,23 +4,46 @@ package s3fs
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/fs"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 var (
-	_ fs.FS        = (*S3FS)(nil)
-	_ fs.StatFS    = (*S3FS)(nil)
-	_ fs.ReadDirFS = (*S3FS)(nil)
+	_ fs.FS         = (*S3FS)(nil)
+	_ fs.StatFS     = (*S3FS)(nil)
+	_ fs.ReadDirFS  = (*S3FS)(nil)
+	_ fs.SubFS      = (*S3FS)(nil)
+	_ fs.ReadFileFS = (*S3FS)(nil)
+	_ ContextFS     = (*S3FS)(nil)
 )
 
 var errNotDir = errors.New("not a dir")
 
-// Option is a function that provides optional features to S3FS.
-type Option func(*S3FS)
+// Option provides optional features to S3FS. Options can only be applied
+// by the constructors, so a S3FS cannot be changed after it was created.
+type Option interface {
+	apply(*S3FS)
+}
+
+// optionFunc is an Option that calls itself.
+type optionFunc func(*S3FS)
+
+func (fn optionFunc) apply(fsys *S3FS) { fn(fsys) }
 
 // WithReadSeeker enables Seek functionality on files opened with this fs.
 //
@@ -28,45 +51,380 @@ type Option func(*S3FS)
 // position. This can cause problems if the file changed between opening
 // and calling Seek. In that case, fs.ErrNotExist error is returned, which
 // has to be handled by the caller.
-func WithReadSeeker(fsys *S3FS) { fsys.readSeeker = true }
+var WithReadSeeker Option = optionFunc(func(fsys *S3FS) { fsys.readSeeker = true })
+
+// WithSeekDetectReplace makes Seek detect files that were replaced with
+// the same content since they were opened. Such files keep their ETag, so
+// they are only detected by their LastModified time, which is compared
+// with the time of the object returned for the new position. Seek then
+// fails with an error wrapping fs.ErrNotExist.
+//
+// The request also sends If-Unmodified-Since, but S3 ignores it if the
+// ETag matches.
+func WithSeekDetectReplace() Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.seekDetectReplace = true
+	})
+}
+
+// defaultSeekForwardThreshold is the default value of
+// WithSeekForwardThreshold.
+const defaultSeekForwardThreshold = 64 << 10
+
+// WithSeekForwardThreshold sets the largest forward seek that is done by
+// reading and discarding the bytes in between, instead of opening the
+// file again at the new offset. This keeps the connection for small
+// seeks, e.g. when skipping headers of records. The default is 64KB, and
+// zero always opens the file again.
+func WithSeekForwardThreshold(n int64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.seekForwardThreshold = n
+	})
+}
+
+// WithPrefix roots the filesystem at the directory prefix of the bucket,
+// so that e.g. "staging" and "production" environments can share a bucket.
+// Names passed to and returned by the filesystem are relative to prefix.
+//
+// New panics if prefix is not a valid path. A single trailing delimiter
+// is allowed.
+func WithPrefix(prefix string) Option {
+	return optionFunc(func(fsys *S3FS) {
+		// the delimiter is appended by New, since it may not be set yet.
+		fsys.prefix = prefix
+	})
+}
+
+// WithDelimiter sets the delimiter used to simulate directories instead
+// of "/", e.g. "|" or ":". Names passed to the filesystem are split by d
+// and they may contain slashes, so they are not required to be valid
+// according to fs.ValidPath.
+//
+// Glob always treats "/" as the separator, and fstest.TestFS compliance is
+// only guaranteed with the default delimiter. WithDelimiter panics if d is
+// empty.
+func WithDelimiter(d string) Option {
+	if d == "" {
+		panic("s3fs: empty delimiter")
+	}
+
+	return optionFunc(func(fsys *S3FS) {
+		fsys.delim = d
+	})
+}
+
+// WithListingConcurrency makes directories prefetch up to n pages of
+// ListObjectsV2 results in the background, while the caller processes the
+// previous ones. Since every page depends on the previous one, this hides
+// the latency of S3 calls rather than issuing them in parallel.
+//
+// Directories opened with Open must be closed to stop prefetching early.
+func WithListingConcurrency(n int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.listingConcurrency = n
+	})
+}
+
+// WithNotFoundErrorCodes adds S3 error codes that are reported as
+// fs.ErrNotExist, for S3 compatible stores that don't use the AWS codes,
+// e.g. "NoSuchObject" returned by some MinIO versions. The codes are
+// checked in addition to the defaults: NoSuchKey, NotFound, NoSuchBucket
+// and NoSuchBucketPolicy.
+func WithNotFoundErrorCodes(codes ...string) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.notFoundCodes = append(fsys.notFoundCodes, codes...)
+	})
+}
+
+// WithPermissionErrorCodes adds S3 error codes that are reported as
+// fs.ErrPermission. The codes are checked in addition to the defaults:
+// AccessDenied, AllAccessDisabled, InvalidAccessKeyId and Forbidden.
+func WithPermissionErrorCodes(codes ...string) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.permissionCodes = append(fsys.permissionCodes, codes...)
+	})
+}
+
+// WithPageSize sets the maximum number of keys returned by a single
+// ListObjectsV2 call made when reading directories and walking the tree.
+// S3 returns at most 1000 keys per call, which is also the default.
+//
+// Smaller pages make the first entries of large directories available
+// sooner, at the cost of more requests. Stat always requests a single key.
+func WithPageSize(n int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.pageSize = n
+	})
+}
+
+// WithDirMarkers makes directories listed by ReadDir include the empty
+// objects whose keys end with the delimiter, e.g. "dir/". Such objects are
+// created by the S3 console and tools like rclone to mark an empty
+// directory, and are listed as directories instead of files. The marker of
+// the directory being read is not listed.
+func WithDirMarkers() Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.dirMarkers = true
+	})
+}
 
 // Client wraps the s3 client methods that this package is using.
 // This interface may change in the future and should not be relied on by
 // packages using it.
 type Client interface {
 	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
-	ListObjects(ctx context.Context, params *s3.ListObjectsInput, optFns ...func(*s3.Options)) (*s3.ListObjectsOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 }
 
+var _ S3Api = (*s3.Client)(nil)
+
+// S3Api is Client with the methods needed to write and remove files. It is
+// implemented by *s3.Client.
+//
+// Other optional features, such as multipart uploads and versioning, are
+// used when the client implements the corresponding *s3.Client methods.
+type S3Api interface {
+	Client
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// ContextFS is a filesystem that can open files bound to a context.
+type ContextFS interface {
+	fs.FS
+	OpenContext(ctx context.Context, name string) (fs.File, error)
+}
+
 // S3FS is a S3 filesystem implementation.
 //
 // S3 has a flat structure instead of a hierarchy. S3FS simulates directories
-// by using prefixes and delims ("/"). Because directories are simulated, ModTime
+// by using prefixes and delims ("/" by default). Because directories are simulated, ModTime
 // is always a default Time value (IsZero returns true).
 type S3FS struct {
 	cl         Client
 	bucket     string
+	prefix     string
+	delim      string
 	readSeeker bool
+
+	seekForwardThreshold int64
+	seekDetectReplace    bool
+	ctx                  context.Context
+	acl                  types.ObjectCannedACL
+	sse                  sseOptions
+
+	renameWorkers int
+	deleteWorkers int
+	trashPrefix   string
+
+	multipartThreshold int64
+	partSize           int64
+	uploadWorkers      int
+	maxMemoryPartSize  int64
+
+	statCache *ttlCache[fileInfo]
+	dirCache  *ttlCache[[]fs.DirEntry]
+
+	contentCache *contentCache
+	negCache     *ttlCache[struct{}]
+
+	listingConcurrency int
+	batchStatWorkers   int
+	pageSize           int
+	dirMarkers         bool
+
+	retry  retryOptions
+	limits rateLimits
+
+	inventoryBucket string
+	inventoryKey    string
+
+	role roleOptions
+
+	// mounts is set on the root of a filesystem returned by
+	// NewMultiBucketFS.
+	mounts *multiBucketFS
+
+	validateBucket bool
+	err            error
+
+	notFoundCodes   []string
+	permissionCodes []string
+
+	progress       ProgressFunc
+	uploadProgress UploadProgressFunc
+
+	readLimit  *rate.Limiter
+	writeLimit *rate.Limiter
+
+	checksums bool
+
+	decompressors map[string]DecompressFunc
+
+	timeouts map[string]time.Duration
+
+	tracer  trace.Tracer
+	metrics Metrics
+	logger  Logger
 }
 
 // New returns a new filesystem that works on the specified bucket.
 func New(cl Client, bucket string, opts ...Option) *S3FS {
+	return newS3FS(context.Background(), cl, bucket, opts...)
+}
+
+// NewV2 is like New, but the returned filesystem uses ctx for all S3
+// calls, including the one made by WithBucketValidation; see WithContext.
+// cl has to implement S3Api, so that the filesystem supports writes.
+func NewV2(ctx context.Context, cl S3Api, bucket string, opts ...Option) *S3FS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	return newS3FS(ctx, cl, bucket, opts...)
+}
+
+func newS3FS(ctx context.Context, cl Client, bucket string, opts ...Option) *S3FS {
 	fsys := &S3FS{
 		cl:     cl,
 		bucket: bucket,
+		delim:  "/",
+		ctx:    ctx,
+
+		seekForwardThreshold: defaultSeekForwardThreshold,
+
+		metrics: NopMetrics{},
+		logger:  DiscardLogger{},
 	}
 
 	for _, opt := range opts {
-		opt(fsys)
+		opt.apply(fsys)
+	}
+
+	fsys.setPrefix(fsys.prefix)
+
+	if cl != nil {
+		fsys.err = fsys.checkBucket(fsys.ctx)
 	}
 
 	return fsys
 }
 
+// WithContext returns a shallow copy of f that uses ctx for all S3 calls,
+// including the ones made by files and directories opened with it.
+// Reading from an opened file fails with ctx.Err() once ctx is done.
+//
+// The provided ctx must be non-nil.
+func (f *S3FS) WithContext(ctx context.Context) *S3FS {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	f2 := *f
+	f2.ctx = ctx
+	return &f2
+}
+
+// OpenContext is like Open but binds the file to ctx. See WithContext.
+func (f *S3FS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	return f.WithContext(ctx).Open(name)
+}
+
+// Sub implements fs.SubFS. The returned filesystem is a *S3FS rooted at dir,
+// which shares all options with f.
+func (f *S3FS) Sub(dir string) (fs.FS, error) {
+	if f.mounts != nil && dir != "." {
+		return f.multi().sub(dir)
+	}
+
+	if !f.validPath(dir) {
+		return nil, &fs.PathError{
+			Op:   "sub",
+			Path: dir,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if dir == "." {
+		return f, nil
+	}
+
+	f2 := *f
+	f2.prefix = f.prefix + dir + f.delim
+	return &f2, nil
+}
+
+// WithPrefix returns a shallow copy of f rooted at the directory prefix
+// of the bucket, like the WithPrefix option. Unlike Sub, prefix is not
+// relative to the prefix of f. All other options are shared with f.
+//
+// It panics if prefix is not a valid path.
+func (f *S3FS) WithPrefix(prefix string) *S3FS {
+	f2 := *f
+	f2.setPrefix(prefix)
+	return &f2
+}
+
+// WithBucket returns a shallow copy of f that works on bucket. All other
+// options are shared with f, but the caches start empty, since their
+// entries belong to the bucket of f. If WithBucketValidation was used,
+// bucket is validated as well; see Err.
+func (f *S3FS) WithBucket(bucket string) *S3FS {
+	f2 := *f
+	f2.bucket = bucket
+	f2.statCache = f.statCache.fresh()
+	f2.dirCache = f.dirCache.fresh()
+	f2.negCache = f.negCache.fresh()
+	f2.contentCache = f.contentCache.fresh()
+	f2.err = nil
+	if f2.cl != nil {
+		f2.err = f2.checkBucket(f2.ctx)
+	}
+	return &f2
+}
+
+// Bucket returns the name of the bucket of f.
+func (f *S3FS) Bucket() string { return f.bucket }
+
+// Prefix returns the directory of the bucket f is rooted at, including the
+// trailing delimiter, or "" for the root of the bucket.
+func (f *S3FS) Prefix() string { return f.prefix }
+
+// String returns the S3 URI of the root of f, e.g. "s3://bucket/prefix/".
+func (f *S3FS) String() string {
+	return "s3://" + f.bucket + "/" + f.prefix
+}
+
+// setPrefix normalizes and sets the prefix of f. It panics if prefix is not
+// a valid path.
+func (f *S3FS) setPrefix(prefix string) {
+	f.prefix = ""
+	if prefix == "" {
+		return
+	}
+
+	p := strings.TrimSuffix(prefix, f.delim)
+	if !f.validPath(p) {
+		panic("s3fs: invalid prefix: " + strconv.Quote(prefix))
+	}
+
+	if p != "." {
+		f.prefix = p + f.delim
+	}
+}
+
 // Open implements fs.FS.
+//
+// Files are read directly from the body of the GetObject response, so the
+// content is streamed and never held in memory as a whole, unless it is
+// cached with WithFileContentCache.
 func (f *S3FS) Open(name string) (fs.File, error) {
-	if !fs.ValidPath(name) {
+	if f.mounts != nil {
+		return f.multi().Open(name)
+	}
+
+	if !f.validPath(name) {
 		return nil, &fs.PathError{
 			Op:   "open",
 			Path: name,
@@ -75,18 +433,38 @@ func (f *S3FS) Open(name string) (fs.File, error) {
 	}
 
 	if name == "." {
-		return openDir(f.cl, f.bucket, name)
+		return f.openDir(name)
+	}
+
+	if f.cachedNotExist(name) {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrNotExist,
+		}
 	}
 
-	file, err := openFile(f.cl, f.bucket, name)
+	file, err := f.openFile(name)
 
 	if err != nil {
-		if isNotFoundErr(err) {
-			switch d, err := openDir(f.cl, f.bucket, name); {
+		if f.isPermissionErr(err) {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  fs.ErrPermission,
+			}
+		}
+
+		if f.isNotFoundErr(err) {
+			switch d, err := f.openDir(name); {
 			case err == nil:
 				return d, nil
-			case !isNotFoundErr(err) && !errors.Is(err, errNotDir) && !errors.Is(err, fs.ErrNotExist):
-				return nil, err
+			case !f.isNotFoundErr(err) && !errors.Is(err, errNotDir) && !errors.Is(err, fs.ErrNotExist):
+				return nil, &fs.PathError{
+					Op:   "open",
+					Path: name,
+					Err:  err,
+				}
 			}
 
 			return nil, &fs.PathError{
@@ -110,9 +488,112 @@ func (f *S3FS) Open(name string) (fs.File, error) {
 	return file, nil
 }
 
+// ReadFile implements fs.ReadFileFS.
+//
+// Unlike fs.ReadFile, it does not call Stat to size the buffer, because
+// GetObject already returns the length of the object.
+func (f *S3FS) ReadFile(name string) ([]byte, error) {
+	if f.mounts != nil {
+		return f.multi().ReadFile(name)
+	}
+
+	if !f.validPath(name) {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if f.cachedNotExist(name) {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrNotExist,
+		}
+	}
+
+	if name == "." {
+		return f.readFileOpen(name)
+	}
+
+	out, err := call(f, f.ctx, f.cl.GetObject, &s3.GetObjectInput{
+		Key:          ptr(f.key(name)),
+		Bucket:       &f.bucket,
+		ChecksumMode: f.checksumMode(),
+	})
+
+	if f.isNotFoundErr(err) {
+		// name may be a directory, let Open report the right error.
+		return f.readFileOpen(name)
+	}
+
+	if err != nil {
+		if f.isPermissionErr(err) {
+			err = fs.ErrPermission
+		}
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+	body := f.progressBody(f.limitRead(f.checksumBody(name, out)), out.ContentLength)
+
+	fn := f.decompressor(out.ContentEncoding)
+	if fn != nil {
+		if body, err = decompress(fn, body); err != nil {
+			return nil, &fs.PathError{
+				Op:   "read",
+				Path: name,
+				Err:  err,
+			}
+		}
+	}
+	r := &ctxReader{ctx: f.ctx, r: body}
+
+	var data []byte
+	if out.ContentLength != nil && *out.ContentLength >= 0 && fn == nil {
+		data = make([]byte, *out.ContentLength)
+		_, err = io.ReadFull(r, data)
+	} else {
+		data, err = io.ReadAll(r)
+	}
+
+	// io.ReadFull drops the checksum error of the last Read, but Close
+	// reports it as well.
+	if cerr := body.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "read",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return data, nil
+}
+
+// readFileOpen reads name with Open. It is used by ReadFile for names
+// that may be directories.
+func (f *S3FS) readFileOpen(name string) ([]byte, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
 // Stat implements fs.StatFS.
 func (f *S3FS) Stat(name string) (fs.FileInfo, error) {
-	fi, err := stat(f.cl, f.bucket, name)
+	if f.mounts != nil {
+		return f.multi().Stat(name)
+	}
+
+	fi, err := f.stat(name)
 	if err != nil {
 		return nil, &fs.PathError{
 			Op:   "stat",
@@ -125,7 +606,17 @@ func (f *S3FS) Stat(name string) (fs.FileInfo, error) {
 
 // ReadDir implements fs.ReadDirFS.
 func (f *S3FS) ReadDir(name string) ([]fs.DirEntry, error) {
-	d, err := openDir(f.cl, f.bucket, name)
+	if f.mounts != nil {
+		return f.multi().ReadDir(name)
+	}
+
+	if f.validPath(name) {
+		if des, ok := f.cachedDir(name); ok {
+			return des, nil
+		}
+	}
+
+	d, err := f.openDir(name)
 	if err != nil {
 		return nil, &fs.PathError{
 			Op:   "readdir",
@@ -133,33 +624,71 @@ func (f *S3FS) ReadDir(name string) ([]fs.DirEntry, error) {
 			Err:  err,
 		}
 	}
+	defer d.Close()
+
 	return d.ReadDir(-1)
 }
 
-func stat(s3cl Client, bucket, name string) (fs.FileInfo, error) {
-	if !fs.ValidPath(name) {
+func (f *S3FS) stat(name string) (fs.FileInfo, error) {
+	if !f.validPath(name) {
 		return nil, fs.ErrInvalid
 	}
 
 	if name == "." {
 		return &dir{
-			s3cl:   s3cl,
-			bucket: bucket,
+			fsys: f,
 			fileInfo: fileInfo{
-				name: ".",
-				mode: fs.ModeDir,
+				name:  ".",
+				mode:  fs.ModeDir,
+				delim: f.delim,
 			},
 		}, nil
 	}
 
-	head, err := s3cl.HeadObject(
-		context.Background(),
-		&s3.HeadObjectInput{
-			Bucket: &bucket,
-			Key:    &name,
-		})
+	if fi, ok := f.cachedStat(name); ok {
+		if fi.IsDir() {
+			return &dir{fsys: f, fileInfo: fi}, nil
+		}
+		return &fi, nil
+	}
+
+	if f.cachedNotExist(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	fi, err := f.statObject(name)
+	if err != nil {
+		switch {
+		case f.isNotFoundErr(err):
+			// ListObjectsV2 fails if the bucket does not exist.
+			err = fs.ErrNotExist
+		case f.isPermissionErr(err):
+			err = fs.ErrPermission
+		}
+
+		if errors.Is(err, fs.ErrNotExist) {
+			f.cacheNotExist(name)
+		}
+		return nil, err
+	}
+
+	switch fi := fi.(type) {
+	case *fileInfo:
+		f.cacheStat(name, *fi)
+	case *dir:
+		f.cacheStat(name, fi.fileInfo)
+	}
+	return fi, nil
+}
+
+// statObject is like stat, but always calls S3.
+func (f *S3FS) statObject(name string) (fs.FileInfo, error) {
+	head, err := call(f, f.ctx, f.cl.HeadObject, &s3.HeadObjectInput{
+		Bucket: &f.bucket,
+		Key:    ptr(f.key(name)),
+	})
 	if err != nil {
-		if !isNotFoundErr(err) {
+		if !f.isNotFoundErr(err) {
 			return nil, err
 		}
 	} else {
@@ -168,35 +697,131 @@ func stat(s3cl Client, bucket, name string) (fs.FileInfo, error) {
 			size:    derefInt64(head.ContentLength),
 			mode:    0,
 			modTime: derefTime(head.LastModified),
+			delim:   f.delim,
+			sys:     metaFromHeadObject(head),
 		}, nil
 	}
 
-	out, err := s3cl.ListObjects(
-		context.Background(),
-		&s3.ListObjectsInput{
-			Bucket:    &bucket,
-			Delimiter: ptr("/"),
-			Prefix:    ptr(name + "/"),
-			MaxKeys:   ptr[int32](1),
-		})
+	out, err := call(f, f.ctx, f.cl.ListObjectsV2, &s3.ListObjectsV2Input{
+		Bucket:    &f.bucket,
+		Delimiter: &f.delim,
+		Prefix:    ptr(f.dirPrefix(name)),
+		MaxKeys:   ptr[int32](1),
+
+		OptionalObjectAttributes: listAttributes,
+	})
 	if err != nil {
 		return nil, err
 	}
 	if len(out.CommonPrefixes) > 0 || len(out.Contents) > 0 {
 		return &dir{
-			s3cl:   s3cl,
-			bucket: bucket,
+			fsys: f,
 			fileInfo: fileInfo{
-				name: name,
-				mode: fs.ModeDir,
+				name:  name,
+				mode:  fs.ModeDir,
+				delim: f.delim,
 			},
 		}, nil
 	}
 	return nil, fs.ErrNotExist
 }
 
-func openDir(s3cl Client, bucket, name string) (fs.ReadDirFile, error) {
-	fi, err := stat(s3cl, bucket, name)
+// maxKeys returns the MaxKeys of listings set with WithPageSize.
+func (f *S3FS) maxKeys() *int32 {
+	if f.pageSize <= 0 {
+		return nil
+	}
+	return ptr(int32(min(f.pageSize, math.MaxInt32)))
+}
+
+// key returns the S3 key of the file name.
+func (f *S3FS) key(name string) string {
+	return f.prefix + name
+}
+
+// dirPrefix returns the S3 prefix of objects in the directory name.
+func (f *S3FS) dirPrefix(name string) string {
+	if name == "." {
+		return f.prefix
+	}
+	return f.prefix + name + f.delim
+}
+
+// validPath reports whether name is a valid path. With the default
+// delimiter, it is the same as fs.ValidPath. Otherwise names only have to
+// be valid UTF-8 and must not contain empty, "." or ".." elements.
+func (f *S3FS) validPath(name string) bool {
+	if f.delim == "/" {
+		return fs.ValidPath(name)
+	}
+
+	if name == "." {
+		return true
+	}
+
+	if !utf8.ValidString(name) {
+		return false
+	}
+
+	for _, elem := range strings.Split(name, f.delim) {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// baseName is like path.Base, but uses delim as the separator.
+func baseName(name, delim string) string {
+	if delim == "" || delim == "/" {
+		return path.Base(name)
+	}
+
+	name = strings.TrimSuffix(name, delim)
+	if i := strings.LastIndex(name, delim); i >= 0 {
+		return name[i+len(delim):]
+	}
+	return name
+}
+
+// listAll calls fn for every object whose name starts with prefix.
+// Unlike dir.ReadDir, it does not use a delimiter, so objects in all
+// "subdirectories" are listed as well. name is the key of the object
+// relative to the root of f.
+func (f *S3FS) listAll(ctx context.Context, prefix string, fn func(name string, o types.Object) error) error {
+	var token *string
+	for {
+		out, err := call(f, ctx, f.cl.ListObjectsV2, &s3.ListObjectsV2Input{
+			Bucket:            &f.bucket,
+			Prefix:            ptr(f.prefix + prefix),
+			ContinuationToken: token,
+			MaxKeys:           f.maxKeys(),
+
+			OptionalObjectAttributes: listAttributes,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, o := range out.Contents {
+			if o.Key == nil {
+				continue
+			}
+
+			if err := fn(strings.TrimPrefix(*o.Key, f.prefix), o); err != nil {
+				return err
+			}
+		}
+
+		if out.IsTruncated == nil || !*out.IsTruncated || out.NextContinuationToken == nil {
+			return nil
+		}
+		token = out.NextContinuationToken
+	}
+}
+
+func (f *S3FS) openDir(name string) (fs.ReadDirFile, error) {
+	fi, err := f.stat(name)
 	if err != nil {
 		return nil, err
 	}
@@ -207,11 +832,24 @@ func openDir(s3cl Client, bucket, name string) (fs.ReadDirFile, error) {
 	return nil, errNotDir
 }
 
+// notFoundErrorCodes are the S3 error codes of objects and buckets that do
+// not exist.
+var notFoundErrorCodes = []string{
+	"NoSuchKey",
+	"NotFound",
+	"NoSuchBucket",
+	"NoSuchBucketPolicy",
+}
+
 func isNotFoundErr(err error) bool {
 	if e := new(types.NoSuchKey); errors.As(err, &e) {
 		return true
 	}
 
+	if hasErrorCode(err, notFoundErrorCodes) {
+		return true
+	}
+
 	if e := new(http.ResponseError); errors.As(err, &e) {
 		// localstack workaround
 		if e.HTTPStatusCode() == 404 {
@@ -222,4 +860,50 @@ func isNotFoundErr(err error) bool {
 	return false
 }
 
+// permissionErrorCodes are the S3 error codes of requests that were not
+// allowed. HEAD responses have no body, so HeadObject fails with the
+// generic code Forbidden.
+var permissionErrorCodes = []string{
+	"AccessDenied",
+	"AllAccessDisabled",
+	"InvalidAccessKeyId",
+	"Forbidden",
+}
+
+func isPermissionErr(err error) bool {
+	return hasErrorCode(err, permissionErrorCodes)
+}
+
+// isNotFoundErr is like the function isNotFoundErr, but it also checks the
+// codes set with WithNotFoundErrorCodes.
+func (f *S3FS) isNotFoundErr(err error) bool {
+	return isNotFoundErr(err) || hasErrorCode(err, f.notFoundCodes)
+}
+
+// isPermissionErr is like the function isPermissionErr, but it also checks
+// the codes set with WithPermissionErrorCodes.
+func (f *S3FS) isPermissionErr(err error) bool {
+	return isPermissionErr(err) || hasErrorCode(err, f.permissionCodes)
+}
+
+// hasErrorCode reports whether err is an S3 error with one of codes.
+func hasErrorCode(err error, codes []string) bool {
+	var e smithy.APIError
+	return errors.As(err, &e) && slices.Contains(codes, e.ErrorCode())
+}
+
 type fileNoSeek struct{ fs.File }
+
+func (f fileNoSeek) ReadAt(p []byte, off int64) (int, error) {
+	return f.File.(io.ReaderAt).ReadAt(p, off)
+}
+
+func (f fileNoSeek) WriteTo(w io.Writer) (int64, error) {
+	return f.File.(io.WriterTo).WriteTo(w)
+}
+
+func (f fileNoSeek) ETag() string { return f.File.(ETagFile).ETag() }
+
+func errUnsupported(method string) error {
+	return fmt.Errorf("s3fs: client does not implement %s: %w", method, errors.ErrUnsupported)
+}
@@ -3,24 +3,36 @@ package s3fs_test
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
 	"crypto/tls"
 	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"reflect"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"testing/fstest"
+	"testing/iotest"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/jszwec/s3fs/v2"
 )
 
@@ -162,19 +174,19 @@ func TestSeeker(t *testing.T) {
 				desc:         "seek before beginning with whence SeekCurrent",
 				offset:       -1,
 				whence:       io.SeekCurrent,
-				errorMessage: "s3fs.file.Seek: seeked to a negative position",
+				errorMessage: "seek " + testFile + ": seeked to negative position -1",
 			},
 			{
 				desc:         "seek before beginning with whence SeekStart",
 				offset:       -1,
 				whence:       io.SeekStart,
-				errorMessage: "s3fs.file.Seek: seeked to a negative position",
+				errorMessage: "seek " + testFile + ": seeked to negative position -1",
 			},
 			{
 				desc:         "seek with invalid whence",
 				offset:       0,
 				whence:       3,
-				errorMessage: "s3fs.file.Seek: invalid whence",
+				errorMessage: "seek " + testFile + ": invalid whence 3",
 			},
 		}
 
@@ -976,10 +988,8 @@ func TestFS(t *testing.T) {
 							t.Fatalf("expected err to be PathError: got %#v", err)
 						}
 
-						// currently we don't implement fs.SubFS.
-						// fs.Sub calls open instead of Stat.
-						if perr.Op != "open" {
-							t.Errorf("expected op to be open; got %s", perr.Op)
+						if perr.Op != "stat" {
+							t.Errorf("expected op to be stat; got %s", perr.Op)
 						}
 					})
 				})
@@ -1051,9 +1061,9 @@ func TestFS(t *testing.T) {
 		s3fs *s3fs.S3FS
 	}{
 		{desc: "standard", s3fs: s3fs.New(wrappedCl, *bucket)},
-		{desc: "max keys = 1", s3fs: s3fs.New(&client{MaxKeys: ptr[int32](1), Client: wrappedCl}, *bucket)},
-		{desc: "max keys = 2", s3fs: s3fs.New(&client{MaxKeys: ptr[int32](2), Client: wrappedCl}, *bucket)},
-		{desc: "max keys = 3", s3fs: s3fs.New(&client{MaxKeys: ptr[int32](3), Client: wrappedCl}, *bucket)},
+		{desc: "max keys = 1", s3fs: s3fs.New(wrappedCl, *bucket, s3fs.WithPageSize(1))},
+		{desc: "max keys = 2", s3fs: s3fs.New(wrappedCl, *bucket, s3fs.WithPageSize(2))},
+		{desc: "max keys = 3", s3fs: s3fs.New(wrappedCl, *bucket, s3fs.WithPageSize(3))},
 	}
 
 	for _, f := range fixtures {
@@ -1074,13 +1084,13 @@ func TestDirRead(t *testing.T) {
 	tests := []struct {
 		desc     string
 		n        int
-		outs     []s3.ListObjectsOutput
+		outs     []s3.ListObjectsV2Output
 		expected [][]fileinfo
 	}{
 		{
 			desc: "all in one request - dir first",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c", "e"}, []string{"b", "d", "f"}),
 			},
 			expected: [][]fileinfo{
@@ -1095,7 +1105,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - n = 0",
 			n:    0,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c", "e"}, []string{"b", "d", "f"}),
 			},
 			expected: [][]fileinfo{
@@ -1112,7 +1122,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - n = 2",
 			n:    2,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a"}, nil),
 				newListOutput([]string{"c"}, []string{"b", "d"}),
 				newListOutput([]string{"e"}, nil),
@@ -1136,7 +1146,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "one per request - dir first",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a"}, nil),
 				newListOutput(nil, []string{"b"}),
 				newListOutput([]string{"c"}, []string{"d"}),
@@ -1155,7 +1165,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - file first",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"b", "d", "f"}, []string{"a", "c", "e"}),
 			},
 			expected: [][]fileinfo{
@@ -1170,7 +1180,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "with dir duplicates",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c"}, []string{"b"}),
 				newListOutput([]string{"c", "e", "c"}, []string{"d"}),
 				newListOutput([]string{"e", "a"}, []string{"f"}),
@@ -1188,7 +1198,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - dirs only",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c", "e"}, nil),
 			},
 			expected: [][]fileinfo{
@@ -1197,10 +1207,21 @@ func TestDirRead(t *testing.T) {
 				{{"e", true}},
 			},
 		},
+		{
+			desc: "same dir on consecutive pages",
+			n:    -1,
+			outs: []s3.ListObjectsV2Output{
+				newListOutput([]string{"a"}, []string{"b"}),
+				newListOutput([]string{"a"}, []string{"c"}),
+			},
+			expected: [][]fileinfo{
+				{{"a", true}, {"b", false}, {"c", false}},
+			},
+		},
 		{
 			desc: "single dir per request - dirs only",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a"}, nil),
 				newListOutput([]string{"c"}, nil),
 				newListOutput([]string{"e"}, nil),
@@ -1213,69 +1234,1816 @@ func TestDirRead(t *testing.T) {
 		},
 	}
 
-	for _, test := range tests {
-		t.Run(test.desc, func(t *testing.T) {
-			f, err := s3fs.New(&mockClient{
-				outs: test.outs,
-			}, "test").Open(".")
-			if err != nil {
-				t.Fatal("expected err to be nil; got ", err)
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			f, err := s3fs.New(&mockClient{
+				outs: test.outs,
+			}, "test").Open(".")
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			fi, err := f.Stat()
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if !fi.IsDir() {
+				t.Fatal("expected the file to be a directory")
+			}
+
+			var fis [][]fileinfo
+			for {
+				files, err := f.(fs.ReadDirFile).ReadDir(test.n)
+				if err != nil && !errors.Is(err, io.EOF) {
+					t.Fatal("did not expect err:", err)
+				}
+
+				if len(files) > 0 {
+					var out []fileinfo
+					for _, f := range files {
+						out = append(out, fileinfo{f.Name(), f.IsDir()})
+					}
+					fis = append(fis, out)
+				}
+
+				if test.n <= 0 || errors.Is(err, io.EOF) {
+					break
+				}
+			}
+
+			if !reflect.DeepEqual(fis, test.expected) {
+				t.Errorf("want %v; got %v", test.expected, fis)
+			}
+		})
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	fsys := s3fs.New(newMemClient("dir/file.txt"), "test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cfsys := fsys.WithContext(ctx)
+
+	f, err := cfsys.Open("dir/file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	cancel()
+
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v; got %v", context.Canceled, err)
+	}
+
+	for _, fn := range []func() error{
+		func() error { _, err := cfsys.Open("dir/file.txt"); return err },
+		func() error { _, err := cfsys.Stat("dir/file.txt"); return err },
+		func() error { _, err := cfsys.ReadDir("dir"); return err },
+	} {
+		if err := fn(); !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v; got %v", context.Canceled, err)
+		}
+	}
+
+	if _, err := fsys.Stat("dir/file.txt"); err != nil {
+		t.Errorf("expected the original fs to be unaffected; got %v", err)
+	}
+}
+
+func TestReadFileContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cl := &cancelingBodyClient{memClient: newMemClient("file.txt"), cancel: cancel}
+	fsys := s3fs.New(cl, "test").WithContext(ctx)
+
+	if _, err := fsys.ReadFile("file.txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v; got %v", context.Canceled, err)
+	}
+}
+
+// cancelingBodyClient returns bodies that read a byte at a time and call
+// cancel after the first one.
+type cancelingBodyClient struct {
+	*memClient
+	cancel context.CancelFunc
+}
+
+func (c *cancelingBodyClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	r := iotest.OneByteReader(out.Body)
+	out.Body = io.NopCloser(readerFunc(func(p []byte) (int, error) {
+		defer c.cancel()
+		return r.Read(p)
+	}))
+	return out, nil
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+func TestNewV2(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fsys := s3fs.NewV2(ctx, newMemClient("file.txt"), "test")
+
+	if err := fsys.WriteFile("new.txt", []byte("data"), 0644); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if err := fsys.Remove("new.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	cancel()
+
+	if _, err := fsys.Stat("file.txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v; got %v", context.Canceled, err)
+	}
+}
+
+func TestDirStatName(t *testing.T) {
+	fsys := s3fs.New(newMemClient("dir1/dir11/file.txt"), "test")
+
+	f, err := fsys.Open("dir1/dir11")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if fi.Name() != "dir11" {
+		t.Errorf("want name %q; got %q", "dir11", fi.Name())
+	}
+
+	if !fi.IsDir() {
+		t.Error("expected the file to be a directory")
+	}
+}
+
+func TestReadDirBaseNames(t *testing.T) {
+	fsys := s3fs.New(newMemClient(
+		"file.txt",
+		"a/file.txt",
+		"a/b/file.txt",
+		"a/b/c/d/file.txt",
+		"a/b/c/d/e/f/file.txt",
+	), "test")
+
+	var n int
+	err := fs.WalkDir(fsys, ".", func(name string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+
+		n++
+		if de.Name() != path.Base(de.Name()) || strings.Contains(de.Name(), "/") {
+			t.Errorf("%s: expected a base name; got %q", name, de.Name())
+		}
+		if want := path.Base(name); de.Name() != want {
+			t.Errorf("%s: want name %q; got %q", name, want, de.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 11 {
+		t.Errorf("want 11 entries; got %d", n)
+	}
+}
+
+func TestSub(t *testing.T) {
+	fsys := s3fs.New(newMemClient("dir1/dir11/file.txt", "dir1/file.txt", "file.txt"), "test", s3fs.WithReadSeeker)
+
+	sub, err := fs.Sub(fsys, "dir1")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if _, ok := sub.(*s3fs.S3FS); !ok {
+		t.Fatalf("expected fs.Sub to return *s3fs.S3FS; got %T", sub)
+	}
+
+	sub, err = fs.Sub(sub, "dir11")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if err := fstest.TestFS(sub, "file.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := sub.Open("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	if _, ok := f.(io.Seeker); !ok {
+		t.Error("expected options to be inherited by the sub fs")
+	}
+
+	fi, err := fs.Stat(sub, ".")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if fi.Name() != "dir11" {
+		t.Errorf("want %q; got %q", "dir11", fi.Name())
+	}
+
+	_, err = fs.Stat(sub, "not-exist")
+
+	var perr *fs.PathError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected err to be PathError: got %#v", err)
+	}
+
+	if perr.Op != "stat" || perr.Path != "not-exist" {
+		t.Errorf("want stat not-exist; got %s %s", perr.Op, perr.Path)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	cl := newMemClient()
+	cl.objects["file.txt"] = "0123456789"
+
+	fsys := s3fs.New(cl, "test")
+
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		t.Fatalf("expected file to implement io.ReaderAt; got %T", f)
+	}
+
+	fixtures := []struct {
+		off  int64
+		size int
+		want string
+		err  error
+	}{
+		{off: 0, size: 4, want: "0123"},
+		{off: 6, size: 4, want: "6789"},
+		{off: 8, size: 4, want: "89", err: io.EOF},
+		{off: 10, size: 4, want: "", err: io.EOF},
+	}
+
+	var wg sync.WaitGroup
+	for _, fixture := range fixtures {
+		fixture := fixture
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p := make([]byte, fixture.size)
+			n, err := ra.ReadAt(p, fixture.off)
+			if err != fixture.err {
+				t.Errorf("off=%d: want err %v; got %v", fixture.off, fixture.err, err)
+			}
+			if got := string(p[:n]); got != fixture.want {
+				t.Errorf("off=%d: want %q; got %q", fixture.off, fixture.want, got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(data) != "0123456789" {
+		t.Errorf("expected ReadAt not to move the offset; got %q", data)
+	}
+
+	cl.objects["file.txt"] = "changed"
+
+	if _, err := ra.ReadAt(make([]byte, 1), 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist after the file changed; got %v", err)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	files := []string{
+		"file.txt",
+		"logs/2024-01-01/error.log",
+		"logs/2024-01-01/info.log",
+		"logs/2024-01-02/error.log",
+		"logs/2024-02-01/error.log",
+		"logs/readme.md",
+	}
+
+	mapFS := fstest.MapFS{}
+	for _, f := range files {
+		mapFS[f] = &fstest.MapFile{Data: []byte("content")}
+	}
+
+	patterns := []string{
+		"*",
+		"file.txt",
+		"not-exist",
+		"logs/*",
+		"logs/2024-01-*/error.log",
+		"logs/*/*.log",
+		"*/2024-0?-01",
+		"logs/[a-z]*",
+		"missing/*",
+		"file.txt/*",
+		"missing/*/error.log",
+		"file.txt/*/error.log",
+	}
+
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			cl := newMemClient(files...)
+			fsys := s3fs.New(cl, "test")
+
+			want, err := fs.Glob(mapFS, pattern)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			got, err := fs.Glob(fsys, pattern)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("want %v; got %v", want, got)
+			}
+
+			if n := cl.count("ListObjectsV2"); n > 1 {
+				t.Errorf("expected at most 1 ListObjectsV2 call; got %d", n)
+			}
+		})
+	}
+
+	t.Run("bad pattern", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(files...), "test")
+		if _, err := fs.Glob(fsys, "[]"); !errors.Is(err, path.ErrBadPattern) {
+			t.Errorf("want %v; got %v", path.ErrBadPattern, err)
+		}
+	})
+
+	t.Run("delimited listing", func(t *testing.T) {
+		fixtures := []struct {
+			pattern   string
+			delimited bool
+		}{
+			{pattern: "*", delimited: true},
+			{pattern: "logs/*.md", delimited: true},
+			{pattern: "logs/*/error.log", delimited: false},
+		}
+
+		for _, f := range fixtures {
+			cl := &delimiterClient{memClient: newMemClient(files...)}
+			if _, err := fs.Glob(s3fs.New(cl, "test"), f.pattern); err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if len(cl.delims) != 1 || (cl.delims[0] != "") != f.delimited {
+				t.Errorf("%s: expected delimited listing to be %t; got delimiters %q", f.pattern, f.delimited, cl.delims)
+			}
+		}
+	})
+
+	t.Run("custom delimiter", func(t *testing.T) {
+		cl := newMemClient("file.txt", "logs:a.log", "logs:b.md", "logs:2024:c.log")
+		fsys := s3fs.New(cl, "test", s3fs.WithDelimiter(":"))
+
+		fixtures := []struct {
+			pattern  string
+			expected []string
+		}{
+			{pattern: "logs:*.log", expected: []string{"logs:a.log"}},
+			{pattern: "*:*", expected: []string{"logs:2024", "logs:a.log", "logs:b.md"}},
+			{pattern: "*:*:*.log", expected: []string{"logs:2024:c.log"}},
+		}
+
+		for _, f := range fixtures {
+			got, err := fs.Glob(fsys, f.pattern)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if !reflect.DeepEqual(f.expected, got) {
+				t.Errorf("%s: want %v; got %v", f.pattern, f.expected, got)
+			}
+		}
+
+		got, err := s3fs.GlobRecursive(fsys, "**:*.log")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if expected := []string{"logs:2024:c.log", "logs:a.log"}; !reflect.DeepEqual(expected, got) {
+			t.Errorf("want %v; got %v", expected, got)
+		}
+	})
+}
+
+type delimiterClient struct {
+	*memClient
+	delims []string
+}
+
+func (c *delimiterClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.delims = append(c.delims, aws.ToString(in.Delimiter))
+	return c.memClient.ListObjectsV2(ctx, in, optFns...)
+}
+
+func TestGlobRecursive(t *testing.T) {
+	files := []string{
+		"backups/db.sql",
+		"backups/2024/01/db.sql",
+		"backups/2024/01/notes.txt",
+		"backups/2024/02/users.sql",
+		"logs/app.log",
+		"schema.sql",
+	}
+
+	fixtures := []struct {
+		pattern  string
+		expected []string
+	}{
+		{
+			pattern:  "backups/**/*.sql",
+			expected: []string{"backups/2024/01/db.sql", "backups/2024/02/users.sql", "backups/db.sql"},
+		},
+		{
+			pattern:  "**/*.sql",
+			expected: []string{"backups/2024/01/db.sql", "backups/2024/02/users.sql", "backups/db.sql", "schema.sql"},
+		},
+		{
+			pattern:  "backups/**/**/db.sql",
+			expected: []string{"backups/2024/01/db.sql", "backups/db.sql"},
+		},
+		{
+			pattern:  "backups/**",
+			expected: []string{"backups/2024", "backups/2024/01", "backups/2024/01/db.sql", "backups/2024/01/notes.txt", "backups/2024/02", "backups/2024/02/users.sql", "backups/db.sql"},
+		},
+		{
+			pattern:  "**/01",
+			expected: []string{"backups/2024/01"},
+		},
+		{
+			pattern:  "backups/*/*",
+			expected: []string{"backups/2024/01", "backups/2024/02"},
+		},
+		{
+			pattern: "missing/**/*.sql",
+		},
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.pattern, func(t *testing.T) {
+			cl := newMemClient(files...)
+
+			got, err := s3fs.GlobRecursive(s3fs.New(cl, "test"), fixture.pattern)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if !reflect.DeepEqual(got, fixture.expected) {
+				t.Errorf("want %v; got %v", fixture.expected, got)
+			}
+
+			if n := cl.count("ListObjectsV2"); n > 1 {
+				t.Errorf("expected at most 1 ListObjectsV2 call; got %d", n)
+			}
+		})
+	}
+
+	if _, err := s3fs.GlobRecursive(s3fs.New(newMemClient(), "test"), "**/[]"); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("want %v; got %v", path.ErrBadPattern, err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	cl := newMemClient("dir/file.txt")
+	fsys := s3fs.New(cl, "test")
+
+	data, err := fs.ReadFile(fsys, "dir/file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	if n := cl.count("GetObject"); n != 1 {
+		t.Errorf("expected 1 GetObject call; got %d", n)
+	}
+
+	if n := cl.count("HeadObject"); n != 0 {
+		t.Errorf("expected no HeadObject calls; got %d", n)
+	}
+
+	if _, err := fs.ReadFile(fsys, "not-exist"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	for _, name := range []string{".", "dir"} {
+		var perr *fs.PathError
+		if _, err := fs.ReadFile(fsys, name); !errors.As(err, &perr) || perr.Op != "read" {
+			t.Errorf("%s: expected read error; got %v", name, err)
+		}
+	}
+
+	t.Run("root", func(t *testing.T) {
+		cl := &closeCountClient{memClient: newMemClient("dir/file.txt")}
+		cl.objects[""] = "root"
+
+		if _, err := fs.ReadFile(s3fs.New(cl, "test"), "."); err == nil {
+			t.Error("expected an error")
+		}
+
+		if n := cl.count("GetObject"); n != 0 {
+			t.Errorf("expected no GetObject calls; got %d", n)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		cl := &closeCountClient{memClient: newMemClient("dir/file.txt")}
+
+		if _, err := fs.ReadFile(s3fs.New(cl, "test"), "dir/file.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if cl.closed != 1 {
+			t.Errorf("expected the body to be closed once; got %d", cl.closed)
+		}
+	})
+}
+
+func TestListingConcurrency(t *testing.T) {
+	var files []string
+	for i := 0; i < 25; i++ {
+		files = append(files, fmt.Sprintf("dir/%02d.txt", i), fmt.Sprintf("dir/%02d/file.txt", i))
+	}
+
+	cl := newMemClient(files...)
+
+	want, err := fs.ReadDir(s3fs.New(cl, "test", s3fs.WithPageSize(3)), "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	fsys := s3fs.New(cl, "test", s3fs.WithPageSize(3), s3fs.WithListingConcurrency(4))
+
+	got, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(got) != 50 || !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	f, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if des, err := f.(fs.ReadDirFile).ReadDir(5); err != nil || len(des) != 5 {
+		t.Errorf("expected 5 entries; got %d %v", len(des), err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+}
+
+func TestConcurrentDirRead(t *testing.T) {
+	var files []string
+	for i := 0; i < 50; i++ {
+		files = append(files, fmt.Sprintf("dir/%02d.txt", i))
+	}
+
+	for _, opts := range [][]s3fs.Option{nil, {s3fs.WithListingConcurrency(2)}} {
+		fsys := s3fs.New(newMemClient(files...), "test", append(opts, s3fs.WithPageSize(7))...)
+
+		f, err := fsys.Open("dir")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		var (
+			mu   sync.Mutex
+			seen = make(map[string]int)
+			wg   sync.WaitGroup
+		)
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					des, err := f.(fs.ReadDirFile).ReadDir(10)
+
+					mu.Lock()
+					for _, de := range des {
+						seen[de.Name()]++
+					}
+					mu.Unlock()
+
+					if err != nil {
+						if !errors.Is(err, io.EOF) {
+							t.Error("expected io.EOF; got ", err)
+						}
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		if err := f.Close(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(seen) != len(files) {
+			t.Errorf("expected %d entries; got %d", len(files), len(seen))
+		}
+
+		for name, n := range seen {
+			if n != 1 {
+				t.Errorf("expected %s to be read once; got %d", name, n)
+			}
+		}
+	}
+}
+
+func TestWithDirMarkers(t *testing.T) {
+	type fileinfo struct {
+		name  string
+		isDir bool
+	}
+
+	names := func(des []fs.DirEntry) (out []fileinfo) {
+		for _, de := range des {
+			out = append(out, fileinfo{de.Name(), de.IsDir()})
+		}
+		return out
+	}
+
+	t.Run("own marker", func(t *testing.T) {
+		cl := newMemClient("dir/file.txt")
+		cl.objects["dir/"] = ""
+
+		des, err := fs.ReadDir(s3fs.New(cl, "test"), "dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []fileinfo{{"dir", false}, {"file.txt", false}}; !reflect.DeepEqual(names(des), want) {
+			t.Errorf("want %v; got %v", want, names(des))
+		}
+
+		des, err = fs.ReadDir(s3fs.New(cl, "test", s3fs.WithDirMarkers()), "dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []fileinfo{{"file.txt", false}}; !reflect.DeepEqual(names(des), want) {
+			t.Errorf("want %v; got %v", want, names(des))
+		}
+	})
+
+	t.Run("sub-directory marker", func(t *testing.T) {
+		out := newListOutput([]string{"b/"}, []string{"a/", "b/", "c.txt"})
+		out.Contents = append(out.Contents, types.Object{Key: ptr("d/"), Size: ptr[int64](1)})
+		out.IsTruncated = ptr(false)
+
+		f, err := s3fs.New(&mockClient{outs: []s3.ListObjectsV2Output{out}}, "test", s3fs.WithDirMarkers()).Open(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		des, err := f.(fs.ReadDirFile).ReadDir(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// "d/" is not empty, so it is not a marker.
+		want := []fileinfo{{"a", true}, {"b", true}, {"c.txt", false}, {"d", false}}
+		if !reflect.DeepEqual(names(des), want) {
+			t.Errorf("want %v; got %v", want, names(des))
+		}
+	})
+}
+
+func TestReadDirEmptyPages(t *testing.T) {
+	empty := s3.ListObjectsV2Output{
+		IsTruncated:           ptr(true),
+		NextContinuationToken: ptr("token"),
+	}
+	last := newListOutput(nil, []string{"a"})
+	last.IsTruncated = ptr(false)
+
+	t.Run("recovers", func(t *testing.T) {
+		cl := &mockClient{outs: []s3.ListObjectsV2Output{empty, empty, last}}
+
+		des, err := s3fs.New(cl, "test").ReadDir(".")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		if len(des) != 1 || des[0].Name() != "a" {
+			t.Errorf("want [a]; got %v", des)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		cl := &mockClient{outs: []s3.ListObjectsV2Output{empty, empty, empty, last}}
+		logger := &recordingLogger{}
+
+		_, err := s3fs.New(cl, "test", s3fs.WithLogger(logger)).ReadDir(".")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Op != "readdir" {
+			t.Errorf("want readdir *fs.PathError; got %v", err)
+		}
+
+		if cl.i != 3 {
+			t.Errorf("want 3 ListObjectsV2 calls; got %d", cl.i)
+		}
+
+		if !slices.Contains(logger.levels(), s3fs.LevelWarn) {
+			t.Errorf("expected a warning to be logged; got %v", logger.levels())
+		}
+	})
+}
+
+func TestReadDirContinuationToken(t *testing.T) {
+	// The tokens are equal to existing keys, which made ListObjects
+	// include the key again on the next page when used as a marker.
+	pages := map[string]s3.ListObjectsV2Output{
+		"": {
+			Contents:              newListOutput(nil, []string{"a", "b"}).Contents,
+			IsTruncated:           ptr(true),
+			NextContinuationToken: ptr("b"),
+		},
+		"b": {
+			Contents:              newListOutput(nil, []string{"c"}).Contents,
+			CommonPrefixes:        newListOutput([]string{"d/"}, nil).CommonPrefixes,
+			IsTruncated:           ptr(true),
+			NextContinuationToken: ptr("d/"),
+		},
+		"d/": {
+			Contents:    newListOutput(nil, []string{"e"}).Contents,
+			IsTruncated: ptr(false),
+		},
+	}
+
+	des, err := fs.ReadDir(s3fs.New(&tokenClient{pages: pages}, "test"), ".")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	var names []string
+	for _, de := range des {
+		names = append(names, de.Name())
+	}
+
+	if expected := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("want %v; got %v", expected, names)
+	}
+}
+
+// tokenClient returns pages by their continuation token.
+type tokenClient struct {
+	s3fs.Client
+	pages map[string]s3.ListObjectsV2Output
+}
+
+func (c *tokenClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out, ok := c.pages[aws.ToString(in.ContinuationToken)]
+	if !ok {
+		return nil, fmt.Errorf("unexpected continuation token: %q", aws.ToString(in.ContinuationToken))
+	}
+	return &out, nil
+}
+
+func TestWithPrefix(t *testing.T) {
+	cl := newMemClient("staging/a.txt", "staging/dir/b.txt", "production/c.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithPrefix("staging/"))
+
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Stat(fsys, "c.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	fi, err := fs.Stat(fsys, ".")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if fi.Name() != "staging" {
+		t.Errorf("want %q; got %q", "staging", fi.Name())
+	}
+
+	for _, prefix := range []string{"/staging", "../staging", "staging//"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q: expected New to panic", prefix)
+				}
+			}()
+			s3fs.New(cl, "test", s3fs.WithPrefix(prefix))
+		}()
+	}
+}
+
+func TestDerivedFS(t *testing.T) {
+	cl := newMemClient("2024/01/a.txt", "2024/02/b.txt", "other/c.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithPrefix("2024/01"), s3fs.WithStatCache(time.Minute, 0))
+
+	t.Run("WithPrefix", func(t *testing.T) {
+		des, err := fsys.WithPrefix("2024/02/").ReadDir(".")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(des) != 1 || des[0].Name() != "b.txt" {
+			t.Errorf("unexpected entries: %v", des)
+		}
+
+		if _, err := fsys.WithPrefix("").Stat("other/c.txt"); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+
+		if _, err := fsys.Stat("a.txt"); err != nil {
+			t.Error("expected the original fs to be unaffected; got ", err)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		fsys.WithPrefix("../a")
+	})
+
+	t.Run("WithBucket", func(t *testing.T) {
+		cl2 := newMemClient("2024/01/z.txt")
+		fsys := s3fs.New(&bucketClient{buckets: map[string]*memClient{"test": cl, "test2": cl2}}, "test",
+			s3fs.WithPrefix("2024/01"), s3fs.WithStatCache(time.Minute, 0))
+
+		if _, err := fsys.Stat("a.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		fsys2 := fsys.WithBucket("test2")
+
+		if _, err := fsys2.Stat("a.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected the stat cache not to be shared; got %v", err)
+		}
+
+		if _, err := fsys2.Stat("z.txt"); err != nil {
+			t.Error("expected err to be nil; got ", err)
+		}
+	})
+}
+
+func TestS3FSString(t *testing.T) {
+	fixtures := []struct {
+		fsys   *s3fs.S3FS
+		prefix string
+		str    string
+	}{
+		{fsys: s3fs.New(newMemClient(), "test"), prefix: "", str: "s3://test/"},
+		{fsys: s3fs.New(newMemClient(), "test", s3fs.WithPrefix("a/b")), prefix: "a/b/", str: "s3://test/a/b/"},
+	}
+
+	for _, f := range fixtures {
+		if f.fsys.Bucket() != "test" {
+			t.Errorf("want bucket test; got %s", f.fsys.Bucket())
+		}
+
+		if f.fsys.Prefix() != f.prefix {
+			t.Errorf("want prefix %q; got %q", f.prefix, f.fsys.Prefix())
+		}
+
+		if s := fmt.Sprint(f.fsys); s != f.str {
+			t.Errorf("want %s; got %s", f.str, s)
+		}
+	}
+}
+
+// bucketClient routes calls to a memClient per bucket.
+type bucketClient struct {
+	s3fs.Client
+	buckets map[string]*memClient
+}
+
+func (c *bucketClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return c.buckets[aws.ToString(in.Bucket)].HeadObject(ctx, in, optFns...)
+}
+
+func (c *bucketClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return c.buckets[aws.ToString(in.Bucket)].ListObjectsV2(ctx, in, optFns...)
+}
+
+func TestWithDelimiter(t *testing.T) {
+	cl := newMemClient("logs|2024|a/b.txt", "logs|2024|c.txt", "logs|2025|d.txt", "other.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithDelimiter("|"))
+
+	des, err := fs.ReadDir(fsys, "logs|2024")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	var names []string
+	for _, de := range des {
+		names = append(names, de.Name())
+	}
+
+	if expected := []string{"a/b.txt", "c.txt"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("want %v; got %v", expected, names)
+	}
+
+	fi, err := fs.Stat(fsys, "logs|2024|a/b.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if fi.Name() != "a/b.txt" {
+		t.Errorf("want %q; got %q", "a/b.txt", fi.Name())
+	}
+
+	fi, err = fs.Stat(fsys, "logs")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if !fi.IsDir() {
+		t.Error("expected logs to be a directory")
+	}
+
+	sub, err := fs.Sub(fsys, "logs")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	data, err := fs.ReadFile(sub, "2025|d.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	if _, err := fs.Stat(fsys, "logs||c.txt"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+	}
+}
+
+func TestFileInfoSys(t *testing.T) {
+	cl := newMemClient()
+	fsys := s3fs.New(cl, "test")
+
+	if err := fsys.WriteFile("dir/data.json", []byte("{}"), 0644); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	fi, err := fs.Stat(fsys, "dir/data.json")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	m, ok := fi.Sys().(*s3fs.S3ObjectMeta)
+	if !ok {
+		t.Fatalf("expected *s3fs.S3ObjectMeta; got %T", fi.Sys())
+	}
+
+	if m.ContentType != "application/json" || m.ETag == "" {
+		t.Errorf("unexpected metadata: %+v", m)
+	}
+
+	f, err := fsys.Open("dir/data.json")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	fi, err = f.Stat()
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if m, ok := fi.Sys().(*s3fs.S3ObjectMeta); !ok || m.ContentType != "application/json" {
+		t.Errorf("expected metadata from GetObject; got %+v", fi.Sys())
+	}
+
+	fi, err = fs.Stat(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if fi.Sys() != nil {
+		t.Errorf("expected directories to have no metadata; got %v", fi.Sys())
+	}
+}
+
+func TestReadDirSys(t *testing.T) {
+	cl := newMemClient("dir/a.txt", "dir/b.txt", "dir/sub/c.txt")
+	fsys := s3fs.New(cl, "test")
+
+	des, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	heads := cl.count("HeadObject")
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if de.IsDir() {
+			if fi.Sys() != nil {
+				t.Errorf("%s: expected directories to have no metadata; got %v", de.Name(), fi.Sys())
+			}
+			continue
+		}
+
+		m, ok := fi.Sys().(*s3fs.S3ObjectMeta)
+		if !ok {
+			t.Fatalf("%s: expected *s3fs.S3ObjectMeta; got %T", de.Name(), fi.Sys())
+		}
+
+		if m.StorageClass == "" || m.ETag == "" {
+			t.Errorf("%s: unexpected metadata: %+v", de.Name(), m)
+		}
+	}
+
+	if n := cl.count("HeadObject"); n != heads {
+		t.Errorf("expected no HeadObject calls; got %d", n-heads)
+	}
+}
+
+func TestReadDirRestoreStatus(t *testing.T) {
+	expiry := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cl := &restoreClient{
+		memClient: newMemClient("dir/archived.txt", "dir/ongoing.txt", "dir/file.txt"),
+		status: map[string]*types.RestoreStatus{
+			"dir/archived.txt": {IsRestoreInProgress: ptr(false), RestoreExpiryDate: &expiry},
+			"dir/ongoing.txt":  {IsRestoreInProgress: ptr(true)},
+		},
+	}
+	fsys := s3fs.New(cl, "test")
+
+	want := map[string]string{
+		"archived.txt": `ongoing-request="false", expiry-date="Tue, 02 Jan 2024 03:04:05 GMT"`,
+		"ongoing.txt":  `ongoing-request="true"`,
+		"file.txt":     "",
+	}
+
+	des, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if got := fi.Sys().(*s3fs.S3ObjectMeta).RestoreStatus; got != want[de.Name()] {
+			t.Errorf("%s: want %q; got %q", de.Name(), want[de.Name()], got)
+		}
+	}
+
+	// listings without a delimiter request it as well.
+	if _, err := fsys.RecursiveList(context.Background(), "dir"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if cl.missing.Load() {
+		t.Error("expected every ListObjectsV2 call to request the restore status")
+	}
+}
+
+// restoreClient returns the restore status of objects if ListObjectsV2
+// requests it, like S3.
+type restoreClient struct {
+	*memClient
+	status  map[string]*types.RestoreStatus
+	missing atomic.Bool
+}
+
+func (c *restoreClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out, err := c.memClient.ListObjectsV2(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(in.OptionalObjectAttributes, types.OptionalObjectAttributesRestoreStatus) {
+		c.missing.Store(true)
+		return out, nil
+	}
+
+	for i, o := range out.Contents {
+		out.Contents[i].RestoreStatus = c.status[aws.ToString(o.Key)]
+	}
+	return out, nil
+}
+
+func TestETagFile(t *testing.T) {
+	for _, opts := range [][]s3fs.Option{nil, {s3fs.WithReadSeeker}} {
+		cl := newMemClient("file.txt")
+		fsys := s3fs.New(cl, "test", opts...)
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		ef, ok := f.(s3fs.ETagFile)
+		if !ok {
+			t.Fatalf("expected file to implement s3fs.ETagFile; got %T", f)
+		}
+
+		if want := etag("content"); ef.ETag() != want {
+			t.Errorf("want %q; got %q", want, ef.ETag())
+		}
+
+		if n := cl.count("HeadObject"); n != 0 {
+			t.Errorf("expected no HeadObject calls; got %d", n)
+		}
+	}
+}
+
+func TestFileWriteTo(t *testing.T) {
+	for _, opts := range [][]s3fs.Option{nil, {s3fs.WithReadSeeker}} {
+		cl := newMemClient()
+		cl.objects["file.txt"] = "0123456789"
+		fsys := s3fs.New(cl, "test", opts...)
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		if _, ok := f.(io.WriterTo); !ok {
+			t.Fatalf("expected file to implement io.WriterTo; got %T", f)
+		}
+
+		if _, err := f.Read(make([]byte, 2)); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		var buf bytes.Buffer
+		n, err := io.Copy(&buf, f)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if n != 8 || buf.String() != "23456789" {
+			t.Errorf("want 8 bytes %q; got %d bytes %q", "23456789", n, buf.String())
+		}
+
+		if s, ok := f.(io.Seeker); ok {
+			if off, err := s.Seek(0, io.SeekCurrent); err != nil || off != 10 {
+				t.Errorf("want offset 10; got %d (err: %v)", off, err)
+			}
+		}
+	}
+}
+
+func TestFileStatFallback(t *testing.T) {
+	cl := &chunkedClient{newMemClient("file.txt")}
+	fsys := s3fs.New(cl, "test")
+
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if fi.Name() != "file.txt" || fi.Size() != int64(len("content")) {
+		t.Errorf("want file.txt of size %d; got %s of size %d", len("content"), fi.Name(), fi.Size())
+	}
+
+	if n := cl.count("HeadObject"); n != 1 {
+		t.Errorf("expected 1 HeadObject call; got %d", n)
+	}
+}
+
+// chunkedClient returns GetObject responses without a Content-Length, as if
+// they were sent with chunked transfer encoding.
+type chunkedClient struct {
+	*memClient
+}
+
+func (c *chunkedClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.ContentLength = nil
+	return out, nil
+}
+
+func TestOpenStreams(t *testing.T) {
+	const size = 100 << 20
+
+	fsys := s3fs.New(&largeFileClient{size: size}, "test")
+
+	f, err := fsys.Open("large.bin")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	var (
+		n int64
+		p = make([]byte, 1<<10)
+	)
+	for {
+		m, err := f.Read(p)
+		n += int64(m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+	}
+
+	runtime.ReadMemStats(&after)
+
+	if n != size {
+		t.Errorf("want %d bytes; got %d", size, n)
+	}
+
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size/10 {
+		t.Errorf("expected the file not to be buffered; allocated %d bytes", alloc)
+	}
+}
+
+// largeFileClient returns an object of size bytes, which are generated
+// while it is read, so that the test itself does not hold it in memory.
+type largeFileClient struct {
+	s3fs.Client
+	size int64
+}
+
+func (c *largeFileClient) GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(&io.LimitedReader{R: zeroReader{}, N: c.size}),
+		ContentLength: ptr(c.size),
+		LastModified:  ptr(time.Time{}),
+		ETag:          ptr("etag"),
+	}, nil
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestSeekForward(t *testing.T) {
+	const data = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	fixtures := []struct {
+		desc     string
+		opts     []s3fs.Option
+		getCalls int
+	}{
+		{desc: "default", opts: []s3fs.Option{s3fs.WithReadSeeker}, getCalls: 1},
+		{desc: "small threshold", opts: []s3fs.Option{s3fs.WithReadSeeker, s3fs.WithSeekForwardThreshold(5)}, getCalls: 2},
+		{desc: "disabled", opts: []s3fs.Option{s3fs.WithReadSeeker, s3fs.WithSeekForwardThreshold(0)}, getCalls: 3},
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.desc, func(t *testing.T) {
+			cl := newMemClient()
+			cl.objects["file.txt"] = data
+			fsys := s3fs.New(cl, "test", fixture.opts...)
+
+			f, err := fsys.Open("file.txt")
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+			defer f.Close()
+
+			s := f.(io.ReadSeeker)
+			p := make([]byte, 2)
+
+			for _, off := range []int64{4, 20} {
+				if _, err := s.Seek(off, io.SeekStart); err != nil {
+					t.Fatal("expected err to be nil; got ", err)
+				}
+
+				if _, err := io.ReadFull(s, p); err != nil {
+					t.Fatal("expected err to be nil; got ", err)
+				}
+
+				if want := data[off : off+2]; string(p) != want {
+					t.Errorf("want %q; got %q", want, p)
+				}
+			}
+
+			if n := cl.count("GetObject"); n != fixture.getCalls {
+				t.Errorf("expected %d GetObject calls; got %d", fixture.getCalls, n)
+			}
+		})
+	}
+}
+
+func TestSeekDetectReplace(t *testing.T) {
+	for _, detect := range []bool{false, true} {
+		cl := &modTimeClient{memClient: newMemClient("file.txt"), modTime: time.Unix(100, 0)}
+
+		opts := []s3fs.Option{s3fs.WithReadSeeker, s3fs.WithSeekForwardThreshold(0)}
+		if detect {
+			opts = append(opts, s3fs.WithSeekDetectReplace())
+		}
+
+		f, err := s3fs.New(cl, "test", opts...).Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		// the file is replaced with the same content.
+		cl.modTime = time.Unix(200, 0)
+
+		_, err = f.(io.Seeker).Seek(2, io.SeekStart)
+		switch {
+		case detect && !errors.Is(err, fs.ErrNotExist):
+			t.Errorf("expected ErrNotExist; got %v", err)
+		case !detect && err != nil:
+			t.Errorf("expected err to be nil; got %v", err)
+		}
+
+		if detect && (cl.ifUnmodifiedSince == nil || !cl.ifUnmodifiedSince.Equal(time.Unix(100, 0))) {
+			t.Errorf("expected If-Unmodified-Since to be the open time; got %v", cl.ifUnmodifiedSince)
+		}
+	}
+}
+
+func TestSeekDeleted(t *testing.T) {
+	cl := newMemClient("file.txt")
+
+	f, err := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithSeekForwardThreshold(0)).Open("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	delete(cl.objects, "file.txt")
+
+	_, err = f.(io.Seeker).Seek(2, io.SeekStart)
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "deleted") {
+		t.Errorf("expected ErrNotExist; got %v", err)
+	}
+}
+
+// modTimeClient returns objects modified at modTime.
+type modTimeClient struct {
+	*memClient
+	modTime           time.Time
+	ifUnmodifiedSince *time.Time
+}
+
+func (c *modTimeClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.ifUnmodifiedSince = in.IfUnmodifiedSince
+
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.LastModified = ptr(c.modTime)
+	return out, nil
+}
+
+func TestSeekSize(t *testing.T) {
+	const data = "0123456789"
+
+	cl := &noModTimeClient{memClient: newMemClient()}
+	cl.objects["file.txt"] = data
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithSeekForwardThreshold(0))
+
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	s := f.(io.ReadSeeker)
+
+	for _, off := range []int64{-3, -8, -1} {
+		n, err := s.Seek(off, io.SeekEnd)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if want := int64(len(data)) + off; n != want {
+			t.Errorf("want offset %d; got %d", want, n)
+		}
+	}
+
+	b, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(b) != "9" {
+		t.Errorf("want %q; got %q", "9", b)
+	}
+
+	if n := cl.count("HeadObject"); n != 0 {
+		t.Errorf("expected no HeadObject calls; got %d", n)
+	}
+}
+
+// noModTimeClient omits LastModified from GetObject responses, so that
+// Stat of open files has to call HeadObject.
+type noModTimeClient struct {
+	*memClient
+}
+
+func (c *noModTimeClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.LastModified = nil
+	return out, nil
+}
+
+func TestFileClosed(t *testing.T) {
+	for _, opts := range [][]s3fs.Option{nil, {s3fs.WithReadSeeker}} {
+		fsys := s3fs.New(newMemClient("file.txt"), "test", opts...)
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := f.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+			t.Errorf("Read: want %v; got %v", fs.ErrClosed, err)
+		}
+
+		if _, err := f.Stat(); !errors.Is(err, fs.ErrClosed) {
+			t.Errorf("Stat: want %v; got %v", fs.ErrClosed, err)
+		}
+
+		if _, err := f.(io.ReaderAt).ReadAt(make([]byte, 1), 0); !errors.Is(err, fs.ErrClosed) {
+			t.Errorf("ReadAt: want %v; got %v", fs.ErrClosed, err)
+		}
+
+		if s, ok := f.(io.Seeker); ok {
+			if _, err := s.Seek(1, io.SeekStart); !errors.Is(err, fs.ErrClosed) {
+				t.Errorf("Seek: want %v; got %v", fs.ErrClosed, err)
+			}
+		}
+	}
+}
+
+func TestFileCloseTwice(t *testing.T) {
+	for _, opts := range [][]s3fs.Option{nil, {s3fs.WithReadSeeker}} {
+		cl := &closeCountClient{memClient: newMemClient("file.txt")}
+		fsys := s3fs.New(cl, "test", opts...)
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Error("expected second Close to return nil; got ", err)
+		}
+
+		if cl.closed != 1 {
+			t.Errorf("expected the body to be closed once; got %d", cl.closed)
+		}
+	}
+}
+
+// closeCountClient counts how many times the bodies of GetObject responses
+// were closed. Closing a body twice fails.
+type closeCountClient struct {
+	*memClient
+	closed int
+}
+
+func (c *closeCountClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.Body = &countingCloser{ReadCloser: out.Body, closed: &c.closed}
+	return out, nil
+}
+
+type countingCloser struct {
+	io.ReadCloser
+	closed *int
+	done   bool
+}
+
+func (c *countingCloser) Close() error {
+	if c.done {
+		return errors.New("body closed twice")
+	}
+	c.done = true
+	*c.closed++
+	return c.ReadCloser.Close()
+}
+
+func TestPermissionErr(t *testing.T) {
+	for _, code := range []string{"AccessDenied", "AllAccessDisabled", "InvalidAccessKeyId", "Forbidden"} {
+		t.Run(code, func(t *testing.T) {
+			fsys := s3fs.New(&deniedClient{code: code}, "test")
+
+			if _, err := fsys.Stat("file.txt"); !errors.Is(err, fs.ErrPermission) {
+				t.Errorf("Stat: want %v; got %v", fs.ErrPermission, err)
 			}
 
-			fi, err := f.Stat()
-			if err != nil {
-				t.Fatal("expected err to be nil; got ", err)
+			if _, err := fsys.Open("file.txt"); !errors.Is(err, fs.ErrPermission) {
+				t.Errorf("Open: want %v; got %v", fs.ErrPermission, err)
 			}
 
-			if !fi.IsDir() {
-				t.Fatal("expected the file to be a directory")
+			if _, err := fs.ReadDir(fsys, "dir"); !errors.Is(err, fs.ErrPermission) {
+				t.Errorf("ReadDir: want %v; got %v", fs.ErrPermission, err)
 			}
+		})
+	}
+}
 
-			var fis [][]fileinfo
-			for {
-				files, err := f.(fs.ReadDirFile).ReadDir(test.n)
-				if err != nil && !errors.Is(err, io.EOF) {
-					t.Fatal("did not expect err:", err)
-				}
+// deniedClient fails every call with the given error code.
+type deniedClient struct {
+	s3fs.Client
+	code string
+}
 
-				if len(files) > 0 {
-					var out []fileinfo
-					for _, f := range files {
-						out = append(out, fileinfo{f.Name(), f.IsDir()})
-					}
-					fis = append(fis, out)
-				}
+func (c *deniedClient) err() error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+			Err:      &smithy.GenericAPIError{Code: c.code},
+		},
+	}
+}
 
-				if test.n <= 0 || errors.Is(err, io.EOF) {
-					break
-				}
+func (c *deniedClient) GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, c.err()
+}
+
+func (c *deniedClient) HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, c.err()
+}
+
+func (c *deniedClient) ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return nil, c.err()
+}
+
+func TestCustomErrorCodes(t *testing.T) {
+	fsys := s3fs.New(&codeClient{code: "NoSuchObject"}, "test")
+	if _, err := fsys.Stat("file.txt"); errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected unknown code not to be fs.ErrNotExist")
+	}
+
+	fsys = s3fs.New(&codeClient{code: "NoSuchObject"}, "test", s3fs.WithNotFoundErrorCodes("NoSuchObject"))
+	if _, err := fsys.Stat("file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat: want %v; got %v", fs.ErrNotExist, err)
+	}
+	if _, err := fsys.Open("file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open: want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	fsys = s3fs.New(&codeClient{code: "Unauthorized"}, "test", s3fs.WithPermissionErrorCodes("Unauthorized"))
+	if _, err := fsys.Stat("file.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Stat: want %v; got %v", fs.ErrPermission, err)
+	}
+	if _, err := fsys.Open("file.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Open: want %v; got %v", fs.ErrPermission, err)
+	}
+
+	// the defaults still apply.
+	fsys = s3fs.New(&codeClient{code: "AccessDenied"}, "test", s3fs.WithPermissionErrorCodes("Unauthorized"))
+	if _, err := fsys.Stat("file.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Stat: want %v; got %v", fs.ErrPermission, err)
+	}
+}
+
+// codeClient fails every call with the given error code and no HTTP
+// response.
+type codeClient struct {
+	s3fs.Client
+	code string
+}
+
+func (c *codeClient) GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: c.code}
+}
+
+func (c *codeClient) HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: c.code}
+}
+
+func (c *codeClient) ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return nil, &smithy.GenericAPIError{Code: c.code}
+}
+
+func TestPathErrorOp(t *testing.T) {
+	clients := []struct {
+		desc string
+		cl   s3fs.Client
+		err  error
+	}{
+		{desc: "not found", cl: newMemClient(), err: fs.ErrNotExist},
+		{desc: "permission", cl: &deniedClient{code: "AccessDenied"}, err: fs.ErrPermission},
+		{desc: "other", cl: &codeClient{code: "InternalError"}},
+	}
+
+	ops := []struct {
+		op string
+		fn func(fsys fs.FS, name string) error
+	}{
+		{
+			op: "open",
+			fn: func(fsys fs.FS, name string) error {
+				_, err := fsys.Open(name)
+				return err
+			},
+		},
+		{
+			op: "stat",
+			fn: func(fsys fs.FS, name string) error {
+				_, err := fs.Stat(fsys, name)
+				return err
+			},
+		},
+		{
+			op: "readdir",
+			fn: func(fsys fs.FS, name string) error {
+				_, err := fs.ReadDir(fsys, name)
+				return err
+			},
+		},
+		{
+			op: "open",
+			fn: func(fsys fs.FS, name string) error {
+				_, err := fs.ReadFile(fsys, name)
+				return err
+			},
+		},
+	}
+
+	check := func(t *testing.T, err error, op, name string, want error) {
+		t.Helper()
+
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) {
+			t.Fatalf("want *fs.PathError; got %T: %v", err, err)
+		}
+		if pathErr.Op != op {
+			t.Errorf("want op %q; got %q", op, pathErr.Op)
+		}
+		if pathErr.Path != name {
+			t.Errorf("want path %q; got %q", name, pathErr.Path)
+		}
+		if want != nil && !errors.Is(err, want) {
+			t.Errorf("want %v; got %v", want, err)
+		}
+	}
+
+	for _, o := range ops {
+		for _, c := range clients {
+			t.Run(o.op+" "+c.desc, func(t *testing.T) {
+				fsys := s3fs.New(c.cl, "test")
+				check(t, o.fn(fsys, "dir/file.txt"), o.op, "dir/file.txt", c.err)
+			})
+		}
+
+		t.Run(o.op+" invalid", func(t *testing.T) {
+			fsys := s3fs.New(newMemClient(), "test")
+			check(t, o.fn(fsys, "/dir"), o.op, "/dir", fs.ErrInvalid)
+		})
+	}
+
+	t.Run("read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		fsys := s3fs.New(newMemClient("file.txt"), "test").WithContext(ctx)
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		cancel()
+		_, err = f.Read(make([]byte, 1))
+		check(t, err, "read", "file.txt", context.Canceled)
+	})
+
+	seekFixtures := []struct {
+		desc   string
+		offset int64
+		whence int
+		change func(cl *memClient)
+		err    error
+	}{
+		{desc: "not found", offset: 1, whence: io.SeekStart, change: func(cl *memClient) { delete(cl.objects, "file.txt") }, err: fs.ErrNotExist},
+		{desc: "changed", offset: 1, whence: io.SeekStart, change: func(cl *memClient) { cl.objects["file.txt"] = "changed" }, err: fs.ErrNotExist},
+		{desc: "negative offset", offset: -1, whence: io.SeekStart},
+		{desc: "invalid whence", offset: 0, whence: 3},
+	}
+
+	for _, f := range seekFixtures {
+		t.Run("seek "+f.desc, func(t *testing.T) {
+			cl := newMemClient("file.txt")
+			fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithSeekForwardThreshold(0))
+
+			file, err := fsys.Open("file.txt")
+			if err != nil {
+				t.Fatal(err)
 			}
+			defer file.Close()
 
-			if !reflect.DeepEqual(fis, test.expected) {
-				t.Errorf("want %v; got %v", test.expected, fis)
+			if f.change != nil {
+				f.change(cl)
 			}
+
+			_, err = file.(io.Seeker).Seek(f.offset, f.whence)
+			check(t, err, "seek", "file.txt", f.err)
 		})
 	}
 }
 
 type mockClient struct {
 	s3fs.Client
-	outs []s3.ListObjectsOutput
+	outs []s3.ListObjectsV2Output
 	i    int
 }
 
-func (c *mockClient) ListObjects(ctx context.Context, in *s3.ListObjectsInput, _ ...func(*s3.Options)) (*s3.ListObjectsOutput, error) {
+func (c *mockClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
 	defer func() { c.i++ }()
 	if c.i < len(c.outs) {
 		return &c.outs[c.i], nil
 	}
 
-	return &s3.ListObjectsOutput{
+	return &s3.ListObjectsV2Output{
 		IsTruncated: ptr(false),
 	}, nil
 }
 
-func newListOutput(dirs, files []string) (out s3.ListObjectsOutput) {
+func newListOutput(dirs, files []string) (out s3.ListObjectsV2Output) {
 	for _, d := range dirs {
 		out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{
 			Prefix: ptr(d),
@@ -1292,6 +3060,265 @@ func newListOutput(dirs, files []string) (out s3.ListObjectsOutput) {
 	return out
 }
 
+// memClient is an in-memory Client for tests that do not need a running
+// S3 server.
+type memClient struct {
+	s3fs.Client
+
+	mu           sync.Mutex
+	objects      map[string]string
+	contentTypes map[string]string
+	calls        map[string]int
+}
+
+func newMemClient(files ...string) *memClient {
+	c := &memClient{
+		objects:      make(map[string]string),
+		contentTypes: make(map[string]string),
+		calls:        make(map[string]int),
+	}
+	for _, f := range files {
+		c.objects[f] = "content"
+	}
+	return c
+}
+
+func (c *memClient) count(op string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[op]
+}
+
+func (c *memClient) contentType(key *string) *string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ct, ok := c.contentTypes[aws.ToString(key)]; ok {
+		return &ct
+	}
+	return nil
+}
+
+func (c *memClient) get(ctx context.Context, op string, key *string) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[op]++
+	data, ok := c.objects[aws.ToString(key)]
+	return data, ok, nil
+}
+
+func (c *memClient) GetObject(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok, err := c.get(ctx, "GetObject", in.Key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+
+	if in.IfMatch != nil && *in.IfMatch != etag(data) {
+		return nil, responseError(http.StatusPreconditionFailed)
+	}
+
+	body := data
+	if in.Range != nil {
+		var start, end int
+		if n, _ := fmt.Sscanf(*in.Range, "bytes=%d-%d", &start, &end); n < 2 || end >= len(data) {
+			end = len(data) - 1
+		}
+		if start >= len(data) {
+			return nil, responseError(http.StatusRequestedRangeNotSatisfiable)
+		}
+		body = data[start : end+1]
+	}
+
+	var contentRange *string
+	if in.Range != nil {
+		contentRange = ptr(fmt.Sprintf("bytes %d-%d/%d", len(data)-len(body), len(data)-1, len(data)))
+	}
+
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: ptr(int64(len(body))),
+		ContentRange:  contentRange,
+		ContentType:   c.contentType(in.Key),
+		LastModified:  ptr(time.Time{}),
+		ETag:          ptr(etag(data)),
+	}, nil
+}
+
+func (c *memClient) PutObject(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls["PutObject"]++
+	c.objects[aws.ToString(in.Key)] = string(data)
+	c.contentTypes[aws.ToString(in.Key)] = aws.ToString(in.ContentType)
+
+	return &s3.PutObjectOutput{ETag: ptr(etag(string(data)))}, nil
+}
+
+func (c *memClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls["DeleteObject"]++
+	delete(c.objects, aws.ToString(in.Key))
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (c *memClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls["DeleteObjects"]++
+
+	if len(in.Delete.Objects) > 1000 {
+		return nil, responseError(http.StatusBadRequest)
+	}
+
+	for _, o := range in.Delete.Objects {
+		delete(c.objects, aws.ToString(o.Key))
+	}
+
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func (c *memClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	src, err := url.PathUnescape(aws.ToString(in.CopySource))
+	if err != nil {
+		return nil, err
+	}
+	_, src, _ = strings.Cut(src, "/")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls["CopyObject"]++
+
+	data, ok := c.objects[src]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	c.objects[aws.ToString(in.Key)] = data
+
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (c *memClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	data, ok, err := c.get(ctx, "HeadObject", in.Key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+
+	return &s3.HeadObjectOutput{
+		ContentLength: ptr(int64(len(data))),
+		ContentType:   c.contentType(in.Key),
+		LastModified:  ptr(time.Time{}),
+		ETag:          ptr(etag(data)),
+	}, nil
+}
+
+func (c *memClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls["ListObjectsV2"]++
+
+	keys := make([]string, 0, len(c.objects))
+	for k := range c.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	prefix, delim := aws.ToString(in.Prefix), aws.ToString(in.Delimiter)
+	maxKeys := int(aws.ToInt32(in.MaxKeys))
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	var (
+		out  s3.ListObjectsV2Output
+		seen = make(map[string]bool)
+		n    int
+	)
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) || k <= aws.ToString(in.ContinuationToken) {
+			continue
+		}
+
+		if n == maxKeys {
+			out.IsTruncated = ptr(true)
+			return &out, nil
+		}
+
+		if i := strings.Index(k[len(prefix):], delim); delim != "" && i >= 0 {
+			p := k[:len(prefix)+i+len(delim)]
+			if !seen[p] {
+				seen[p] = true
+				out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: ptr(p)})
+				out.NextContinuationToken = ptr(p + "\xff")
+				n++
+			}
+			continue
+		}
+
+		out.Contents = append(out.Contents, types.Object{
+			Key:          ptr(k),
+			Size:         ptr(int64(len(c.objects[k]))),
+			LastModified: ptr(time.Time{}),
+			ETag:         ptr(etag(c.objects[k])),
+			StorageClass: types.ObjectStorageClassStandard,
+		})
+		out.NextContinuationToken = ptr(k)
+		n++
+	}
+
+	out.IsTruncated = ptr(false)
+	return &out, nil
+}
+
+func responseError(status int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      errors.New(http.StatusText(status)),
+		},
+	}
+}
+
+func etag(data string) string {
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", md5.Sum([]byte(data))))
+}
+
 type Client interface {
 	s3fs.Client
 }
@@ -1369,9 +3396,9 @@ func createBucket(t *testing.T, cl *s3.Client, bucket string) {
 func cleanBucket(t *testing.T, cl *s3.Client, bucket string) {
 	t.Helper()
 
-	out, err := cl.ListObjects(
+	out, err := cl.ListObjectsV2(
 		context.Background(),
-		&s3.ListObjectsInput{
+		&s3.ListObjectsV2Input{
 			Bucket: ptr(bucket),
 		})
 	if err != nil {
@@ -1398,28 +3425,16 @@ func envDefault(env, def string) string {
 	return os.Getenv(env)
 }
 
-type client struct {
-	MaxKeys *int32
-	s3fs.Client
-}
-
-func (c *client) ListObjects(ctx context.Context, in *s3.ListObjectsInput, _ ...func(*s3.Options)) (*s3.ListObjectsOutput, error) {
-	if c.MaxKeys != nil {
-		in.MaxKeys = c.MaxKeys
-	}
-	return c.Client.ListObjects(ctx, in)
-}
-
 type modTimeTruncateClient struct {
 	Client
 }
 
-// Minio returns modTime that includes microseconds if data comes from ListObjects
+// Minio returns modTime that includes microseconds if data comes from ListObjectsV2
 // while data coming from GetObject's modTimes are accurate down to seconds.
 // To make this test pass while using Minio we build this client that truncates
 // modTimes to Second.
-func (c *modTimeTruncateClient) ListObjects(ctx context.Context, in *s3.ListObjectsInput, _ ...func(*s3.Options)) (*s3.ListObjectsOutput, error) {
-	out, err := c.Client.ListObjects(context.Background(), in)
+func (c *modTimeTruncateClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out, err := c.Client.ListObjectsV2(context.Background(), in)
 	if err != nil {
 		return out, err
 	}
@@ -1440,9 +3455,9 @@ type metricClient struct {
 	Client
 }
 
-func (c *metricClient) ListObjects(ctx context.Context, in *s3.ListObjectsInput, _ ...func(*s3.Options)) (*s3.ListObjectsOutput, error) {
+func (c *metricClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
 	atomic.AddInt64(&listC, 1)
-	return c.Client.ListObjects(ctx, in)
+	return c.Client.ListObjectsV2(ctx, in)
 }
 
 func (c *metricClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
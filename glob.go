@@ -0,0 +1,266 @@
+package s3fs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var _ fs.GlobFS = (*S3FS)(nil)
+
+// Glob implements fs.GlobFS.
+//
+// If only the last element of pattern has wildcards, Glob lists its
+// directory with a single delimited listing. Otherwise, instead of reading
+// every directory that may match pattern, Glob lists all objects under the
+// longest directory of pattern without wildcards with a single recursive
+// listing and matches the results locally.
+//
+// Unlike fs.Glob, it returns errors from listing the bucket.
+func (f *S3FS) Glob(pattern string) ([]string, error) {
+	if f.mounts != nil {
+		return fs.Glob(f.multi(), pattern)
+	}
+
+	// Check the pattern is well-formed.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	if !hasMeta(pattern) {
+		if _, err := f.stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	elems := strings.Split(pattern, f.delim)
+
+	var dir []string
+	for i, elem := range elems {
+		if hasMeta(elem) {
+			dir = elems[:i]
+			break
+		}
+	}
+
+	var (
+		matches []string
+		err     error
+	)
+	if len(dir) == len(elems)-1 {
+		matches, err = f.globDir(dir, elems[len(elems)-1])
+	} else {
+		matches, err = f.globAll(dir, elems)
+	}
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "glob",
+			Path: pattern,
+			Err:  err,
+		}
+	}
+	return matches, nil
+}
+
+// globDir returns the names of the entries of the directory with the path
+// elements parent that match pattern, which must not contain the delimiter.
+func (f *S3FS) globDir(parent []string, pattern string) ([]string, error) {
+	name := "."
+	if len(parent) > 0 {
+		name = strings.Join(parent, f.delim)
+	}
+
+	d := &dir{
+		fsys: f,
+		fileInfo: fileInfo{
+			name:  name,
+			mode:  fs.ModeDir,
+			delim: f.delim,
+		},
+	}
+	des, err := d.ReadDir(-1)
+	if err != nil {
+		// Like fs.Glob, a missing directory or a file has no matches.
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, errNotDir) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var matches []string
+	for _, de := range des {
+		if ok, _ := path.Match(pattern, de.Name()); !ok {
+			continue
+		}
+
+		if name == "." {
+			matches = append(matches, de.Name())
+		} else {
+			matches = append(matches, name+f.delim+de.Name())
+		}
+	}
+	return matches, nil
+}
+
+// globAll matches the pattern elements against a recursive listing of the
+// directory dir.
+func (f *S3FS) globAll(dir, elems []string) ([]string, error) {
+	prefix := ""
+	if len(dir) > 0 {
+		prefix = strings.Join(dir, f.delim) + f.delim
+	}
+
+	seen := make(map[string]bool)
+	var matches []string
+	err := f.listAll(f.ctx, prefix, func(name string, _ types.Object) error {
+		parts := strings.Split(strings.TrimSuffix(name, f.delim), f.delim)
+		if len(parts) < len(elems) {
+			return nil
+		}
+
+		// Keys deeper than the pattern imply directories that may match it.
+		parts = parts[:len(elems)]
+		name = strings.Join(parts, f.delim)
+		if seen[name] || !f.validPath(name) {
+			return nil
+		}
+		seen[name] = true
+
+		if matchElems(elems, parts) {
+			matches = append(matches, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// GlobRecursive is like fs.Glob, but an element "**" of pattern matches
+// zero or more directories, e.g. "backups/**/*.sql" matches both
+// "backups/db.sql" and "backups/2024/01/db.sql".
+//
+// Patterns with "**" are matched against a single recursive listing of
+// the longest directory of pattern without wildcards. Other patterns are
+// passed to fs.Glob.
+func GlobRecursive(fsys *S3FS, pattern string) ([]string, error) {
+	// Check the pattern is well-formed.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	elems := strings.Split(pattern, fsys.delim)
+	if !hasDoubleStar(elems) {
+		return fs.Glob(fsys, pattern)
+	}
+
+	var dir []string
+	for i, elem := range elems {
+		if hasMeta(elem) {
+			dir = elems[:i]
+			break
+		}
+	}
+
+	prefix := ""
+	if len(dir) > 0 {
+		prefix = strings.Join(dir, fsys.delim) + fsys.delim
+	}
+
+	seen := make(map[string]bool)
+	var matches []string
+	err := fsys.listAll(fsys.ctx, prefix, func(name string, _ types.Object) error {
+		parts := strings.Split(strings.TrimSuffix(name, fsys.delim), fsys.delim)
+
+		// Every key implies its parent directories, which may match too.
+		for i := len(dir) + 1; i <= len(parts); i++ {
+			name := strings.Join(parts[:i], fsys.delim)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if fsys.validPath(name) && matchRecursive(elems, parts[:i]) {
+				matches = append(matches, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "glob",
+			Path: pattern,
+			Err:  err,
+		}
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func hasDoubleStar(elems []string) bool {
+	for _, elem := range elems {
+		if elem == "**" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchElems reports whether the path elements name match the pattern
+// elements one by one.
+func matchElems(pattern, name []string) bool {
+	for i := range pattern {
+		if ok, _ := path.Match(pattern[i], name[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRecursive reports whether the path elements name match the pattern
+// elements, where "**" matches zero or more elements.
+func matchRecursive(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] != "**" {
+			if len(name) == 0 {
+				return false
+			}
+
+			if ok, _ := path.Match(pattern[0], name[0]); !ok {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+			continue
+		}
+
+		// Consecutive "**" are the same as one.
+		for len(pattern) > 0 && pattern[0] == "**" {
+			pattern = pattern[1:]
+		}
+
+		if len(pattern) == 0 {
+			return true
+		}
+
+		for i := range name {
+			if matchRecursive(pattern, name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(name) == 0
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
@@ -0,0 +1,16 @@
+package s3fs
+
+import "net/http"
+
+// NewHTTPFileSystem returns fsys as an http.FileSystem, e.g. to serve a
+// bucket with http.FileServer. Files are opened as with WithReadSeeker,
+// since http.ServeContent seeks to serve range requests, and directories
+// implement Readdir with ReadDir.
+//
+// Files decompressed with WithDecompressContent cannot be seeked, so they
+// cannot be served this way.
+func NewHTTPFileSystem(fsys *S3FS) http.FileSystem {
+	f := *fsys
+	WithReadSeeker.apply(&f)
+	return http.FS(&f)
+}
@@ -0,0 +1,79 @@
+package s3fs_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestHTTPFileSystem(t *testing.T) {
+	cl := newMemClient("dir/b.txt")
+	cl.objects["dir/a.txt"] = "0123456789"
+
+	srv := httptest.NewServer(http.FileServer(s3fs.NewHTTPFileSystem(s3fs.New(cl, "test"))))
+	defer srv.Close()
+
+	get := func(path, rng string) (int, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	fixtures := []struct {
+		desc   string
+		path   string
+		rng    string
+		status int
+		body   string
+	}{
+		{desc: "file", path: "/dir/a.txt", status: http.StatusOK, body: "0123456789"},
+		{desc: "range", path: "/dir/a.txt", rng: "bytes=2-4", status: http.StatusPartialContent, body: "234"},
+		{desc: "missing", path: "/dir/missing.txt", status: http.StatusNotFound},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.desc, func(t *testing.T) {
+			status, body := get(f.path, f.rng)
+			if status != f.status {
+				t.Errorf("want status %d; got %d", f.status, status)
+			}
+			if f.body != "" && body != f.body {
+				t.Errorf("want %q; got %q", f.body, body)
+			}
+		})
+	}
+
+	t.Run("dir", func(t *testing.T) {
+		status, body := get("/dir/", "")
+		if status != http.StatusOK {
+			t.Errorf("want status %d; got %d", http.StatusOK, status)
+		}
+		for _, name := range []string{"a.txt", "b.txt"} {
+			if !strings.Contains(body, name) {
+				t.Errorf("expected %q in the listing; got %q", name, body)
+			}
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// DirIterator lists a directory one entry at a time. It is an alternative
+// to StreamDir that does not start any goroutines: a page of entries is
+// only requested from S3 when Next is called and the previous page was
+// exhausted. WithListingConcurrency does not apply to it.
+//
+// Like with StreamDir, entries are only sorted within every page.
+// A DirIterator is not safe for concurrent use.
+type DirIterator struct {
+	fsys   *S3FS
+	name   string
+	d      *dir
+	buf    []fs.DirEntry
+	eof    bool
+	closed bool
+}
+
+// NewDirIterator returns an iterator over the entries of the named
+// directory. No S3 calls are made until Next is called.
+func (f *S3FS) NewDirIterator(name string) *DirIterator {
+	return &DirIterator{fsys: f, name: name}
+}
+
+// Next returns the next entry of the directory. It returns io.EOF once all
+// entries were returned. ctx is used for the S3 calls made by Next.
+//
+// If listing a page fails, the error is returned and the next call to Next
+// requests the same page again.
+func (it *DirIterator) Next(ctx context.Context) (fs.DirEntry, error) {
+	if it.closed {
+		return nil, &fs.PathError{
+			Op:   "readdir",
+			Path: it.name,
+			Err:  fs.ErrClosed,
+		}
+	}
+
+	for len(it.buf) == 0 {
+		if it.eof {
+			return nil, io.EOF
+		}
+
+		fsys := it.fsys.WithContext(ctx)
+		fsys.listingConcurrency = 0
+
+		if it.d == nil {
+			d, err := fsys.openDir(it.name)
+			if err != nil {
+				return nil, &fs.PathError{
+					Op:   "readdir",
+					Path: it.name,
+					Err:  err,
+				}
+			}
+			it.d = d.(*dir)
+		}
+		it.d.fsys = fsys
+
+		des, err := it.d.nextPage()
+		switch {
+		case errors.Is(err, io.EOF):
+			it.eof = true
+		case err != nil:
+			return nil, err
+		}
+		it.buf = des
+	}
+
+	de := it.buf[0]
+	it.buf = it.buf[1:]
+	return de, nil
+}
+
+// Close releases the buffered entries. Next fails after Close.
+func (it *DirIterator) Close() error {
+	it.closed = true
+	it.d, it.buf = nil, nil
+	return nil
+}
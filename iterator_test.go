@@ -0,0 +1,120 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestDirIterator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("lazy pages", func(t *testing.T) {
+		var files []string
+		for i := 0; i < 9; i++ {
+			files = append(files, fmt.Sprintf("dir/%d.txt", i))
+		}
+
+		cl := newMemClient(files...)
+		fsys := s3fs.New(cl, "test", s3fs.WithPageSize(3))
+
+		it := fsys.NewDirIterator("dir")
+		if n := cl.count("ListObjectsV2"); n != 0 {
+			t.Fatalf("expected no calls before Next; got %d", n)
+		}
+
+		var (
+			names []string
+			calls []int
+		)
+		for {
+			de, err := it.Next(ctx)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+			names = append(names, de.Name())
+			calls = append(calls, cl.count("ListObjectsV2"))
+		}
+
+		expected := []string{"0.txt", "1.txt", "2.txt", "3.txt", "4.txt", "5.txt", "6.txt", "7.txt", "8.txt"}
+		if !reflect.DeepEqual(expected, names) {
+			t.Errorf("want %v; got %v", expected, names)
+		}
+
+		// every page is requested when its first entry is needed.
+		for i := 3; i < len(calls); i += 3 {
+			if calls[i-1] != calls[i-3] || calls[i] != calls[i-1]+1 {
+				t.Errorf("unexpected ListObjectsV2 calls %v", calls)
+				break
+			}
+		}
+
+		if _, err := it.Next(ctx); !errors.Is(err, io.EOF) {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+
+		if err := it.Close(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := it.Next(ctx); !errors.Is(err, fs.ErrClosed) {
+			t.Errorf("expected ErrClosed; got %v", err)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		it := s3fs.New(newMemClient("a.txt"), "test").NewDirIterator("missing")
+		defer it.Close()
+
+		if _, err := it.Next(ctx); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected ErrNotExist; got %v", err)
+		}
+	})
+
+	t.Run("resume after error", func(t *testing.T) {
+		cl := &flakyListClient{
+			failures: 1,
+			pages: map[string]s3.ListObjectsV2Output{
+				"": {
+					Contents:              []types.Object{{Key: ptr("a")}},
+					IsTruncated:           ptr(true),
+					NextContinuationToken: ptr("page2"),
+				},
+				"page2": {
+					Contents:    []types.Object{{Key: ptr("b")}},
+					IsTruncated: ptr(false),
+				},
+			},
+		}
+
+		it := s3fs.New(cl, "test").NewDirIterator(".")
+		defer it.Close()
+
+		if de, err := it.Next(ctx); err != nil || de.Name() != "a" {
+			t.Fatalf("expected a; got %v %v", de, err)
+		}
+
+		if _, err := it.Next(ctx); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if de, err := it.Next(ctx); err != nil || de.Name() != "b" {
+			t.Fatalf("expected b; got %v %v", de, err)
+		}
+
+		if expected := []string{"", "page2", "page2"}; !reflect.DeepEqual(expected, cl.tokens) {
+			t.Errorf("want %v; got %v", expected, cl.tokens)
+		}
+	})
+}
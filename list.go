@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// RecursiveList returns all files under the named directory, including the
+// files in its subdirectories. It lists the objects without a delimiter,
+// which takes one ListObjectsV2 call per page of objects, instead of one
+// listing per directory as when calling ReadDir recursively.
+//
+// Only files are returned, in the order of their keys; no entries are
+// returned for directories. Unlike the entries returned by ReadDir, the
+// Name of every entry is the full name of the file, as accepted by Open.
+func (f *S3FS) RecursiveList(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	des, err := f.recursiveList(ctx, name)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "recursivelist",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return des, nil
+}
+
+func (f *S3FS) recursiveList(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	if !f.validPath(name) {
+		return nil, fs.ErrInvalid
+	}
+
+	prefix := ""
+	if name != "." {
+		prefix = name + f.delim
+	}
+
+	var (
+		des []fs.DirEntry
+		n   int
+	)
+	err := f.listAll(ctx, prefix, func(file string, o types.Object) error {
+		n++
+		if strings.HasSuffix(file, f.delim) || !f.validPath(file) {
+			// a directory marker, or a key that is not a valid name.
+			return nil
+		}
+
+		des = append(des, listEntry{f.fileEntry(file, o)})
+		return nil
+	})
+	switch {
+	case err == nil:
+	case f.isNotFoundErr(err):
+		return nil, fs.ErrNotExist
+	case f.isPermissionErr(err):
+		return nil, fs.ErrPermission
+	default:
+		return nil, err
+	}
+
+	if n == 0 && name != "." {
+		return nil, fs.ErrNotExist
+	}
+
+	if des == nil {
+		des = []fs.DirEntry{}
+	}
+	return des, nil
+}
+
+// fileEntry returns the entry of the file name listed as o.
+func (f *S3FS) fileEntry(name string, o types.Object) dirEntry {
+	return dirEntry{
+		fileInfo: fileInfo{
+			name:    name,
+			size:    derefInt64(o.Size),
+			modTime: derefTime(o.LastModified),
+			delim:   f.delim,
+			sys:     metaFromObject(o),
+		},
+	}
+}
+
+// listEntry is an entry returned by RecursiveList. Its Name is the full
+// name of the file.
+type listEntry struct {
+	dirEntry
+}
+
+func (e listEntry) Name() string { return e.name }
@@ -0,0 +1,64 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestRecursiveList(t *testing.T) {
+	cl := newMemClient("dir/a.txt", "dir/sub/b.txt", "dir/sub/deep/c.txt", "other.txt")
+	cl.objects["dir/empty/"] = ""
+
+	fsys := s3fs.New(cl, "test")
+
+	fixtures := []struct {
+		name string
+		want []string
+	}{
+		{name: ".", want: []string{"dir/a.txt", "dir/sub/b.txt", "dir/sub/deep/c.txt", "other.txt"}},
+		{name: "dir", want: []string{"dir/a.txt", "dir/sub/b.txt", "dir/sub/deep/c.txt"}},
+		{name: "dir/sub", want: []string{"dir/sub/b.txt", "dir/sub/deep/c.txt"}},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.name, func(t *testing.T) {
+			des, err := fsys.RecursiveList(context.Background(), f.name)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			var names []string
+			for _, de := range des {
+				if de.IsDir() {
+					t.Errorf("unexpected directory %s", de.Name())
+				}
+
+				fi, err := de.Info()
+				if err != nil {
+					t.Fatal("expected err to be nil; got ", err)
+				}
+				if fi.Size() != int64(len("content")) {
+					t.Errorf("%s: want size %d; got %d", de.Name(), len("content"), fi.Size())
+				}
+				names = append(names, de.Name())
+			}
+
+			if !slices.Equal(names, f.want) {
+				t.Errorf("want %v; got %v", f.want, names)
+			}
+		})
+	}
+
+	if n := cl.count("ListObjectsV2"); n != len(fixtures) {
+		t.Errorf("expected one ListObjectsV2 call per listing; got %d", n)
+	}
+
+	if _, err := fsys.RecursiveList(context.Background(), "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
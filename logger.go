@@ -0,0 +1,88 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Levels passed to Logger.
+const (
+	LevelDebug = "DEBUG"
+	LevelWarn  = "WARN"
+	LevelError = "ERROR"
+)
+
+// Logger logs the S3 calls made by S3FS. args are alternating keys and
+// values, as in log/slog.
+//
+// Every call is logged at LevelDebug with its operation, bucket, key and
+// duration. Retried errors are logged at LevelWarn with the number of the
+// failed attempt, and so are listings that are abandoned because the
+// server kept returning empty pages. Errors returned to the caller are
+// logged at LevelError. Files that do not exist and canceled contexts are
+// not logged as errors.
+type Logger interface {
+	Log(ctx context.Context, level, msg string, args ...any)
+}
+
+// WithLogger logs S3 calls with l. It must be safe for concurrent use.
+func WithLogger(l Logger) Option {
+	return optionFunc(func(fsys *S3FS) {
+		if l == nil {
+			l = DiscardLogger{}
+		}
+		fsys.logger = l
+	})
+}
+
+// DiscardLogger is a Logger that logs nothing. It is used when WithLogger
+// is not set.
+type DiscardLogger struct{}
+
+// Log implements Logger.
+func (DiscardLogger) Log(context.Context, string, string, ...any) {}
+
+// SlogLogger returns a Logger that logs to h.
+func SlogLogger(h slog.Handler) Logger {
+	return slogLogger{slog.New(h)}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (l slogLogger) Log(ctx context.Context, level, msg string, args ...any) {
+	lvl := slog.LevelInfo
+	switch level {
+	case LevelDebug:
+		lvl = slog.LevelDebug
+	case LevelWarn:
+		lvl = slog.LevelWarn
+	case LevelError:
+		lvl = slog.LevelError
+	}
+	l.l.Log(ctx, lvl, msg, args...)
+}
+
+func (f *S3FS) logCall(ctx context.Context, op, bucket, key string, d time.Duration, err error) {
+	args := []any{
+		"operation", op,
+		"bucket", bucket,
+		"key", key,
+		"duration", d,
+	}
+
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	f.logger.Log(ctx, LevelDebug, "s3fs: S3 call", args...)
+
+	// not found errors and 304 responses to conditional requests are
+	// expected results rather than failures.
+	if err != nil && !f.isNotFoundErr(err) && !hasStatusCode(err, http.StatusNotModified) && !errors.Is(err, context.Canceled) {
+		f.logger.Log(ctx, LevelError, "s3fs: S3 call failed", args...)
+	}
+}
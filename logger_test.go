@@ -0,0 +1,73 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWithLogger(t *testing.T) {
+	l := &recordingLogger{}
+	cl := &flakyClient{memClient: newMemClient("file.txt"), failures: 2, status: http.StatusServiceUnavailable}
+	fsys := s3fs.New(cl, "test", s3fs.WithRetry(2, time.Millisecond), s3fs.WithLogger(l))
+
+	if _, err := fsys.Stat("file.txt"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	want := []string{s3fs.LevelWarn, s3fs.LevelDebug, s3fs.LevelError}
+	if got := l.levels(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	l = &recordingLogger{}
+	fsys = s3fs.New(newMemClient(), "test", s3fs.WithLogger(l))
+
+	fsys.Stat("not-exist")
+
+	for _, lvl := range l.levels() {
+		if lvl != s3fs.LevelDebug {
+			t.Errorf("expected missing files to be logged at %s; got %s", s3fs.LevelDebug, lvl)
+		}
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	fsys := s3fs.New(newMemClient("file.txt"), "test", s3fs.WithLogger(s3fs.SlogLogger(h)))
+
+	if _, err := fsys.Stat("file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	for _, s := range []string{"level=DEBUG", "operation=HeadObject", "bucket=test", "key=file.txt", "duration="} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in %q", s, buf.String())
+		}
+	}
+}
+
+type recordingLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordingLogger) Log(_ context.Context, level, _ string, _ ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, level)
+}
+
+func (l *recordingLogger) levels() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.logs...)
+}
@@ -0,0 +1,91 @@
+package s3fs
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3ObjectMeta is the S3 metadata of a file. It is returned by the Sys
+// method of fs.FileInfo for files whose metadata was fetched from S3:
+//
+//	if m, ok := fi.Sys().(*s3fs.S3ObjectMeta); ok {
+//		fmt.Println(m.ContentType)
+//	}
+//
+// Files listed with ReadDir only have the metadata returned by
+// ListObjectsV2: ETag, StorageClass and RestoreStatus. Directories have no
+// metadata.
+type S3ObjectMeta struct {
+	ETag         string
+	ContentType  string
+	StorageClass string
+	VersionID    string
+
+	// UserMetadata holds the x-amz-meta-* headers, without the prefix.
+	UserMetadata map[string]string
+
+	// RestoreStatus is the x-amz-restore header of archived objects,
+	// e.g. `ongoing-request="false", expiry-date="..."`.
+	RestoreStatus string
+
+	// ExpiresAt is the value of the Expires header, if set.
+	ExpiresAt time.Time
+}
+
+func metaFromGetObject(out *s3.GetObjectOutput) *S3ObjectMeta {
+	return &S3ObjectMeta{
+		ETag:          derefString(out.ETag),
+		ContentType:   derefString(out.ContentType),
+		StorageClass:  string(out.StorageClass),
+		VersionID:     derefString(out.VersionId),
+		UserMetadata:  out.Metadata,
+		RestoreStatus: derefString(out.Restore),
+		ExpiresAt:     derefTime(out.Expires),
+	}
+}
+
+func metaFromHeadObject(out *s3.HeadObjectOutput) *S3ObjectMeta {
+	return &S3ObjectMeta{
+		ETag:          derefString(out.ETag),
+		ContentType:   derefString(out.ContentType),
+		StorageClass:  string(out.StorageClass),
+		VersionID:     derefString(out.VersionId),
+		UserMetadata:  out.Metadata,
+		RestoreStatus: derefString(out.Restore),
+		ExpiresAt:     derefTime(out.Expires),
+	}
+}
+
+func metaFromObject(o types.Object) *S3ObjectMeta {
+	return &S3ObjectMeta{
+		ETag:          derefString(o.ETag),
+		StorageClass:  string(o.StorageClass),
+		RestoreStatus: restoreStatus(o.RestoreStatus),
+	}
+}
+
+// listAttributes are the optional attributes requested with every
+// ListObjectsV2 call, since S3 only returns the restore status of archived
+// objects if it is requested.
+var listAttributes = []types.OptionalObjectAttributes{types.OptionalObjectAttributesRestoreStatus}
+
+// restoreStatus formats the restore status returned by ListObjectsV2 the
+// same way as the x-amz-restore header.
+func restoreStatus(rs *types.RestoreStatus) string {
+	if rs == nil || rs.IsRestoreInProgress == nil {
+		return ""
+	}
+
+	if *rs.IsRestoreInProgress {
+		return `ongoing-request="true"`
+	}
+
+	s := `ongoing-request="false"`
+	if rs.RestoreExpiryDate != nil {
+		s += `, expiry-date="` + rs.RestoreExpiryDate.UTC().Format(http.TimeFormat) + `"`
+	}
+	return s
+}
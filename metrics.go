@@ -0,0 +1,46 @@
+package s3fs
+
+import "time"
+
+// Metrics records the S3 calls made by S3FS, e.g. as Prometheus histograms
+// or StatsD timers.
+type Metrics interface {
+	// RecordOperation is called after every S3 call returns. op is the
+	// name of the operation, e.g. "GetObject", and key is the key of the
+	// object or the prefix for listings. d includes the time spent on
+	// retries and waiting for rate limits. err is nil if the call
+	// succeeded.
+	RecordOperation(op, bucket, key string, d time.Duration, err error)
+}
+
+// WithMetrics records every S3 call with m. It must be safe for concurrent
+// use.
+func WithMetrics(m Metrics) Option {
+	return optionFunc(func(fsys *S3FS) {
+		if m == nil {
+			m = NopMetrics{}
+		}
+		fsys.metrics = m
+	})
+}
+
+// NopMetrics is a Metrics that records nothing. It is used when
+// WithMetrics is not set.
+type NopMetrics struct{}
+
+// RecordOperation implements Metrics.
+func (NopMetrics) RecordOperation(string, string, string, time.Duration, error) {}
+
+// MultiMetrics returns a Metrics that records every operation with all of
+// ms, in order.
+func MultiMetrics(ms ...Metrics) Metrics {
+	return multiMetrics(append([]Metrics{}, ms...))
+}
+
+type multiMetrics []Metrics
+
+func (ms multiMetrics) RecordOperation(op, bucket, key string, d time.Duration, err error) {
+	for _, m := range ms {
+		m.RecordOperation(op, bucket, key, d, err)
+	}
+}
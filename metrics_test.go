@@ -0,0 +1,70 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWithMetrics(t *testing.T) {
+	m1, m2 := &recordingMetrics{}, &recordingMetrics{}
+	fsys := s3fs.New(newMemClient("dir/file.txt"), "test", s3fs.WithMetrics(s3fs.MultiMetrics(m1, m2)))
+
+	if _, err := fs.ReadFile(fsys, "dir/file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if _, err := fsys.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	for _, m := range []*recordingMetrics{m1, m2} {
+		ops := m.recorded()
+		if len(ops) < 2 {
+			t.Fatalf("expected at least 2 operations; got %d", len(ops))
+		}
+
+		want := operationRecord{op: "GetObject", bucket: "test", key: "dir/file.txt"}
+		if ops[0] != want {
+			t.Errorf("want %+v; got %+v", want, ops[0])
+		}
+
+		if ops[1].op != "HeadObject" || ops[1].key != "missing.txt" || !ops[1].failed {
+			t.Errorf("expected a failed HeadObject call; got %+v", ops[1])
+		}
+	}
+}
+
+func TestNopMetrics(t *testing.T) {
+	fsys := s3fs.New(newMemClient("file.txt"), "test", s3fs.WithMetrics(nil))
+
+	if _, err := fsys.Stat("file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+}
+
+type operationRecord struct {
+	op, bucket, key string
+	failed          bool
+}
+
+type recordingMetrics struct {
+	mu  sync.Mutex
+	ops []operationRecord
+}
+
+func (m *recordingMetrics) RecordOperation(op, bucket, key string, _ time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops = append(m.ops, operationRecord{op: op, bucket: bucket, key: key, failed: err != nil})
+}
+
+func (m *recordingMetrics) recorded() []operationRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]operationRecord{}, m.ops...)
+}
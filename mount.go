@@ -0,0 +1,280 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var (
+	_ fs.ReadDirFS  = (*multiBucketFS)(nil)
+	_ fs.ReadFileFS = (*multiBucketFS)(nil)
+	_ fs.StatFS     = (*multiBucketFS)(nil)
+)
+
+// BucketMount mounts a bucket in a filesystem returned by
+// NewMultiBucketFS.
+type BucketMount struct {
+	// Prefix is the directory the bucket is mounted at, e.g. "projectA/".
+	// It must be a single path element.
+	Prefix string
+
+	// Bucket is the name of the mounted bucket.
+	Bucket string
+
+	// Client is the client used for the bucket.
+	Client Client
+}
+
+// NewMultiBucketFS returns a filesystem that combines the buckets in
+// entries, each mounted at its own top level directory. The root directory
+// only lists the mount points. Every bucket is accessed with a S3FS
+// created with opts.
+//
+// Open, ReadDir, ReadFile, Stat, Glob and Sub route names to the bucket
+// mounted at their first element. Other methods of the returned filesystem
+// fail, since the root does not belong to any bucket; use Sub to get the
+// filesystem of a bucket.
+//
+// It panics if a prefix is not a single valid path element or if two
+// buckets are mounted at the same prefix.
+func NewMultiBucketFS(entries []BucketMount, opts ...Option) *S3FS {
+	m := &multiBucketFS{mounts: make(map[string]*S3FS, len(entries))}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Prefix, "/")
+		if !fs.ValidPath(name) || name == "." || strings.Contains(name, "/") {
+			panic("s3fs: invalid mount prefix: " + strconv.Quote(e.Prefix))
+		}
+
+		if _, ok := m.mounts[name]; ok {
+			panic("s3fs: duplicate mount prefix: " + strconv.Quote(e.Prefix))
+		}
+
+		m.mounts[name] = New(e.Client, e.Bucket, opts...)
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+
+	fsys := New(mountRootClient{}, "")
+	fsys.mounts = m
+	return fsys
+}
+
+// multi returns the mounts of f bound to the context of f.
+func (f *S3FS) multi() *multiBucketFS {
+	m := *f.mounts
+	m.ctx = f.ctx
+	return &m
+}
+
+// errMountRoot is returned by the S3 calls made on the root of a
+// filesystem returned by NewMultiBucketFS.
+var errMountRoot = errors.New("s3fs: the root of a multi-bucket filesystem has no bucket")
+
+// mountRootClient is the client of the root of a multi-bucket filesystem.
+type mountRootClient struct{}
+
+func (mountRootClient) HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, errMountRoot
+}
+
+func (mountRootClient) ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return nil, errMountRoot
+}
+
+func (mountRootClient) GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, errMountRoot
+}
+
+// multiBucketFS implements the filesystem methods of the root of
+// a filesystem returned by NewMultiBucketFS.
+type multiBucketFS struct {
+	mounts map[string]*S3FS
+	names  []string
+	ctx    context.Context
+}
+
+func (m *multiBucketFS) Open(name string) (fs.File, error) {
+	if name == "." {
+		return &mountRoot{m: m}, nil
+	}
+
+	fsys, rest, err := m.route("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fsys.Open(rest)
+	if err != nil {
+		return nil, mountErr(err, name)
+	}
+
+	if rest == "." {
+		return &mountDir{ReadDirFile: f.(fs.ReadDirFile), name: name}, nil
+	}
+	return f, nil
+}
+
+func (m *multiBucketFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == "." {
+		return m.entries(), nil
+	}
+
+	fsys, rest, err := m.route("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	des, err := fsys.ReadDir(rest)
+	return des, mountErr(err, name)
+}
+
+func (m *multiBucketFS) ReadFile(name string) ([]byte, error) {
+	if name == "." {
+		// Like S3FS.ReadFile, report that the root is a directory.
+		return io.ReadAll(&mountRoot{m: m})
+	}
+
+	fsys, rest, err := m.route("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := fsys.ReadFile(rest)
+	return data, mountErr(err, name)
+}
+
+func (m *multiBucketFS) Stat(name string) (fs.FileInfo, error) {
+	if name == "." {
+		return mountInfo("."), nil
+	}
+
+	fsys, rest, err := m.route("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if rest == "." {
+		return mountInfo(name), nil
+	}
+
+	fi, err := fsys.Stat(rest)
+	return fi, mountErr(err, name)
+}
+
+// route returns the filesystem of the bucket name belongs to, and the name
+// of the file in the bucket.
+func (m *multiBucketFS) route(op, name string) (*S3FS, string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, "", &fs.PathError{
+			Op:   op,
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	mount, rest, ok := strings.Cut(name, "/")
+	if !ok {
+		rest = "."
+	}
+
+	fsys, ok := m.mounts[mount]
+	if !ok {
+		return nil, "", &fs.PathError{
+			Op:   op,
+			Path: name,
+			Err:  fs.ErrNotExist,
+		}
+	}
+
+	if m.ctx != nil {
+		fsys = fsys.WithContext(m.ctx)
+	}
+	return fsys, rest, nil
+}
+
+// sub returns the filesystem of the directory dir.
+func (m *multiBucketFS) sub(dir string) (fs.FS, error) {
+	fsys, rest, err := m.route("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := fsys.Sub(rest)
+	return sub, mountErr(err, dir)
+}
+
+func (m *multiBucketFS) entries() []fs.DirEntry {
+	des := make([]fs.DirEntry, 0, len(m.names))
+	for _, name := range m.names {
+		des = append(des, dirEntry{fileInfo: *mountInfo(name)})
+	}
+	return des
+}
+
+// mountErr replaces the path of a *fs.PathError returned by the bucket
+// filesystem with name.
+func mountErr(err error, name string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{
+			Op:   pathErr.Op,
+			Path: name,
+			Err:  pathErr.Err,
+		}
+	}
+	return err
+}
+
+func mountInfo(name string) *fileInfo {
+	return &fileInfo{
+		name:  name,
+		mode:  fs.ModeDir,
+		delim: "/",
+	}
+}
+
+// mountDir is the root directory of a mounted bucket, which is named
+// after its mount point.
+type mountDir struct {
+	fs.ReadDirFile
+	name string
+}
+
+func (d *mountDir) Stat() (fs.FileInfo, error) { return mountInfo(d.name), nil }
+
+// mountRoot is the root directory of a multi-bucket filesystem.
+type mountRoot struct {
+	m      *multiBucketFS
+	offset int
+}
+
+func (d *mountRoot) Stat() (fs.FileInfo, error) { return mountInfo("."), nil }
+
+func (d *mountRoot) Read([]byte) (int, error) {
+	return 0, &fs.PathError{
+		Op:   "read",
+		Path: ".",
+		Err:  errors.New("is a directory"),
+	}
+}
+
+func (d *mountRoot) Close() error { return nil }
+
+func (d *mountRoot) ReadDir(n int) ([]fs.DirEntry, error) {
+	des := d.m.entries()[d.offset:]
+	if n > 0 {
+		if len(des) == 0 {
+			return nil, io.EOF
+		}
+		des = des[:min(n, len(des))]
+	}
+	d.offset += len(des)
+	return des, nil
+}
@@ -0,0 +1,98 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestMultiBucketFS(t *testing.T) {
+	fsys := s3fs.NewMultiBucketFS([]s3fs.BucketMount{
+		{Prefix: "projectB/", Bucket: "b", Client: newMemClient("b.txt", "dir/c.txt")},
+		{Prefix: "projectA/", Bucket: "a", Client: newMemClient("data/file.csv")},
+	})
+
+	if err := fstest.TestFS(fsys, "projectA/data/file.csv", "projectB/b.txt", "projectB/dir/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	des, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(des) != 2 || des[0].Name() != "projectA" || des[1].Name() != "projectB" || !des[0].IsDir() {
+		t.Errorf("unexpected root entries: %v", des)
+	}
+
+	data, err := fsys.ReadFile("projectA/data/file.csv")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	for _, name := range []string{"projectC/a.txt", "projectA/b.txt"} {
+		_, err := fsys.Open(name)
+
+		var pathErr *fs.PathError
+		if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &pathErr) || pathErr.Path != name {
+			t.Errorf("expected ErrNotExist for %s; got %v", name, err)
+		}
+	}
+
+	t.Run("readfile root", func(t *testing.T) {
+		_, err := fsys.ReadFile(".")
+
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Op != "read" || pathErr.Path != "." || pathErr.Err.Error() != "is a directory" {
+			t.Errorf("expected read . is a directory; got %v", err)
+		}
+	})
+
+	t.Run("sub", func(t *testing.T) {
+		sub, err := fsys.Sub("projectB/dir")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := fstest.TestFS(sub, "c.txt"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := fsys.Sub("projectC"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		matches, err := fs.Glob(fsys, "*/*.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(matches) != 1 || matches[0] != "projectB/b.txt" {
+			t.Errorf("want [projectB/b.txt]; got %v", matches)
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		if err := fsys.WriteFile("a.txt", nil, 0644); err == nil {
+			t.Error("expected WriteFile to fail on the root")
+		}
+	})
+
+	t.Run("invalid prefix", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		s3fs.NewMultiBucketFS([]s3fs.BucketMount{{Prefix: "a/b/", Bucket: "a", Client: newMemClient()}})
+	})
+}
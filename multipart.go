@@ -0,0 +1,315 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// minPartSize is the minimum size of all parts of a multipart upload
+	// but the last one.
+	minPartSize = 5 << 20
+
+	defaultMultipartThreshold = 5 << 20
+	defaultPartSize           = minPartSize
+	defaultUploadWorkers      = 4
+	defaultMaxMemoryPartSize  = 16 << 20
+)
+
+type multipartClient interface {
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// WithMultipartThreshold sets the size above which files written with
+// OpenForWrite are uploaded with a multipart upload instead of a single
+// PutObject call. The default is 5MB.
+//
+// Multipart uploads are only used if the Client implements the multipart
+// upload methods of *s3.Client.
+func WithMultipartThreshold(bytes int64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.multipartThreshold = bytes
+	})
+}
+
+// WithPartSize sets the size of the parts of multipart uploads. The
+// default and minimum is 5MB.
+//
+// Parts that are not larger than the limit set with WithMaxMemoryPartSize
+// are buffered in memory, so at most (n+1)*bytes are held per file, where
+// n is the number of upload workers. Larger parts are written to
+// temporary files while they are uploaded, so only the part being written
+// is held in memory.
+func WithPartSize(bytes int64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.partSize = bytes
+	})
+}
+
+// WithMaxMemoryPartSize sets the largest part of a multipart upload that
+// is kept in memory while it is uploaded. Larger parts are written to
+// temporary files in os.TempDir, which are removed once the part is
+// uploaded. The default is 16MB.
+func WithMaxMemoryPartSize(bytes int64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.maxMemoryPartSize = bytes
+	})
+}
+
+// WithUploadWorkers sets the number of parts of a multipart upload that
+// are uploaded concurrently. The default is 4.
+func WithUploadWorkers(n int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.uploadWorkers = n
+	})
+}
+
+func (f *S3FS) shouldUseMultipart(size int64) bool {
+	threshold := f.multipartThreshold
+	if threshold <= 0 {
+		threshold = defaultMultipartThreshold
+	}
+
+	_, ok := f.cl.(multipartClient)
+	return ok && size > threshold
+}
+
+func (f *S3FS) uploadPartSize() int {
+	return int(max(f.partSize, minPartSize))
+}
+
+func (f *S3FS) memoryPartSize() int {
+	if f.maxMemoryPartSize <= 0 {
+		return defaultMaxMemoryPartSize
+	}
+	return int(f.maxMemoryPartSize)
+}
+
+// partBody is the body of an UploadPart request.
+type partBody interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+type memPart struct{ *bytes.Reader }
+
+func (memPart) Close() error { return nil }
+
+// tempPart is a part buffered in a temporary file, which is removed on
+// Close.
+type tempPart struct{ *os.File }
+
+func (p tempPart) Close() error {
+	return errors.Join(p.File.Close(), os.Remove(p.Name()))
+}
+
+// newPartBody copies data, so that the caller may reuse it once
+// newPartBody returns.
+func (f *S3FS) newPartBody(data []byte) (partBody, error) {
+	if len(data) <= f.memoryPartSize() {
+		return memPart{bytes.NewReader(bytes.Clone(data))}, nil
+	}
+
+	file, err := os.CreateTemp("", "s3fs-part-*")
+	if err != nil {
+		return nil, err
+	}
+
+	p := tempPart{file}
+	if _, err := file.Write(data); err != nil {
+		return nil, errors.Join(err, p.Close())
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Join(err, p.Close())
+	}
+	return p, nil
+}
+
+// multipartUpload uploads the parts of a file concurrently.
+type multipartUpload struct {
+	fsys *S3FS
+	cl   multipartClient
+	name string
+	id   *string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	next   int32
+
+	// sent is the number of bytes passed to send.
+	sent int64
+
+	mu       sync.Mutex
+	parts    []types.CompletedPart
+	err      error
+	uploaded int64
+	total    int64
+}
+
+func (f *S3FS) createMultipartUpload(name string) (*multipartUpload, error) {
+	cl := f.cl.(multipartClient)
+
+	out, err := call(f, f.ctx, cl.CreateMultipartUpload, &s3.CreateMultipartUploadInput{
+		Bucket:      &f.bucket,
+		Key:         ptr(f.key(name)),
+		ContentType: ptr(contentType(name)),
+		ACL:         f.acl,
+
+		ServerSideEncryption: f.sse.algorithm,
+		SSEKMSKeyId:          f.sse.keyID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workers := f.uploadWorkers
+	if workers <= 0 {
+		workers = defaultUploadWorkers
+	}
+
+	ctx, cancel := context.WithCancel(f.ctx)
+	return &multipartUpload{
+		fsys:   f,
+		cl:     cl,
+		name:   name,
+		id:     out.UploadId,
+		ctx:    ctx,
+		cancel: cancel,
+		sem:    make(chan struct{}, workers),
+		total:  -1,
+	}, nil
+}
+
+// send uploads data as the next part in the background. It blocks while
+// all workers are busy. data is not used after send returns.
+func (u *multipartUpload) send(data []byte) error {
+	if err := u.failed(); err != nil {
+		return err
+	}
+
+	select {
+	case u.sem <- struct{}{}:
+	case <-u.ctx.Done():
+		if err := u.failed(); err != nil {
+			return err
+		}
+		return u.ctx.Err()
+	}
+
+	body, err := u.fsys.newPartBody(data)
+	if err != nil {
+		<-u.sem
+		return err
+	}
+
+	u.next++
+	n := u.next
+	size := int64(len(data))
+	u.sent += size
+
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		defer func() { <-u.sem }()
+		defer body.Close()
+
+		out, err := call(u.fsys, u.ctx, u.cl.UploadPart, &s3.UploadPartInput{
+			Bucket:        &u.fsys.bucket,
+			Key:           ptr(u.fsys.key(u.name)),
+			UploadId:      u.id,
+			PartNumber:    &n,
+			Body:          u.fsys.limitWrite(u.ctx, body),
+			ContentLength: &size,
+		})
+
+		u.mu.Lock()
+		defer u.mu.Unlock()
+
+		if err != nil {
+			if u.err == nil {
+				u.err = err
+			}
+			u.cancel()
+			return
+		}
+		u.parts = append(u.parts, types.CompletedPart{ETag: out.ETag, PartNumber: &n})
+
+		u.uploaded += size
+		if fn := u.fsys.uploadProgress; fn != nil {
+			fn(u.uploaded, u.total)
+		}
+	}()
+	return nil
+}
+
+func (u *multipartUpload) failed() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.err
+}
+
+// complete uploads the last part and completes the upload. The upload is
+// aborted if any part failed.
+func (u *multipartUpload) complete(last []byte) error {
+	u.mu.Lock()
+	u.total = u.sent + int64(len(last))
+	u.mu.Unlock()
+
+	if len(last) > 0 {
+		if err := u.send(last); err != nil {
+			return u.abort(err)
+		}
+	}
+
+	u.wg.Wait()
+	defer u.cancel()
+
+	if err := u.failed(); err != nil {
+		return u.abort(err)
+	}
+
+	sort.Slice(u.parts, func(i, j int) bool {
+		return *u.parts[i].PartNumber < *u.parts[j].PartNumber
+	})
+
+	_, err := call(u.fsys, u.fsys.ctx, u.cl.CompleteMultipartUpload, &s3.CompleteMultipartUploadInput{
+		Bucket:          &u.fsys.bucket,
+		Key:             ptr(u.fsys.key(u.name)),
+		UploadId:        u.id,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+	})
+	if err != nil {
+		return u.abort(err)
+	}
+
+	u.fsys.invalidate(u.name, false)
+	return nil
+}
+
+// abort waits for the running workers and aborts the upload, so that S3
+// does not keep the uploaded parts. It returns err joined with the error
+// of aborting.
+func (u *multipartUpload) abort(err error) error {
+	u.cancel()
+	u.wg.Wait()
+
+	_, abortErr := call(u.fsys, u.fsys.ctx, u.cl.AbortMultipartUpload, &s3.AbortMultipartUploadInput{
+		Bucket:   &u.fsys.bucket,
+		Key:      ptr(u.fsys.key(u.name)),
+		UploadId: u.id,
+	})
+	return errors.Join(err, abortErr)
+}
@@ -0,0 +1,310 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	const partSize = 5 << 20
+
+	data := bytes.Repeat([]byte("0123456789"), (2*partSize+partSize/2)/10)
+
+	t.Run("small file", func(t *testing.T) {
+		cl := newMultipartClient()
+		fsys := s3fs.New(cl, "test")
+
+		writeAll(t, fsys, "small.txt", []byte("data"))
+
+		if n := cl.count("CreateMultipartUpload"); n != 0 {
+			t.Errorf("expected no multipart uploads; got %d", n)
+		}
+
+		if cl.objects["small.txt"] != "data" {
+			t.Errorf("want %q; got %q", "data", cl.objects["small.txt"])
+		}
+	})
+
+	t.Run("large file", func(t *testing.T) {
+		cl := newMultipartClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithPartSize(partSize), s3fs.WithUploadWorkers(2))
+
+		writeAll(t, fsys, "large.bin", data)
+
+		if n := cl.count("CreateMultipartUpload"); n != 1 {
+			t.Errorf("expected 1 multipart upload; got %d", n)
+		}
+
+		if n := cl.count("UploadPart"); n != 3 {
+			t.Errorf("expected 3 parts; got %d", n)
+		}
+
+		if n := cl.count("PutObject"); n != 0 {
+			t.Errorf("expected no PutObject calls; got %d", n)
+		}
+
+		if cl.objects["large.bin"] != string(data) {
+			t.Error("uploaded data does not match")
+		}
+
+		if cl.maxWorkers > 2 {
+			t.Errorf("expected at most 2 concurrent parts; got %d", cl.maxWorkers)
+		}
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		cl := newMultipartClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithMultipartThreshold(int64(len(data))))
+
+		writeAll(t, fsys, "large.bin", data)
+
+		if n := cl.count("CreateMultipartUpload"); n != 0 {
+			t.Errorf("expected no multipart uploads; got %d", n)
+		}
+	})
+
+	t.Run("failed part", func(t *testing.T) {
+		cl := newMultipartClient()
+		cl.failPart = 2
+		fsys := s3fs.New(cl, "test", s3fs.WithUploadWorkers(1))
+
+		w, err := fsys.OpenForWrite("large.bin")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		_, err = io.Copy(w, bytes.NewReader(data))
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if n := cl.count("AbortMultipartUpload"); n != 1 {
+			t.Errorf("expected the upload to be aborted; got %d", n)
+		}
+
+		if n := cl.count("CompleteMultipartUpload"); n != 0 {
+			t.Errorf("expected the upload not to be completed; got %d", n)
+		}
+
+		if _, ok := cl.objects["large.bin"]; ok {
+			t.Error("expected the file not to exist")
+		}
+	})
+
+	t.Run("temp files", func(t *testing.T) {
+		for _, failPart := range []int32{0, 2} {
+			t.Run(fmt.Sprintf("fail part %d", failPart), func(t *testing.T) {
+				dir := t.TempDir()
+				t.Setenv("TMPDIR", dir)
+
+				var mu sync.Mutex
+				var files int
+
+				cl := newMultipartClient()
+				cl.failPart = failPart
+				cl.onUpload = func() {
+					entries, _ := os.ReadDir(dir)
+
+					mu.Lock()
+					defer mu.Unlock()
+					files = max(files, len(entries))
+				}
+
+				fsys := s3fs.New(cl, "test",
+					s3fs.WithPartSize(partSize),
+					s3fs.WithMaxMemoryPartSize(partSize/2),
+					s3fs.WithUploadWorkers(2),
+				)
+
+				w, err := fsys.OpenForWrite("large.bin")
+				if err != nil {
+					t.Fatal("expected err to be nil; got ", err)
+				}
+
+				_, err = io.Copy(w, bytes.NewReader(data))
+				if cerr := w.Close(); err == nil {
+					err = cerr
+				}
+
+				if failPart == 0 {
+					if err != nil {
+						t.Fatal("expected err to be nil; got ", err)
+					}
+					if cl.objects["large.bin"] != string(data) {
+						t.Error("uploaded data does not match")
+					}
+				} else if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				if files == 0 {
+					t.Error("expected parts to be uploaded from temporary files")
+				}
+
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					t.Fatal("expected err to be nil; got ", err)
+				}
+				if len(entries) != 0 {
+					t.Errorf("expected temporary files to be removed; got %d", len(entries))
+				}
+			})
+		}
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test")
+
+		writeAll(t, fsys, "large.bin", data)
+
+		if cl.objects["large.bin"] != string(data) {
+			t.Error("uploaded data does not match")
+		}
+	})
+}
+
+func writeAll(t *testing.T, fsys *s3fs.S3FS, name string, data []byte) {
+	t.Helper()
+
+	w, err := fsys.OpenForWrite(name)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	// write in small chunks, like io.Copy does.
+	if _, err := io.CopyBuffer(w, bytes.NewReader(data), make([]byte, 32<<10)); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+}
+
+// multipartClient is a memClient that supports multipart uploads.
+type multipartClient struct {
+	*memClient
+	failPart int32
+	onUpload func()
+
+	mpMu       sync.Mutex
+	uploads    map[string]map[int32][]byte
+	workers    int
+	maxWorkers int
+}
+
+func newMultipartClient() *multipartClient {
+	return &multipartClient{
+		memClient: newMemClient(),
+		uploads:   make(map[string]map[int32][]byte),
+	}
+}
+
+func (c *multipartClient) record(op string) {
+	c.memClient.mu.Lock()
+	defer c.memClient.mu.Unlock()
+	c.calls[op]++
+}
+
+func (c *multipartClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	c.record("CreateMultipartUpload")
+
+	c.mpMu.Lock()
+	defer c.mpMu.Unlock()
+
+	id := fmt.Sprint(len(c.uploads) + 1)
+	c.uploads[id] = make(map[int32][]byte)
+	return &s3.CreateMultipartUploadOutput{UploadId: &id}, nil
+}
+
+func (c *multipartClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	c.record("UploadPart")
+
+	c.mpMu.Lock()
+	c.workers++
+	c.maxWorkers = max(c.maxWorkers, c.workers)
+	c.mpMu.Unlock()
+
+	defer func() {
+		c.mpMu.Lock()
+		c.workers--
+		c.mpMu.Unlock()
+	}()
+
+	if c.onUpload != nil {
+		c.onUpload()
+	}
+
+	if *in.PartNumber == c.failPart {
+		return nil, responseError(http.StatusForbidden)
+	}
+
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mpMu.Lock()
+	defer c.mpMu.Unlock()
+
+	c.uploads[*in.UploadId][*in.PartNumber] = data
+	return &s3.UploadPartOutput{ETag: ptr(etag(string(data)))}, nil
+}
+
+func (c *multipartClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	c.record("CompleteMultipartUpload")
+
+	c.mpMu.Lock()
+	parts := c.uploads[*in.UploadId]
+	delete(c.uploads, *in.UploadId)
+	c.mpMu.Unlock()
+
+	if !sort.SliceIsSorted(in.MultipartUpload.Parts, func(i, j int) bool {
+		return *in.MultipartUpload.Parts[i].PartNumber < *in.MultipartUpload.Parts[j].PartNumber
+	}) {
+		return nil, responseError(http.StatusBadRequest)
+	}
+
+	var buf bytes.Buffer
+	for _, p := range in.MultipartUpload.Parts {
+		data, ok := parts[*p.PartNumber]
+		if !ok || etag(string(data)) != *p.ETag {
+			return nil, responseError(http.StatusBadRequest)
+		}
+		buf.Write(data)
+	}
+
+	c.memClient.mu.Lock()
+	defer c.memClient.mu.Unlock()
+	c.objects[*in.Key] = buf.String()
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (c *multipartClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	c.record("AbortMultipartUpload")
+
+	c.mpMu.Lock()
+	defer c.mpMu.Unlock()
+
+	if _, ok := c.uploads[*in.UploadId]; !ok {
+		return nil, errors.New("no such upload")
+	}
+	delete(c.uploads, *in.UploadId)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
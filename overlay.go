@@ -0,0 +1,15 @@
+package s3fs
+
+import "io/fs"
+
+// NewOverlayFS returns a filesystem that serves files from local, and falls
+// back to remote for files that do not exist in local. Directories list the
+// entries of both, with entries of local replacing the entries of remote
+// with the same name.
+//
+// It is meant for development, e.g. to override a few files of an asset
+// tree stored in S3 with local copies. It is the same as
+// NewUnionFS(local, remote).
+func NewOverlayFS(local fs.FS, remote *S3FS) fs.FS {
+	return NewUnionFS(local, remote)
+}
@@ -0,0 +1,57 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestOverlayFS(t *testing.T) {
+	local := fstest.MapFS{
+		"a.txt":         {Data: []byte("local")},
+		"dir/local.txt": {Data: []byte("local")},
+	}
+	remote := s3fs.New(newMemClient("a.txt", "b.txt", "dir/remote.txt", "remote/c.txt"), "test")
+
+	fsys := s3fs.NewOverlayFS(local, remote)
+
+	if err := fstest.TestFS(fsys, "a.txt", "b.txt", "dir/local.txt", "dir/remote.txt", "remote/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []struct {
+		name string
+		want string
+	}{
+		{name: "a.txt", want: "local"},
+		{name: "b.txt", want: "content"},
+		{name: "dir/remote.txt", want: "content"},
+	}
+
+	for _, f := range fixtures {
+		data, err := fs.ReadFile(fsys, f.name)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != f.want {
+			t.Errorf("%s: want %q; got %q", f.name, f.want, data)
+		}
+	}
+
+	des, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(des) != 2 || des[0].Name() != "local.txt" || des[1].Name() != "remote.txt" {
+		t.Errorf("unexpected entries: %v", des)
+	}
+
+	if _, err := fs.Stat(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist; got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package s3fs
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// ReadDirPage reads a single page of at most n entries of the named
+// directory, starting at cursor, and returns the cursor of the next page.
+// An empty cursor reads the first page, and an empty next cursor is
+// returned with the last page. n <= 0 uses the page size set with
+// WithPageSize, or the S3 default of 1000.
+//
+// The cursor is the URL-safe base64 encoding of the S3 continuation token,
+// so it can be passed to clients of paginated HTTP APIs without keeping
+// any state on the server. A page can contain fewer than n entries even
+// if it is not the last one. Like with StreamDir, entries are only sorted
+// within every page.
+func (f *S3FS) ReadDirPage(name, cursor string, n int) ([]fs.DirEntry, string, error) {
+	des, next, err := f.readDirPage(name, cursor, n)
+	if err != nil {
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) {
+			err = &fs.PathError{
+				Op:   "readdir",
+				Path: name,
+				Err:  err,
+			}
+		}
+		return nil, "", err
+	}
+	return des, next, nil
+}
+
+func (f *S3FS) readDirPage(name, cursor string, n int) ([]fs.DirEntry, string, error) {
+	var token *string
+	if cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(b) == 0 {
+			return nil, "", fs.ErrInvalid
+		}
+		token = ptr(string(b))
+	}
+
+	fsys := *f
+	fsys.listingConcurrency = 0
+	if n > 0 {
+		fsys.pageSize = n
+	}
+
+	var d *dir
+	if token == nil {
+		fi, err := fsys.openDir(name)
+		if err != nil {
+			return nil, "", err
+		}
+		d = fi.(*dir)
+	} else {
+		// the directory existed when the first page was read, so it is not
+		// checked again.
+		if !f.validPath(name) {
+			return nil, "", fs.ErrInvalid
+		}
+		d = &dir{
+			fileInfo: fileInfo{
+				name:  name,
+				mode:  fs.ModeDir,
+				delim: f.delim,
+			},
+			continuationToken: token,
+		}
+	}
+	d.fsys = &fsys
+
+	des, err := d.nextPage()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+
+	// the directories that sort after the last file of the page are kept
+	// by readNext for the next page, which is read with a new dir.
+	for name, used := range d.dirs {
+		if !used {
+			des = append(des, d.dirEntry(name))
+		}
+	}
+	sort.Slice(des, func(i, j int) bool {
+		return des[i].Name() < des[j].Name()
+	})
+
+	if d.done || d.continuationToken == nil {
+		return des, "", nil
+	}
+	return des, base64.RawURLEncoding.EncodeToString([]byte(*d.continuationToken)), nil
+}
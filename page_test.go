@@ -0,0 +1,87 @@
+package s3fs_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestReadDirPage(t *testing.T) {
+	var files []string
+	for i := 0; i < 5; i++ {
+		files = append(files, fmt.Sprintf("dir/%d.txt", i), fmt.Sprintf("dir/%d/file.txt", i))
+	}
+
+	fsys := s3fs.New(newMemClient(files...), "test")
+
+	want, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	var (
+		got    []fs.DirEntry
+		cursor string
+		pages  int
+	)
+	for {
+		des, next, err := fsys.ReadDirPage("dir", cursor, 3)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(des) > 3 {
+			t.Errorf("expected at most 3 entries; got %d", len(des))
+		}
+
+		got = append(got, des...)
+		pages++
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if pages != 4 {
+		t.Errorf("expected 4 pages; got %d", pages)
+	}
+
+	sort.Slice(got, func(i, j int) bool { return got[i].Name() < got[j].Name() })
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		fixtures := []struct {
+			desc   string
+			name   string
+			cursor string
+			err    error
+		}{
+			{desc: "not exist", name: "missing", err: fs.ErrNotExist},
+			{desc: "invalid cursor", name: "dir", cursor: "!", err: fs.ErrInvalid},
+			{desc: "invalid path", name: "/dir", err: fs.ErrInvalid},
+		}
+
+		for _, f := range fixtures {
+			t.Run(f.desc, func(t *testing.T) {
+				_, _, err := fsys.ReadDirPage(f.name, f.cursor, 3)
+				if !errors.Is(err, f.err) {
+					t.Errorf("expected %v; got %v", f.err, err)
+				}
+
+				var pathErr *fs.PathError
+				if !errors.As(err, &pathErr) || pathErr.Op != "readdir" || pathErr.Path != f.name {
+					t.Errorf("expected readdir PathError; got %#v", err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// presignClient is implemented by *s3.PresignClient. Clients that are not
+// *s3.Client can implement it to support PresignGetObject and
+// PresignPutObject.
+type presignClient interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignPutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// PresignGetObject returns a URL that allows anyone to download the named
+// file until expiry passes, e.g. to redirect HTTP clients to S3 instead
+// of proxying the content.
+//
+// The file is checked with Stat first, so PresignGetObject fails with
+// fs.ErrNotExist if it does not exist. The client passed to New has to be
+// *s3.Client or implement the methods of *s3.PresignClient.
+func (f *S3FS) PresignGetObject(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	u, err := f.presignGetObject(ctx, name, expiry)
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return u, nil
+}
+
+func (f *S3FS) presignGetObject(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	if !f.validPath(name) || name == "." {
+		return "", fs.ErrInvalid
+	}
+
+	cl, err := f.presignClient("PresignGetObject")
+	if err != nil {
+		return "", err
+	}
+
+	fi, err := f.WithContext(ctx).stat(name)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return "", errors.New("is a directory")
+	}
+
+	req, err := cl.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    ptr(f.key(name)),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// PresignPutObject returns a URL that allows anyone to upload the named
+// file with an HTTP PUT request until expiry passes.
+//
+// Options such as WithACL and WithSSEKMS are not part of the URL, so they
+// don't apply to files uploaded with it. The client passed to New has to
+// be *s3.Client or implement the methods of *s3.PresignClient.
+func (f *S3FS) PresignPutObject(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	u, err := f.presignPutObject(ctx, name, expiry)
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return u, nil
+}
+
+func (f *S3FS) presignPutObject(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	if !f.validPath(name) || name == "." {
+		return "", fs.ErrInvalid
+	}
+
+	cl, err := f.presignClient("PresignPutObject")
+	if err != nil {
+		return "", err
+	}
+
+	req, err := cl.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: &f.bucket,
+		Key:    ptr(f.key(name)),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (f *S3FS) presignClient(method string) (presignClient, error) {
+	switch cl := f.cl.(type) {
+	case *s3.Client:
+		return s3.NewPresignClient(cl), nil
+	case presignClient:
+		return cl, nil
+	}
+	return nil, errUnsupported(method)
+}
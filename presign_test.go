@@ -0,0 +1,91 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestPresign(t *testing.T) {
+	s3cl := s3.New(s3.Options{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "key", SecretAccessKey: "secret"}, nil
+		}),
+	})
+
+	fsys := s3fs.New(&presignMemClient{
+		memClient:     newMemClient("dir/file.txt"),
+		PresignClient: s3.NewPresignClient(s3cl),
+	}, "test")
+
+	t.Run("get", func(t *testing.T) {
+		s, err := fsys.PresignGetObject(context.Background(), "dir/file.txt", time.Hour)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if !strings.HasSuffix(u.Path, "/dir/file.txt") {
+			t.Errorf("expected the key in the path; got %q", u.Path)
+		}
+
+		if exp := u.Query().Get("X-Amz-Expires"); exp != "3600" {
+			t.Errorf("want X-Amz-Expires=3600; got %q", exp)
+		}
+	})
+
+	t.Run("get not exist", func(t *testing.T) {
+		if _, err := fsys.PresignGetObject(context.Background(), "not-exist", time.Hour); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("put", func(t *testing.T) {
+		s, err := fsys.PresignPutObject(context.Background(), "new.txt", time.Minute)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if !strings.HasSuffix(u.Path, "/new.txt") {
+			t.Errorf("expected the key in the path; got %q", u.Path)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, name := range []string{".", "/a", "a/../b"} {
+			if _, err := fsys.PresignPutObject(context.Background(), name, time.Minute); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%q: want %v; got %v", name, fs.ErrInvalid, err)
+			}
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient("file.txt"), "test")
+		if _, err := fsys.PresignGetObject(context.Background(), "file.txt", time.Hour); !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("want %v; got %v", errors.ErrUnsupported, err)
+		}
+	})
+}
+
+type presignMemClient struct {
+	*memClient
+	*s3.PresignClient
+}
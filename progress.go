@@ -0,0 +1,103 @@
+package s3fs
+
+import "io"
+
+// ProgressFunc is called with the number of bytes of a file downloaded so
+// far, and its total size or -1 if the size is unknown.
+type ProgressFunc func(downloaded, total int64)
+
+// WithDownloadProgress calls fn after every read from the body of a file
+// opened with Open, OpenVersion, OpenIfModifiedSince or ReadFile, e.g. to
+// display a progress bar.
+//
+// fn is called on the goroutine reading the file, so it must not block;
+// to update a UI, send the values to a buffered channel instead. Bytes
+// read after Seek or with ReadAt are not reported.
+func WithDownloadProgress(fn ProgressFunc) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.progress = fn
+	})
+}
+
+// progressBody wraps the body of a GetObject response to report progress.
+func (f *S3FS) progressBody(body io.ReadCloser, contentLength *int64) io.ReadCloser {
+	if f.progress == nil {
+		return body
+	}
+
+	total := int64(-1)
+	if contentLength != nil {
+		total = *contentLength
+	}
+	return &progressReader{ReadCloser: body, fn: f.progress, total: total}
+}
+
+type progressReader struct {
+	io.ReadCloser
+	fn         ProgressFunc
+	downloaded int64
+	total      int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.downloaded += int64(n)
+		r.fn(r.downloaded, r.total)
+	}
+	return n, err
+}
+
+// UploadProgressFunc is called with the number of bytes of a file uploaded
+// so far, and its total size or -1 if the size is not known yet.
+type UploadProgressFunc func(uploaded, total int64)
+
+// WithUploadProgress calls fn while files are written with WriteFile or
+// OpenForWrite. Files uploaded with a single PutObject call report progress
+// after every read of the request body, and multipart uploads after every
+// part that was uploaded. The total size of a multipart upload is only
+// known once the file is closed.
+//
+// The parts of a multipart upload are uploaded by several goroutines, so
+// fn may be called from any of them, but never concurrently for the same
+// file. fn must not block.
+func WithUploadProgress(fn UploadProgressFunc) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.uploadProgress = fn
+	})
+}
+
+// uploadProgressBody wraps the body of a PutObject request to report
+// progress. It can be rewound for retries.
+func (f *S3FS) uploadProgressBody(body io.ReadSeeker, size int64) io.ReadSeeker {
+	if f.uploadProgress == nil {
+		return body
+	}
+	return &uploadProgressReader{ReadSeeker: body, fn: f.uploadProgress, total: size}
+}
+
+type uploadProgressReader struct {
+	io.ReadSeeker
+	fn       UploadProgressFunc
+	uploaded int64
+	total    int64
+}
+
+func (r *uploadProgressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	if n > 0 {
+		r.uploaded += int64(n)
+		r.fn(r.uploaded, r.total)
+	}
+	return n, err
+}
+
+// Seek makes the progress follow the offset, since the SDK may read the
+// body to sign it before sending it, and retries send it again.
+func (r *uploadProgressReader) Seek(offset int64, whence int) (int64, error) {
+	n, err := r.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		r.uploaded = n
+	}
+	return n, err
+}
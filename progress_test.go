@@ -0,0 +1,129 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWithDownloadProgress(t *testing.T) {
+	type progress struct{ downloaded, total int64 }
+
+	t.Run("open", func(t *testing.T) {
+		var got []progress
+		fsys := s3fs.New(newMemClient("file.txt"), "test", s3fs.WithDownloadProgress(func(downloaded, total int64) {
+			got = append(got, progress{downloaded, total})
+		}))
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		if _, err := io.ReadAll(iotest.HalfReader(f)); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if want := (progress{7, 7}); len(got) == 0 || got[len(got)-1] != want {
+			t.Errorf("expected the last progress to be %v; got %v", want, got)
+		}
+
+		for i := 1; i < len(got); i++ {
+			if got[i].downloaded <= got[i-1].downloaded {
+				t.Errorf("expected cumulative progress; got %v", got)
+			}
+		}
+	})
+
+	t.Run("read file unknown size", func(t *testing.T) {
+		var got []progress
+		fsys := s3fs.New(&noLengthClient{newMemClient("file.txt")}, "test", s3fs.WithDownloadProgress(func(downloaded, total int64) {
+			got = append(got, progress{downloaded, total})
+		}))
+
+		if _, err := fsys.ReadFile("file.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if want := []progress{{7, -1}}; !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v; got %v", want, got)
+		}
+	})
+}
+
+// noLengthClient omits the Content-Length of GetObject responses, like a
+// chunked response.
+type noLengthClient struct {
+	*memClient
+}
+
+func (c *noLengthClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.ContentLength = nil
+	return out, nil
+}
+
+func TestWithUploadProgress(t *testing.T) {
+	type progress struct{ uploaded, total int64 }
+
+	t.Run("put object", func(t *testing.T) {
+		var got []progress
+		fsys := s3fs.New(newMemClient(), "test", s3fs.WithUploadProgress(func(uploaded, total int64) {
+			got = append(got, progress{uploaded, total})
+		}))
+
+		if err := fsys.WriteFile("file.txt", []byte("data"), 0644); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if want := (progress{4, 4}); len(got) == 0 || got[len(got)-1] != want {
+			t.Errorf("expected the last progress to be %v; got %v", want, got)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		const partSize = 5 << 20
+
+		var (
+			mu  sync.Mutex
+			got []progress
+		)
+		fsys := s3fs.New(newMultipartClient(), "test",
+			s3fs.WithPartSize(partSize),
+			s3fs.WithUploadWorkers(2),
+			s3fs.WithUploadProgress(func(uploaded, total int64) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, progress{uploaded, total})
+			}),
+		)
+
+		data := bytes.Repeat([]byte("a"), 2*partSize+10)
+		writeAll(t, fsys, "large.bin", data)
+
+		if len(got) != 3 {
+			t.Fatalf("expected progress after each of 3 parts; got %v", got)
+		}
+
+		for i, p := range got {
+			if i > 0 && p.uploaded <= got[i-1].uploaded {
+				t.Errorf("expected cumulative progress; got %v", got)
+			}
+		}
+
+		if want := (progress{int64(len(data)), int64(len(data))}); got[2] != want {
+			t.Errorf("want %v; got %v", want, got[2])
+		}
+	})
+}
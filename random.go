@@ -0,0 +1,99 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"math/rand"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PickRandom opens a random file from the directory prefix or any of its
+// subdirectories, and returns its name along with the file.
+//
+// Keys are streamed from ListObjectsV2 and sampled with reservoir sampling,
+// so memory usage does not depend on the number of files.
+func PickRandom(ctx context.Context, fsys *S3FS, prefix string, rng *rand.Rand) (string, fs.File, error) {
+	names, files, err := PickN(ctx, fsys, prefix, 1, rng)
+	if err != nil {
+		return "", nil, err
+	}
+	return names[0], files[0], nil
+}
+
+// PickN opens n distinct random files from the directory prefix or any of
+// its subdirectories. If there are fewer than n files, all of them are
+// returned. The caller is responsible for closing the returned files.
+//
+// n must be positive and rng must not be nil, otherwise fs.ErrInvalid is
+// returned.
+func PickN(ctx context.Context, fsys *S3FS, prefix string, n int, rng *rand.Rand) ([]string, []fs.File, error) {
+	names, err := sampleKeys(ctx, fsys, prefix, n, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]fs.File, 0, len(names))
+	for _, name := range names {
+		f, err := fsys.OpenContext(ctx, name)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, nil, err
+		}
+		files = append(files, f)
+	}
+
+	return names, files, nil
+}
+
+func sampleKeys(ctx context.Context, fsys *S3FS, prefix string, n int, rng *rand.Rand) ([]string, error) {
+	if !fsys.validPath(prefix) || n <= 0 || rng == nil {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: prefix,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	p := prefix + fsys.delim
+	if prefix == "." {
+		p = ""
+	}
+
+	var (
+		sample = make([]string, 0, n)
+		seen   int64
+	)
+	err := fsys.listAll(ctx, p, func(name string, _ types.Object) error {
+		if strings.HasSuffix(name, fsys.delim) {
+			return nil
+		}
+
+		seen++
+		if len(sample) < n {
+			sample = append(sample, name)
+			return nil
+		}
+
+		if i := rng.Int63n(seen); i < int64(n) {
+			sample[i] = name
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sample) == 0 {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: prefix,
+			Err:  fs.ErrNotExist,
+		}
+	}
+
+	return sample, nil
+}
@@ -0,0 +1,83 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"math/rand"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestPickRandom(t *testing.T) {
+	files := []string{"a.txt", "dir/a.txt", "dir/b.txt", "dir/sub/c.txt", "other.txt"}
+	fsys := s3fs.New(newMemClient(files...), "test", s3fs.WithPageSize(1))
+
+	t.Run("single", func(t *testing.T) {
+		name, f, err := s3fs.PickRandom(context.Background(), fsys, "dir", rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		switch name {
+		case "dir/a.txt", "dir/b.txt", "dir/sub/c.txt":
+		default:
+			t.Errorf("expected a file from dir; got %s", name)
+		}
+	})
+
+	t.Run("n", func(t *testing.T) {
+		names, opened, err := s3fs.PickN(context.Background(), fsys, ".", 3, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(names) != 3 || len(opened) != 3 {
+			t.Fatalf("expected 3 files; got %v", names)
+		}
+
+		seen := make(map[string]bool)
+		for i, name := range names {
+			opened[i].Close()
+			if seen[name] {
+				t.Errorf("duplicate file %s", name)
+			}
+			seen[name] = true
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, _, err := s3fs.PickRandom(context.Background(), fsys, "notexist", rand.New(rand.NewSource(1)))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fixtures := []struct {
+			desc string
+			n    int
+			rng  *rand.Rand
+		}{
+			{desc: "negative n", n: -1, rng: rand.New(rand.NewSource(1))},
+			{desc: "zero n", n: 0, rng: rand.New(rand.NewSource(1))},
+			{desc: "nil rng", n: 1},
+		}
+
+		for _, f := range fixtures {
+			t.Run(f.desc, func(t *testing.T) {
+				cl := newMemClient(files...)
+				_, _, err := s3fs.PickN(context.Background(), s3fs.New(cl, "test"), ".", f.n, f.rng)
+				if !errors.Is(err, fs.ErrInvalid) {
+					t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+				}
+
+				if n := cl.count("ListObjectsV2"); n != 0 {
+					t.Errorf("expected no listing; got %d", n)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package s3fs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/time/rate"
+)
+
+// WithRateLimit limits the number of S3 calls made by S3FS, including
+// its copies returned by WithContext and Sub, to requestsPerSecond with
+// bursts of up to burst calls. Calls wait for their turn until their
+// context is done.
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.limits.all = rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+	})
+}
+
+// WithOpRateLimits limits the number of ListObjectsV2, HeadObject and
+// GetObject calls per second separately. A limit of zero or less means no
+// limit for the operation. It can be combined with WithRateLimit, in which
+// case both limits apply.
+func WithOpRateLimits(list, head, get float64) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.limits.list = newOpLimiter(list)
+		fsys.limits.head = newOpLimiter(head)
+		fsys.limits.get = newOpLimiter(get)
+	})
+}
+
+func newOpLimiter(r float64) *rate.Limiter {
+	if r <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(r), 1)
+}
+
+type rateLimits struct {
+	all  *rate.Limiter
+	list *rate.Limiter
+	head *rate.Limiter
+	get  *rate.Limiter
+}
+
+// wait blocks until the call with the input in is allowed.
+func (l rateLimits) wait(ctx context.Context, in any) error {
+	if l.all != nil {
+		if err := l.all.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	var op *rate.Limiter
+	switch in.(type) {
+	case *s3.ListObjectsV2Input:
+		op = l.list
+	case *s3.HeadObjectInput:
+		op = l.head
+	case *s3.GetObjectInput:
+		op = l.get
+	}
+
+	if op == nil {
+		return nil
+	}
+	return op.Wait(ctx)
+}
@@ -0,0 +1,59 @@
+package s3fs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	fsys := s3fs.New(newMemClient("file.txt"), "test", s3fs.WithRateLimit(100, 1))
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := fsys.Stat("file.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+	}
+
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("expected calls to be limited; took %v", d)
+	}
+
+	t.Run("context", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient("file.txt"), "test", s3fs.WithRateLimit(0.001, 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		fsys = fsys.WithContext(ctx)
+		if _, err := fsys.Stat("file.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, err := fsys.Stat("file.txt"); err == nil {
+			t.Error("expected the limiter to fail once the context is done")
+		}
+	})
+}
+
+func TestWithOpRateLimits(t *testing.T) {
+	fsys := s3fs.New(newMemClient("file.txt"), "test", s3fs.WithOpRateLimits(0, 0.001, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	fsys = fsys.WithContext(ctx)
+	for i := 0; i < 3; i++ {
+		if _, err := fsys.Open("file.txt"); err != nil {
+			t.Fatal("expected GetObject not to be limited; got ", err)
+		}
+	}
+
+	fsys.Stat("file.txt")
+	if _, err := fsys.Stat("file.txt"); err == nil {
+		t.Error("expected HeadObject to be limited")
+	}
+}
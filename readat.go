@@ -0,0 +1,75 @@
+package s3fs
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var _ io.ReaderAt = (*ReadAtFile)(nil)
+
+// ReadAtFile is a file opened for random access with OpenReadAt.
+type ReadAtFile struct {
+	fsys *S3FS
+	name string
+	size int64
+	eTag string
+}
+
+// OpenReadAt opens the named file for random access, e.g. with archive/zip
+// or readers of columnar formats such as Parquet. Unlike Open, it does not
+// request the content of the file: it only makes a HeadObject call to get
+// its size and ETag.
+func (f *S3FS) OpenReadAt(name string) (*ReadAtFile, error) {
+	if !f.validPath(name) || name == "." {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	head, err := call(f, f.ctx, f.cl.HeadObject, &s3.HeadObjectInput{
+		Bucket: &f.bucket,
+		Key:    ptr(f.key(name)),
+	})
+	if err != nil {
+		switch {
+		case f.isNotFoundErr(err):
+			err = fs.ErrNotExist
+		case f.isPermissionErr(err):
+			err = fs.ErrPermission
+		}
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	return &ReadAtFile{
+		fsys: f,
+		name: name,
+		size: derefInt64(head.ContentLength),
+		eTag: derefString(head.ETag),
+	}, nil
+}
+
+// ReadAt implements io.ReaderAt. Every call issues its own ranged
+// GetObject request, so it is safe for concurrent use.
+//
+// If the file changed since it was opened, ReadAt fails with an error
+// wrapping fs.ErrNotExist.
+func (f *ReadAtFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= f.size && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return f.fsys.readAt("s3fs.ReadAtFile.ReadAt", f.name, f.eTag, nil, p, off)
+}
+
+// Size returns the size of the file when it was opened.
+func (f *ReadAtFile) Size() int64 { return f.size }
+
+// ETag returns the ETag of the file when it was opened.
+func (f *ReadAtFile) ETag() string { return f.eTag }
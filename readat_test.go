@@ -0,0 +1,111 @@
+package s3fs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestOpenReadAt(t *testing.T) {
+	cl := newMemClient()
+	cl.objects["file.txt"] = "0123456789"
+
+	fsys := s3fs.New(cl, "test")
+
+	f, err := fsys.OpenReadAt("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if f.Size() != 10 {
+		t.Errorf("want size 10; got %d", f.Size())
+	}
+
+	if n := cl.count("GetObject"); n != 0 {
+		t.Errorf("expected no GetObject calls; got %d", n)
+	}
+
+	fixtures := []struct {
+		off  int64
+		size int
+		want string
+		err  error
+	}{
+		{off: 0, size: 4, want: "0123"},
+		{off: 6, size: 4, want: "6789"},
+		{off: 8, size: 4, want: "89", err: io.EOF},
+		{off: 10, size: 4, want: "", err: io.EOF},
+	}
+
+	var wg sync.WaitGroup
+	for _, fixture := range fixtures {
+		fixture := fixture
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p := make([]byte, fixture.size)
+			n, err := f.ReadAt(p, fixture.off)
+			if err != fixture.err {
+				t.Errorf("off=%d: want err %v; got %v", fixture.off, fixture.err, err)
+			}
+			if got := string(p[:n]); got != fixture.want {
+				t.Errorf("off=%d: want %q; got %q", fixture.off, fixture.want, got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cl.objects["file.txt"] = "changed"
+
+	if _, err := f.ReadAt(make([]byte, 1), 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist after the file changed; got %v", err)
+	}
+
+	if _, err := fsys.OpenReadAt("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestOpenReadAtZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("inner.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("zipped")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cl := newMemClient()
+	cl.objects["archive.zip"] = buf.String()
+
+	f, err := s3fs.New(cl, "test").OpenReadAt("archive.zip")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	zr, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	data, err := fs.ReadFile(zr, "inner.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(data) != "zipped" {
+		t.Errorf("want %q; got %q", "zipped", data)
+	}
+}
@@ -0,0 +1,104 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+)
+
+var (
+	_ fs.FS         = (*ReadOnlyFS)(nil)
+	_ fs.StatFS     = (*ReadOnlyFS)(nil)
+	_ fs.ReadDirFS  = (*ReadOnlyFS)(nil)
+	_ fs.ReadFileFS = (*ReadOnlyFS)(nil)
+	_ fs.GlobFS     = (*ReadOnlyFS)(nil)
+	_ ContextFS     = (*ReadOnlyFS)(nil)
+	_ WriteFS       = (*ReadOnlyFS)(nil)
+	_ DeleteFS      = (*ReadOnlyFS)(nil)
+)
+
+// ReadOnlyFS wraps S3FS and rejects every modification with
+// fs.ErrPermission, even if the underlying credentials allow writes.
+// It is useful when passing the filesystem to code that should never
+// modify the bucket, e.g. template engines or static site generators.
+type ReadOnlyFS struct {
+	inner *S3FS
+}
+
+// NewReadOnlyFS returns a read-only view of inner.
+func NewReadOnlyFS(inner *S3FS) *ReadOnlyFS {
+	return &ReadOnlyFS{inner: inner}
+}
+
+// WithContext returns a read-only view of inner.WithContext(ctx).
+func (r *ReadOnlyFS) WithContext(ctx context.Context) *ReadOnlyFS {
+	return &ReadOnlyFS{inner: r.inner.WithContext(ctx)}
+}
+
+// OpenContext implements ContextFS.
+func (r *ReadOnlyFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	return r.inner.OpenContext(ctx, name)
+}
+
+// Open implements fs.FS.
+func (r *ReadOnlyFS) Open(name string) (fs.File, error) { return r.inner.Open(name) }
+
+// Stat implements fs.StatFS.
+func (r *ReadOnlyFS) Stat(name string) (fs.FileInfo, error) { return r.inner.Stat(name) }
+
+// ReadDir implements fs.ReadDirFS.
+func (r *ReadOnlyFS) ReadDir(name string) ([]fs.DirEntry, error) { return r.inner.ReadDir(name) }
+
+// ReadFile implements fs.ReadFileFS.
+func (r *ReadOnlyFS) ReadFile(name string) ([]byte, error) { return fs.ReadFile(r.inner, name) }
+
+// Glob implements fs.GlobFS.
+func (r *ReadOnlyFS) Glob(pattern string) ([]string, error) { return fs.Glob(r.inner, pattern) }
+
+// WriteFile always returns fs.ErrPermission.
+func (r *ReadOnlyFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return errReadOnly("write", name)
+}
+
+// Create always returns fs.ErrPermission.
+func (r *ReadOnlyFS) Create(name string) (io.WriteCloser, error) {
+	return nil, errReadOnly("create", name)
+}
+
+// OpenForWrite always returns fs.ErrPermission.
+func (r *ReadOnlyFS) OpenForWrite(name string) (io.WriteCloser, error) {
+	return nil, errReadOnly("open", name)
+}
+
+// Remove always returns fs.ErrPermission.
+func (r *ReadOnlyFS) Remove(name string) error {
+	return errReadOnly("remove", name)
+}
+
+// RemoveAll always returns fs.ErrPermission.
+func (r *ReadOnlyFS) RemoveAll(name string) error {
+	return errReadOnly("removeall", name)
+}
+
+// Rename always returns fs.ErrPermission.
+func (r *ReadOnlyFS) Rename(oldname, newname string) error {
+	return errReadOnly("rename", oldname)
+}
+
+// MkdirAll always returns fs.ErrPermission.
+func (r *ReadOnlyFS) MkdirAll(path string, perm fs.FileMode) error {
+	return errReadOnly("mkdir", path)
+}
+
+// Copy always returns fs.ErrPermission.
+func (r *ReadOnlyFS) Copy(src, dst string) error {
+	return errReadOnly("copy", dst)
+}
+
+func errReadOnly(op, name string) error {
+	return &fs.PathError{
+		Op:   op,
+		Path: name,
+		Err:  fs.ErrPermission,
+	}
+}
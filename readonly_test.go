@@ -0,0 +1,62 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestReadOnlyFS(t *testing.T) {
+	rfs := s3fs.NewReadOnlyFS(s3fs.New(newMemClient("a.txt", "dir/b.txt"), "test"))
+
+	t.Run("read", func(t *testing.T) {
+		data, err := fs.ReadFile(rfs, "dir/b.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != "content" {
+			t.Errorf("want %q; got %q", "content", data)
+		}
+
+		des, err := fs.ReadDir(rfs, ".")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(des) != 2 {
+			t.Errorf("expected 2 entries; got %d", len(des))
+		}
+
+		matches, err := fs.Glob(rfs, "*.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if len(matches) != 1 || matches[0] != "a.txt" {
+			t.Errorf("want [a.txt]; got %v", matches)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		_, createErr := rfs.Create("new.txt")
+		_, openErr := rfs.OpenForWrite("new.txt")
+
+		for _, err := range []error{
+			rfs.WriteFile("new.txt", []byte("content"), 0644),
+			createErr,
+			openErr,
+			rfs.Remove("a.txt"),
+			rfs.RemoveAll("dir"),
+			rfs.Rename("a.txt", "b.txt"),
+			rfs.MkdirAll("new", 0755),
+			rfs.Copy("a.txt", "b.txt"),
+		} {
+			if !errors.Is(err, fs.ErrPermission) {
+				t.Errorf("want %v; got %v", fs.ErrPermission, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package s3fs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+var (
+	_ fs.FS        = (*ReadThroughCacheFS)(nil)
+	_ fs.StatFS    = (*ReadThroughCacheFS)(nil)
+	_ fs.ReadDirFS = (*ReadThroughCacheFS)(nil)
+)
+
+// CacheOption is a function that provides optional features to
+// ReadThroughCacheFS.
+type CacheOption func(*ReadThroughCacheFS)
+
+// WithCacheKeyHasher sets a function that maps S3 keys to keys used in
+// the cache filesystem. By default keys are used as they are.
+func WithCacheKeyHasher(fn func(s3key string) string) CacheOption {
+	return func(c *ReadThroughCacheFS) { c.hashKey = fn }
+}
+
+// ReadThroughCacheFS serves files from a fast local store and falls back
+// to S3 on a cache miss.
+//
+// On a miss the whole file is read from S3 and writeBack is called
+// asynchronously with the cache key and the contents of the file, so that
+// the next Open can be served from the cache. Errors returned by writeBack
+// are ignored; the file is simply fetched from S3 again next time. Close
+// waits for the pending writeBack calls.
+//
+// Directories are always listed from S3 since the cache may contain only
+// a subset of the files.
+type ReadThroughCacheFS struct {
+	inner     *S3FS
+	cache     fs.FS
+	writeBack func(name string, data []byte) error
+	hashKey   func(s3key string) string
+
+	wg sync.WaitGroup
+}
+
+// NewReadThroughCacheFS returns a new read-through cache in front of inner.
+func NewReadThroughCacheFS(inner *S3FS, cacheFS fs.FS, writeBack func(name string, data []byte) error, opts ...CacheOption) *ReadThroughCacheFS {
+	c := &ReadThroughCacheFS{
+		inner:     inner,
+		cache:     cacheFS,
+		writeBack: writeBack,
+		hashKey:   func(s3key string) string { return s3key },
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Open implements fs.FS.
+func (c *ReadThroughCacheFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if f, ok := c.openCached(name); ok {
+		return f, nil
+	}
+
+	f, err := c.inner.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return f, nil
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "read",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if c.writeBack != nil {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.writeBack(c.cacheKey(name), data)
+		}()
+	}
+
+	return &memFile{
+		Reader: bytes.NewReader(data),
+		fi:     fi,
+	}, nil
+}
+
+// Stat implements fs.StatFS.
+func (c *ReadThroughCacheFS) Stat(name string) (fs.FileInfo, error) {
+	if fs.ValidPath(name) {
+		fi, err := fs.Stat(c.cache, c.cacheKey(name))
+		if err == nil && !fi.IsDir() {
+			return renamedFileInfo{fi, path.Base(name)}, nil
+		}
+	}
+	return c.inner.Stat(name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (c *ReadThroughCacheFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return c.inner.ReadDir(name)
+}
+
+// Close waits for the pending writeBack calls to return. It does not
+// close the cache or inner.
+func (c *ReadThroughCacheFS) Close() error {
+	c.wg.Wait()
+	return nil
+}
+
+// cacheKey returns the key of the file name in the cache, which is the
+// hashed S3 key of name.
+func (c *ReadThroughCacheFS) cacheKey(name string) string {
+	return c.hashKey(c.inner.key(name))
+}
+
+func (c *ReadThroughCacheFS) openCached(name string) (fs.File, bool) {
+	f, err := c.cache.Open(c.cacheKey(name))
+	if err != nil {
+		return nil, false
+	}
+
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		f.Close()
+		return nil, false
+	}
+
+	return cachedFile{File: f, name: path.Base(name)}, true
+}
+
+// cachedFile reports the name of the S3 object instead of the cache key,
+// which may differ when a key hasher is used.
+type cachedFile struct {
+	fs.File
+	name string
+}
+
+func (f cachedFile) Stat() (fs.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return renamedFileInfo{fi, f.name}, nil
+}
+
+type renamedFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (fi renamedFileInfo) Name() string { return fi.name }
+
+// memFile is a file whose contents were fully read into memory.
+type memFile struct {
+	*bytes.Reader
+	fi fs.FileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+func (f *memFile) Close() error               { return nil }
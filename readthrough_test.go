@@ -0,0 +1,157 @@
+package s3fs_test
+
+import (
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestReadThroughCacheFS(t *testing.T) {
+	cl := newMemClient("dir/file.txt")
+
+	var (
+		mu    sync.Mutex
+		cache = fstest.MapFS{}
+		done  = make(chan struct{})
+	)
+
+	cfs := s3fs.NewReadThroughCacheFS(
+		s3fs.New(cl, "test"),
+		lockedFS{&mu, cache},
+		func(name string, data []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			cache[name] = &fstest.MapFile{Data: data}
+			close(done)
+			return nil
+		},
+		s3fs.WithCacheKeyHasher(func(key string) string { return "cache/" + key }),
+	)
+
+	read := func(t *testing.T) {
+		t.Helper()
+
+		f, err := cfs.Open("dir/file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != "content" {
+			t.Errorf("want %q; got %q", "content", data)
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if fi.Name() != "file.txt" {
+			t.Errorf("want %q; got %q", "file.txt", fi.Name())
+		}
+	}
+
+	read(t)
+	<-done
+
+	if n := cl.count("GetObject"); n != 1 {
+		t.Fatalf("expected 1 GetObject call; got %d", n)
+	}
+
+	if _, ok := cache["cache/dir/file.txt"]; !ok {
+		t.Fatal("expected file to be written back to the cache")
+	}
+
+	read(t)
+
+	if n := cl.count("GetObject"); n != 1 {
+		t.Errorf("expected second read to hit the cache; got %d GetObject calls", n)
+	}
+
+	if _, err := cfs.Stat("dir/file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if n := cl.count("HeadObject"); n != 0 {
+		t.Errorf("expected Stat to hit the cache; got %d HeadObject calls", n)
+	}
+}
+
+func TestReadThroughCacheFSPrefix(t *testing.T) {
+	cl := newMemClient("dir/file.txt")
+
+	fsys, err := s3fs.New(cl, "test").Sub("dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	var (
+		mu     sync.Mutex
+		cache  = fstest.MapFS{}
+		hashed []string
+	)
+
+	cfs := s3fs.NewReadThroughCacheFS(
+		fsys.(*s3fs.S3FS),
+		lockedFS{&mu, cache},
+		func(name string, data []byte) error {
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			cache[name] = &fstest.MapFile{Data: data}
+			return nil
+		},
+		s3fs.WithCacheKeyHasher(func(key string) string {
+			mu.Lock()
+			defer mu.Unlock()
+			hashed = append(hashed, key)
+			return "cache/" + key
+		}),
+	)
+
+	data, err := fs.ReadFile(cfs, "file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	if err := cfs.Close(); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	// Close waited for writeBack, so no locking is needed.
+	if _, ok := cache["cache/dir/file.txt"]; !ok {
+		t.Errorf("expected the file to be written back under its S3 key; got %v", cache)
+	}
+
+	for _, key := range hashed {
+		if key != "dir/file.txt" {
+			t.Errorf("expected the S3 key to be hashed; got %q", key)
+		}
+	}
+}
+
+type lockedFS struct {
+	mu *sync.Mutex
+	fs.FS
+}
+
+func (l lockedFS) Open(name string) (fs.File, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.FS.Open(name)
+}
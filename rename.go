@@ -0,0 +1,223 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// defaultRenameWorkers is the number of concurrent CopyObject calls made
+// by Rename when WithRenameWorkers is not used.
+const defaultRenameWorkers = 10
+
+type copyObjectClient interface {
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+// WithRenameWorkers sets the number of objects copied concurrently when
+// renaming a directory. The default is 10.
+func WithRenameWorkers(n int) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.renameWorkers = n
+	})
+}
+
+// RenameError is returned by Rename.
+//
+// Copied lists the names of the objects that were already copied to
+// newname, but not deleted from oldname. They exist under both names
+// until the caller removes them or retries the rename.
+type RenameError struct {
+	Old    string
+	New    string
+	Copied []string
+	Err    error
+}
+
+func (e *RenameError) Error() string {
+	return "rename " + e.Old + " " + e.New + ": " + e.Err.Error()
+}
+
+func (e *RenameError) Unwrap() error { return e.Err }
+
+// Rename renames oldname to newname. If oldname is a directory, all the
+// files it contains are moved.
+//
+// S3 has no rename operation, so every object is copied with CopyObject
+// and then deleted. The operation is not atomic: if it fails midway,
+// some objects may exist under both names; see RenameError. Objects
+// larger than 5GB cannot be renamed, since CopyObject does not support
+// them. Existing files under newname are overwritten.
+func (f *S3FS) Rename(oldname, newname string) error {
+	copied, err := f.rename(f.ctx, oldname, newname)
+	if err != nil {
+		return &RenameError{
+			Old:    oldname,
+			New:    newname,
+			Copied: copied,
+			Err:    err,
+		}
+	}
+	return nil
+}
+
+func (f *S3FS) rename(ctx context.Context, oldname, newname string) ([]string, error) {
+	if !f.validPath(oldname) || oldname == "." || !f.validPath(newname) || newname == "." {
+		return nil, fs.ErrInvalid
+	}
+
+	if _, ok := f.cl.(copyObjectClient); !ok {
+		return nil, errUnsupported("CopyObject")
+	}
+
+	fi, err := f.stat(oldname)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.invalidate(oldname, true)
+	defer f.invalidate(newname, true)
+
+	if !fi.IsDir() {
+		if err := f.copyObject(ctx, oldname, newname); err != nil {
+			return nil, err
+		}
+		if err := f.deleteObject(ctx, oldname); err != nil {
+			return []string{oldname}, err
+		}
+		return nil, nil
+	}
+
+	var names []string
+	err = f.listAll(ctx, oldname+f.delim, func(name string, _ types.Object) error {
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	copied, err := f.copyAll(ctx, names, oldname, newname)
+	if err != nil {
+		return copied, err
+	}
+
+	return f.deleteAll(ctx, copied)
+}
+
+// copyAll copies names from the directory oldname to newname using
+// f.renameWorkers goroutines, and returns the names that were copied.
+func (f *S3FS) copyAll(parent context.Context, names []string, oldname, newname string) ([]string, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	workers := f.renameWorkers
+	if workers <= 0 {
+		workers = defaultRenameWorkers
+	}
+
+	var (
+		mu     sync.Mutex
+		copied = make([]string, 0, len(names))
+		errs   []error
+		wg     sync.WaitGroup
+		jobs   = make(chan string)
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				err := f.copyObject(ctx, name, newname+strings.TrimPrefix(name, oldname))
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+					cancel()
+				} else {
+					copied = append(copied, name)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+loop:
+	for _, name := range names {
+		select {
+		case jobs <- name:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(errs) == 0 && len(copied) < len(names) {
+		errs = append(errs, parent.Err())
+	}
+
+	return copied, errors.Join(errs...)
+}
+
+// deleteAll deletes names in batches and returns the names that could not
+// be deleted.
+func (f *S3FS) deleteAll(ctx context.Context, names []string) ([]string, error) {
+	if _, ok := f.cl.(deleteObjectsClient); !ok {
+		return names, errUnsupported("DeleteObjects")
+	}
+
+	var (
+		failed []string
+		errs   []error
+	)
+	for i := 0; i < len(names); i += maxDeleteObjects {
+		batch := names[i:min(i+maxDeleteObjects, len(names))]
+
+		objects := make([]types.ObjectIdentifier, 0, len(batch))
+		for _, name := range batch {
+			objects = append(objects, types.ObjectIdentifier{Key: ptr(f.key(name))})
+		}
+
+		out, err := f.deleteObjects(ctx, objects)
+		if err != nil {
+			return append(failed, names[i:]...), errors.Join(append(errs, err)...)
+		}
+
+		for _, e := range out {
+			failed = append(failed, strings.TrimPrefix(derefString(e.Key), f.prefix))
+			errs = append(errs, f.deleteError(e))
+		}
+	}
+
+	return failed, errors.Join(errs...)
+}
+
+func (f *S3FS) copyObject(ctx context.Context, src, dst string) error {
+	_, err := call(f, ctx, f.cl.(copyObjectClient).CopyObject, &s3.CopyObjectInput{
+		Bucket:     &f.bucket,
+		Key:        ptr(f.key(dst)),
+		CopySource: ptr(copySource(f.bucket, f.key(src))),
+		ACL:        f.acl,
+
+		ServerSideEncryption: f.sse.algorithm,
+		SSEKMSKeyId:          f.sse.keyID(),
+	})
+	return err
+}
+
+// copySource returns the URL-encoded value of the x-amz-copy-source header.
+func copySource(bucket, key string) string {
+	parts := strings.Split(bucket+"/"+key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,131 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestRename(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		cl := newMemClient("dir/a b.txt")
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.Rename("dir/a b.txt", "c.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if _, ok := cl.objects["dir/a b.txt"]; ok {
+			t.Error("expected the old file to be deleted")
+		}
+
+		if cl.objects["c.txt"] != "content" {
+			t.Errorf("want %q; got %q", "content", cl.objects["c.txt"])
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		files := []string{"other.txt"}
+		for i := 0; i < 50; i++ {
+			files = append(files, fmt.Sprintf("old/%d/file.txt", i))
+		}
+
+		cl := newMemClient(files...)
+		fsys := s3fs.New(cl, "test", s3fs.WithRenameWorkers(4))
+
+		if err := fsys.Rename("old", "new/dir"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		var keys []string
+		for k := range cl.objects {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		if len(keys) != 51 || keys[0] != "new/dir/0/file.txt" || keys[50] != "other.txt" {
+			t.Errorf("unexpected objects after rename: %v", keys)
+		}
+
+		if n := cl.count("CopyObject"); n != 50 {
+			t.Errorf("expected 50 CopyObject calls; got %d", n)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(), "test")
+
+		err := fsys.Rename("a", "b")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		cl := &failingCopyClient{memClient: newMemClient("old/a.txt", "old/b.txt")}
+		fsys := s3fs.New(cl, "test", s3fs.WithRenameWorkers(1))
+
+		err := fsys.Rename("old", "new")
+
+		var rerr *s3fs.RenameError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("expected RenameError; got %v", err)
+		}
+
+		if len(rerr.Copied) != 1 || rerr.Copied[0] != "old/a.txt" {
+			t.Errorf("want [old/a.txt]; got %v", rerr.Copied)
+		}
+
+		if _, ok := cl.objects["old/a.txt"]; !ok {
+			t.Error("expected old/a.txt not to be deleted")
+		}
+	})
+}
+
+func TestRenameACL(t *testing.T) {
+	cl := &copyInputClient{memClient: newMemClient("a.txt")}
+	fsys := s3fs.New(cl, "test", s3fs.WithACL(types.ObjectCannedACLBucketOwnerFullControl))
+
+	if err := fsys.Rename("a.txt", "b.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(cl.inputs) != 1 {
+		t.Fatalf("expected 1 CopyObject call; got %d", len(cl.inputs))
+	}
+
+	if acl := cl.inputs[0].ACL; acl != types.ObjectCannedACLBucketOwnerFullControl {
+		t.Errorf("want ACL %q; got %q", types.ObjectCannedACLBucketOwnerFullControl, acl)
+	}
+}
+
+// copyInputClient records the inputs of CopyObject.
+type copyInputClient struct {
+	*memClient
+	inputs []*s3.CopyObjectInput
+}
+
+func (c *copyInputClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	c.inputs = append(c.inputs, in)
+	return c.memClient.CopyObject(ctx, in, optFns...)
+}
+
+// failingCopyClient fails to copy keys ending with b.txt.
+type failingCopyClient struct {
+	*memClient
+}
+
+func (c *failingCopyClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if strings.HasSuffix(*in.CopySource, "b.txt") {
+		return nil, errors.New("copy failed")
+	}
+	return c.memClient.CopyObject(ctx, in, optFns...)
+}
@@ -0,0 +1,101 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithRetry retries S3 calls that failed with a transient error, i.e. a 5xx
+// or 429 response or a network timeout, up to maxAttempts attempts in total.
+// The n-th retry waits baseDelay * 2^(n-1) with a jitter of 10%.
+//
+// Other errors are never retried. Retrying stops as soon as the context
+// is done.
+//
+// The retries of the SDK are disabled for the calls made by fsys, so that
+// a call is attempted at most maxAttempts times instead of maxAttempts
+// times the attempts of the client's retryer.
+//
+// Directories are listed page by page, so a retry repeats only the page
+// that failed. If ReadDir still fails, calling it again continues from
+// that page as well.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.retry = retryOptions{
+			maxAttempts: maxAttempts,
+			baseDelay:   baseDelay,
+		}
+	})
+}
+
+type retryOptions struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// retryable reports whether the call should be retried after attempt
+// failed with err.
+func (r retryOptions) retryable(attempt int, err error) bool {
+	return attempt+1 < r.maxAttempts && isTransientErr(err)
+}
+
+// wait sleeps before the retry of attempt or until ctx is done.
+func (r retryOptions) wait(ctx context.Context, attempt int) error {
+	d := r.baseDelay << attempt
+	d = time.Duration(float64(d) * (0.9 + 0.2*rand.Float64()))
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func isTransientErr(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var re interface{ HTTPStatusCode() int }
+	if errors.As(err, &re) {
+		code := re.HTTPStatusCode()
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// rewind prepares in to be sent again. It reports false if the body of
+// the request cannot be read again.
+func rewind(in any) bool {
+	var body io.Reader
+	switch in := in.(type) {
+	case *s3.PutObjectInput:
+		body = in.Body
+	case *s3.UploadPartInput:
+		body = in.Body
+	}
+
+	if body == nil {
+		return true
+	}
+
+	s, ok := body.(io.Seeker)
+	if !ok {
+		return false
+	}
+	_, err := s.Seek(0, io.SeekStart)
+	return err == nil
+}
@@ -0,0 +1,273 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWithRetry(t *testing.T) {
+	t.Run("transient", func(t *testing.T) {
+		cl := &flakyClient{memClient: newMemClient("file.txt"), failures: 2, status: http.StatusServiceUnavailable}
+		fsys := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond))
+
+		if _, err := fsys.Stat("file.txt"); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := fsys.WriteFile("new.txt", []byte("data"), 0644); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if data := cl.objects["new.txt"]; data != "data" {
+			t.Errorf("expected the body to be sent again; got %q", data)
+		}
+
+		if n := cl.attempts(); n != 6 {
+			t.Errorf("expected 6 attempts; got %d", n)
+		}
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		cl := &flakyClient{memClient: newMemClient("file.txt"), failures: 5, status: http.StatusTooManyRequests}
+		fsys := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond))
+
+		if _, err := fsys.Stat("file.txt"); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if n := cl.attempts(); n != 3 {
+			t.Errorf("expected 3 attempts; got %d", n)
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		cl := &flakyClient{memClient: newMemClient("file.txt"), failures: 5, status: http.StatusForbidden}
+		fsys := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond))
+
+		fsys.Stat("file.txt")
+
+		if n := cl.attempts(); n != 1 {
+			t.Errorf("expected 1 attempt; got %d", n)
+		}
+
+		cl = &flakyClient{memClient: newMemClient()}
+		fsys = s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond))
+
+		if _, err := fsys.Stat("not-exist"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		if n := cl.attempts(); n != 1 {
+			t.Errorf("expected 1 attempt; got %d", n)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		cl := &flakyClient{memClient: newMemClient("file.txt"), failures: 5, status: http.StatusInternalServerError}
+		fsys := s3fs.New(cl, "test", s3fs.WithRetry(5, time.Hour))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := fsys.WithContext(ctx).Stat("file.txt"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("sdk retries", func(t *testing.T) {
+		for _, f := range []struct {
+			opts     []s3fs.Option
+			disabled bool
+		}{
+			{opts: nil, disabled: false},
+			{opts: []s3fs.Option{s3fs.WithRetry(3, time.Millisecond)}, disabled: true},
+		} {
+			cl := &retryerClient{memClient: newMemClient("file.txt")}
+			if _, err := s3fs.New(cl, "test", f.opts...).Stat("file.txt"); err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			_, disabled := cl.retryer.(aws.NopRetryer)
+			if disabled != f.disabled {
+				t.Errorf("expected SDK retries to be disabled: %t; got retryer %T", f.disabled, cl.retryer)
+			}
+		}
+	})
+}
+
+// retryerClient records the retryer of the options of HeadObject.
+type retryerClient struct {
+	*memClient
+	retryer aws.Retryer
+}
+
+func (c *retryerClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	o := s3.Options{Retryer: retry.NewStandard()}
+	for _, fn := range optFns {
+		fn(&o)
+	}
+	c.retryer = o.Retryer
+	return c.memClient.HeadObject(ctx, in, optFns...)
+}
+
+func TestRetryReadDir(t *testing.T) {
+	newClient := func(failures int) *flakyListClient {
+		return &flakyListClient{
+			failures: failures,
+			pages: map[string]s3.ListObjectsV2Output{
+				"": {
+					Contents:              []types.Object{{Key: ptr("a")}},
+					IsTruncated:           ptr(true),
+					NextContinuationToken: ptr("page2"),
+				},
+				"page2": {
+					Contents:    []types.Object{{Key: ptr("b")}},
+					IsTruncated: ptr(false),
+				},
+			},
+		}
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		for _, opts := range [][]s3fs.Option{nil, {s3fs.WithListingConcurrency(2)}} {
+			cl := newClient(2)
+			fsys := s3fs.New(cl, "test", append(opts, s3fs.WithRetry(3, time.Millisecond))...)
+
+			des, err := fs.ReadDir(fsys, ".")
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if len(des) != 2 {
+				t.Errorf("expected 2 entries; got %d", len(des))
+			}
+
+			if expected := []string{"", "page2", "page2", "page2"}; !reflect.DeepEqual(expected, cl.tokens) {
+				t.Errorf("want %q; got %q", expected, cl.tokens)
+			}
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		for _, opts := range [][]s3fs.Option{nil, {s3fs.WithListingConcurrency(2)}} {
+			fsys := s3fs.New(newClient(1), "test", opts...)
+
+			f, err := fsys.Open(".")
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+			defer f.Close()
+
+			d := f.(fs.ReadDirFile)
+			if des, err := d.ReadDir(1); err != nil || des[0].Name() != "a" {
+				t.Fatalf("expected entry a; got %v %v", des, err)
+			}
+
+			if _, err := d.ReadDir(1); err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if des, err := d.ReadDir(1); len(des) != 1 || des[0].Name() != "b" {
+				t.Errorf("expected entry b; got %v %v", des, err)
+			}
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		fsys := s3fs.New(newClient(5), "test", s3fs.WithRetry(5, time.Hour))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := fs.ReadDir(fsys.WithContext(ctx), "."); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
+
+// flakyListClient returns pages by their continuation token, but the first
+// failures requests of the second page fail with 503.
+type flakyListClient struct {
+	s3fs.Client
+	pages    map[string]s3.ListObjectsV2Output
+	failures int
+
+	mu     sync.Mutex
+	tokens []string
+}
+
+func (c *flakyListClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token := aws.ToString(in.ContinuationToken)
+	c.tokens = append(c.tokens, token)
+
+	if token != "" && c.failures > 0 {
+		c.failures--
+		return nil, responseError(http.StatusServiceUnavailable)
+	}
+
+	out := c.pages[token]
+	return &out, nil
+}
+
+// flakyClient fails the first failures calls of HeadObject and PutObject
+// with status.
+type flakyClient struct {
+	*memClient
+	failures int
+	status   int
+
+	mu sync.Mutex
+	n  map[string]int
+}
+
+func (c *flakyClient) fail(op string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.n == nil {
+		c.n = make(map[string]int)
+	}
+	c.n[op]++
+	if c.n[op] <= c.failures {
+		return responseError(c.status)
+	}
+	return nil
+}
+
+func (c *flakyClient) attempts() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n["HeadObject"] + c.n["PutObject"]
+}
+
+func (c *flakyClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if err := c.fail("HeadObject"); err != nil {
+		return nil, err
+	}
+	return c.memClient.HeadObject(ctx, in, optFns...)
+}
+
+func (c *flakyClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := c.fail("PutObject"); err != nil {
+		// consume the body like a real request would.
+		buf := make([]byte, 2)
+		in.Body.Read(buf)
+		return nil, err
+	}
+	return c.memClient.PutObject(ctx, in, optFns...)
+}
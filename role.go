@@ -0,0 +1,113 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+type roleOptions struct {
+	cfg           *aws.Config
+	sts           stscreds.AssumeRoleAPIClient
+	externalID    string
+	serialNumber  string
+	tokenProvider func() (string, error)
+}
+
+// WithAWSConfig sets the configuration used by NewS3FSFromIAMRole to call
+// STS and to create the S3 client. By default it is loaded with
+// config.LoadDefaultConfig, so the base credentials can come from the
+// environment, the shared config files, SSO, web identity or the instance
+// role.
+func WithAWSConfig(cfg aws.Config) Option {
+	return optionFunc(func(fsys *S3FS) { fsys.role.cfg = &cfg })
+}
+
+// WithSTSClient sets the client used by NewS3FSFromIAMRole to assume
+// the role. By default it is created with sts.NewFromConfig.
+func WithSTSClient(cl stscreds.AssumeRoleAPIClient) Option {
+	return optionFunc(func(fsys *S3FS) { fsys.role.sts = cl })
+}
+
+// WithRoleExternalID sets the external id passed to AssumeRole, which is
+// required by trust policies with an sts:ExternalId condition.
+func WithRoleExternalID(id string) Option {
+	return optionFunc(func(fsys *S3FS) { fsys.role.externalID = id })
+}
+
+// WithMFAToken sets the MFA device and the token code used to assume
+// a role protected by MFA. A token code can be used only once, so
+// filesystems created with WithMFAToken stop working after the first
+// credentials expire; use WithMFATokenProvider to refresh them.
+func WithMFAToken(serialNumber, tokenCode string) Option {
+	return WithMFATokenProvider(serialNumber, func() (string, error) {
+		return tokenCode, nil
+	})
+}
+
+// WithMFATokenProvider is like WithMFAToken, but fn is called for a new
+// token code every time the role is assumed, e.g.
+// stscreds.StdinTokenProvider.
+func WithMFATokenProvider(serialNumber string, fn func() (string, error)) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.role.serialNumber = serialNumber
+		fsys.role.tokenProvider = fn
+	})
+}
+
+// NewS3FSFromIAMRole returns a new filesystem that accesses bucket with
+// temporary credentials of roleARN.
+//
+// Credentials are cached and refreshed automatically a minute before
+// they expire.
+func NewS3FSFromIAMRole(ctx context.Context, roleARN, sessionName, bucket string, opts ...Option) (*S3FS, error) {
+	fsys := New(nil, bucket, opts...)
+
+	var cfg aws.Config
+	if fsys.role.cfg != nil {
+		cfg = fsys.role.cfg.Copy()
+	} else {
+		var err error
+		if cfg, err = config.LoadDefaultConfig(ctx); err != nil {
+			return nil, fmt.Errorf("s3fs: failed to load AWS config: %w", err)
+		}
+	}
+
+	cl := fsys.role.sts
+	if cl == nil {
+		cl = sts.NewFromConfig(cfg)
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(cl, roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if fsys.role.externalID != "" {
+			o.ExternalID = &fsys.role.externalID
+		}
+		if fsys.role.serialNumber != "" {
+			o.SerialNumber = &fsys.role.serialNumber
+		}
+		o.TokenProvider = fsys.role.tokenProvider
+	})
+
+	creds := aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = time.Minute
+	})
+
+	// fail early if the role can't be assumed.
+	if _, err := creds.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("s3fs: failed to assume role %s: %w", roleARN, err)
+	}
+
+	fsys.cl = s3.NewFromConfig(cfg, func(o *s3.Options) { o.Credentials = creds })
+
+	if err := fsys.checkBucket(ctx); err != nil {
+		return nil, err
+	}
+	return fsys, nil
+}
@@ -0,0 +1,181 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestNewS3FSFromIAMRole(t *testing.T) {
+	var (
+		sc   mockSTS
+		s3cl recordingHTTPClient
+	)
+
+	fsys, err := s3fs.NewS3FSFromIAMRole(
+		context.Background(),
+		"arn:aws:iam::123456789012:role/test",
+		"session",
+		"test",
+		s3fs.WithAWSConfig(aws.Config{Region: "us-east-1", HTTPClient: &s3cl}),
+		s3fs.WithSTSClient(&sc),
+		s3fs.WithRoleExternalID("external-id"),
+	)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if _, err := fsys.Stat("file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if _, err := fsys.Stat("file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(sc.inputs) < 2 {
+		t.Fatalf("expected expired credentials to be refreshed; got %d AssumeRole calls", len(sc.inputs))
+	}
+
+	in := sc.inputs[0]
+	if aws.ToString(in.RoleArn) != "arn:aws:iam::123456789012:role/test" ||
+		aws.ToString(in.RoleSessionName) != "session" ||
+		aws.ToString(in.ExternalId) != "external-id" ||
+		in.SerialNumber != nil || in.TokenCode != nil {
+		t.Errorf("unexpected AssumeRole input: %+v", in)
+	}
+
+	last := fmt.Sprintf("Credential=AKID%d/", len(sc.inputs))
+	if auth := s3cl.auth[len(s3cl.auth)-1]; !strings.Contains(auth, last) {
+		t.Errorf("expected request to be signed with the latest credentials %s; got %s", last, auth)
+	}
+}
+
+func TestNewS3FSFromIAMRoleMFA(t *testing.T) {
+	var (
+		sc     mockSTS
+		tokens int
+	)
+
+	_, err := s3fs.NewS3FSFromIAMRole(
+		context.Background(),
+		"arn:aws:iam::123456789012:role/test",
+		"session",
+		"test",
+		s3fs.WithAWSConfig(aws.Config{Region: "us-east-1", HTTPClient: &recordingHTTPClient{}}),
+		s3fs.WithSTSClient(&sc),
+		s3fs.WithMFATokenProvider("arn:aws:iam::123456789012:mfa/user", func() (string, error) {
+			tokens++
+			return fmt.Sprintf("%06d", tokens), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(sc.inputs) != 1 || tokens != 1 {
+		t.Fatalf("expected 1 AssumeRole call and 1 token; got %d and %d", len(sc.inputs), tokens)
+	}
+
+	in := sc.inputs[0]
+	if aws.ToString(in.SerialNumber) != "arn:aws:iam::123456789012:mfa/user" || aws.ToString(in.TokenCode) != "000001" {
+		t.Errorf("unexpected AssumeRole input: %+v", in)
+	}
+}
+
+func TestNewS3FSFromIAMRoleMFAToken(t *testing.T) {
+	var sc mockSTS
+
+	_, err := s3fs.NewS3FSFromIAMRole(
+		context.Background(),
+		"arn:aws:iam::123456789012:role/test",
+		"session",
+		"test",
+		s3fs.WithAWSConfig(aws.Config{Region: "us-east-1", HTTPClient: &recordingHTTPClient{}}),
+		s3fs.WithSTSClient(&sc),
+		s3fs.WithMFAToken("arn:aws:iam::123456789012:mfa/user", "123456"),
+	)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	in := sc.inputs[0]
+	if aws.ToString(in.SerialNumber) != "arn:aws:iam::123456789012:mfa/user" || aws.ToString(in.TokenCode) != "123456" {
+		t.Errorf("unexpected AssumeRole input: %+v", in)
+	}
+}
+
+func TestNewS3FSFromIAMRoleError(t *testing.T) {
+	_, err := s3fs.NewS3FSFromIAMRole(
+		context.Background(),
+		"arn:aws:iam::123456789012:role/test",
+		"session",
+		"test",
+		s3fs.WithAWSConfig(aws.Config{Region: "us-east-1"}),
+		s3fs.WithSTSClient(&mockSTS{err: errors.New("access denied")}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected the AssumeRole error; got %v", err)
+	}
+}
+
+// mockSTS returns credentials that are already within the expiry window,
+// so every retrieval assumes the role again.
+type mockSTS struct {
+	mu     sync.Mutex
+	inputs []sts.AssumeRoleInput
+	err    error
+}
+
+func (s *mockSTS) AssumeRole(ctx context.Context, in *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	s.inputs = append(s.inputs, *in)
+	return &sts.AssumeRoleOutput{
+		Credentials: &ststypes.Credentials{
+			AccessKeyId:     ptr(fmt.Sprintf("AKID%d", len(s.inputs))),
+			SecretAccessKey: ptr("secret"),
+			SessionToken:    ptr("token"),
+			Expiration:      ptr(time.Now().Add(time.Second)),
+		},
+	}, nil
+}
+
+// recordingHTTPClient answers every S3 request with an empty object and
+// records the Authorization headers.
+type recordingHTTPClient struct {
+	mu   sync.Mutex
+	auth []string
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.auth = append(c.auth, req.Header.Get("Authorization"))
+	c.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Length": {"0"},
+			"Last-Modified":  {time.Time{}.Format(http.TimeFormat)},
+		},
+		Body:    io.NopCloser(strings.NewReader("")),
+		Request: req,
+	}, nil
+}
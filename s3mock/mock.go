@@ -0,0 +1,552 @@
+// Package s3mock provides an in-memory S3 client for testing code that uses
+// s3fs without a running S3 server.
+//
+//	m := &s3mock.Mock{}
+//	m.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String("bucket")})
+//	fsys := s3fs.New(m, "bucket")
+package s3mock
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/jszwec/s3fs/v2"
+)
+
+var _ s3fs.Client = (*Mock)(nil)
+
+const (
+	// maxListKeys is the maximum number of keys returned by ListObjectsV2.
+	maxListKeys = 1000
+
+	// maxDeleteObjects is the maximum number of keys accepted by
+	// DeleteObjects.
+	maxDeleteObjects = 1000
+)
+
+// Mock is an in-memory S3 client safe for concurrent use. It implements
+// s3fs.Client and the optional methods used by s3fs to write, delete and
+// rename files.
+//
+// Buckets have to be created with CreateBucket before they are used. The
+// zero value is ready to use.
+type Mock struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]*mockObject
+	errs    map[injectedErr]error
+	calls   map[string]int
+}
+
+type mockObject struct {
+	data         []byte
+	contentType  string
+	metadata     map[string]string
+	eTag         string
+	lastModified time.Time
+}
+
+type injectedErr struct {
+	op  string
+	key string
+}
+
+// InjectError makes every call of the operation op, e.g. "GetObject", for
+// key fail with err until it is reset by calling InjectError with a nil
+// error. An empty key matches all keys.
+//
+// Errors injected for "DeleteObjects" and a key are reported as errors of
+// that key in the response, like S3 does, while the other keys are
+// deleted.
+func (m *Mock) InjectError(op, key string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.errs == nil {
+		m.errs = make(map[injectedErr]error)
+	}
+
+	if err == nil {
+		delete(m.errs, injectedErr{op, key})
+		return
+	}
+	m.errs[injectedErr{op, key}] = err
+}
+
+// CallCount returns the number of calls of the operation op, including
+// calls that failed.
+func (m *Mock) CallCount(op string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[op]
+}
+
+// CreateBucket creates the bucket in.Bucket.
+func (m *Mock) CreateBucket(ctx context.Context, in *s3.CreateBucketInput, _ ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.begin(ctx, "CreateBucket", ""); err != nil {
+		return nil, err
+	}
+
+	bucket := aws.ToString(in.Bucket)
+	if _, ok := m.buckets[bucket]; ok {
+		return nil, responseError(http.StatusConflict, &types.BucketAlreadyOwnedByYou{
+			Message: aws.String("bucket " + bucket + " already exists"),
+		})
+	}
+
+	if m.buckets == nil {
+		m.buckets = make(map[string]map[string]*mockObject)
+	}
+	m.buckets[bucket] = make(map[string]*mockObject)
+
+	return &s3.CreateBucketOutput{Location: aws.String("/" + bucket)}, nil
+}
+
+// GetObject implements s3fs.Client. It supports the Range and IfMatch
+// parameters.
+func (m *Mock) GetObject(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, err := m.object(ctx, "GetObject", in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.IfMatch != nil && *in.IfMatch != o.eTag {
+		return nil, preconditionFailed()
+	}
+
+	out := &s3.GetObjectOutput{
+		AcceptRanges:  aws.String("bytes"),
+		ContentType:   aws.String(o.contentType),
+		ETag:          aws.String(o.eTag),
+		LastModified:  aws.Time(o.lastModified),
+		Metadata:      cloneMap(o.metadata),
+		StorageClass:  types.StorageClassStandard,
+		ContentLength: aws.Int64(int64(len(o.data))),
+	}
+
+	data := o.data
+	if in.Range != nil {
+		start, end, err := parseRange(*in.Range, int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		data = data[start : end+1]
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, len(o.data)))
+		out.ContentLength = aws.Int64(int64(len(data)))
+	}
+
+	out.Body = io.NopCloser(bytes.NewReader(data))
+	return out, nil
+}
+
+// HeadObject implements s3fs.Client. It supports the IfMatch parameter.
+func (m *Mock) HeadObject(ctx context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, err := m.object(ctx, "HeadObject", in.Bucket, in.Key)
+	if err != nil {
+		// HEAD responses have no body, so S3 cannot return NoSuchKey.
+		if e := new(types.NoSuchKey); errors.As(err, &e) {
+			return nil, responseError(http.StatusNotFound, &types.NotFound{})
+		}
+		return nil, err
+	}
+
+	if in.IfMatch != nil && *in.IfMatch != o.eTag {
+		return nil, preconditionFailed()
+	}
+
+	return &s3.HeadObjectOutput{
+		AcceptRanges:  aws.String("bytes"),
+		ContentLength: aws.Int64(int64(len(o.data))),
+		ContentType:   aws.String(o.contentType),
+		ETag:          aws.String(o.eTag),
+		LastModified:  aws.Time(o.lastModified),
+		Metadata:      cloneMap(o.metadata),
+	}, nil
+}
+
+// ListObjectsV2 implements s3fs.Client. It supports the Prefix, Delimiter,
+// MaxKeys, StartAfter and ContinuationToken parameters.
+func (m *Mock) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix, delim := aws.ToString(in.Prefix), aws.ToString(in.Delimiter)
+
+	objects, err := m.bucket(ctx, "ListObjectsV2", in.Bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(objects))
+	for k := range objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	maxKeys := int(aws.ToInt32(in.MaxKeys))
+	if maxKeys <= 0 {
+		maxKeys = maxListKeys
+	}
+
+	// The continuation token is the last key or common prefix returned.
+	// Keys under a common prefix sort before prefix+"\xff".
+	after := aws.ToString(in.StartAfter)
+	if in.ContinuationToken != nil {
+		after = *in.ContinuationToken
+	}
+
+	out := &s3.ListObjectsV2Output{
+		Name:              in.Bucket,
+		Prefix:            in.Prefix,
+		Delimiter:         in.Delimiter,
+		MaxKeys:           aws.Int32(int32(maxKeys)),
+		StartAfter:        in.StartAfter,
+		ContinuationToken: in.ContinuationToken,
+		IsTruncated:       aws.Bool(false),
+	}
+
+	var n int32
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) || k <= after {
+			continue
+		}
+
+		if int(n) == maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(after)
+			break
+		}
+
+		if i := strings.Index(k[len(prefix):], delim); delim != "" && i >= 0 {
+			p := k[:len(prefix)+i+len(delim)]
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(p)})
+			after = p + "\xff"
+			n++
+			continue
+		}
+
+		o := objects[k]
+		out.Contents = append(out.Contents, types.Object{
+			Key:          aws.String(k),
+			Size:         aws.Int64(int64(len(o.data))),
+			ETag:         aws.String(o.eTag),
+			LastModified: aws.Time(o.lastModified),
+			StorageClass: types.ObjectStorageClassStandard,
+		})
+		after = k
+		n++
+	}
+
+	out.KeyCount = aws.Int32(n)
+	return out, nil
+}
+
+// PutObject creates or replaces the object in.Key.
+func (m *Mock) PutObject(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var data []byte
+	if in.Body != nil {
+		var err error
+		if data, err = io.ReadAll(in.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(ctx, "PutObject", in.Bucket, aws.ToString(in.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := aws.ToString(in.ContentType)
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+
+	o := &mockObject{
+		data:         data,
+		contentType:  contentType,
+		metadata:     cloneMap(in.Metadata),
+		eTag:         eTag(data),
+		lastModified: now(),
+	}
+	objects[aws.ToString(in.Key)] = o
+
+	return &s3.PutObjectOutput{ETag: aws.String(o.eTag)}, nil
+}
+
+// DeleteObject deletes the object in.Key. Like S3, it does not fail if the
+// object does not exist.
+func (m *Mock) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(ctx, "DeleteObject", in.Bucket, aws.ToString(in.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(objects, aws.ToString(in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects deletes up to 1000 objects.
+func (m *Mock) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(ctx, "DeleteObjects", in.Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Delete == nil || len(in.Delete.Objects) == 0 || len(in.Delete.Objects) > maxDeleteObjects {
+		return nil, responseError(http.StatusBadRequest, &smithy.GenericAPIError{
+			Code:    "MalformedXML",
+			Message: fmt.Sprintf("the request must contain between 1 and %d keys", maxDeleteObjects),
+		})
+	}
+
+	var out s3.DeleteObjectsOutput
+	for _, id := range in.Delete.Objects {
+		key := aws.ToString(id.Key)
+
+		if err := m.errs[injectedErr{"DeleteObjects", key}]; err != nil {
+			out.Errors = append(out.Errors, types.Error{
+				Key:     id.Key,
+				Code:    aws.String("InternalError"),
+				Message: aws.String(err.Error()),
+			})
+			continue
+		}
+
+		delete(objects, key)
+		if !aws.ToBool(in.Delete.Quiet) {
+			out.Deleted = append(out.Deleted, types.DeletedObject{Key: id.Key})
+		}
+	}
+	return &out, nil
+}
+
+// CopyObject copies the object in.CopySource to in.Key. The metadata of
+// the source is kept, unless MetadataDirective is REPLACE.
+func (m *Mock) CopyObject(ctx context.Context, in *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(ctx, "CopyObject", in.Bucket, aws.ToString(in.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	srcBucket, srcKey, err := parseCopySource(aws.ToString(in.CopySource))
+	if err != nil {
+		return nil, err
+	}
+
+	src, ok := m.buckets[srcBucket][srcKey]
+	if !ok {
+		return nil, noSuchKey(srcKey)
+	}
+
+	o := &mockObject{
+		data:         bytes.Clone(src.data),
+		contentType:  src.contentType,
+		metadata:     cloneMap(src.metadata),
+		eTag:         src.eTag,
+		lastModified: now(),
+	}
+	if in.MetadataDirective == types.MetadataDirectiveReplace {
+		o.contentType = aws.ToString(in.ContentType)
+		o.metadata = cloneMap(in.Metadata)
+	}
+	objects[aws.ToString(in.Key)] = o
+
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{
+			ETag:         aws.String(o.eTag),
+			LastModified: aws.Time(o.lastModified),
+		},
+	}, nil
+}
+
+// begin counts the call of op and returns the error injected for key, if
+// any. m.mu must be held.
+func (m *Mock) begin(ctx context.Context, op, key string) error {
+	if m.calls == nil {
+		m.calls = make(map[string]int)
+	}
+	m.calls[op]++
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err, ok := m.errs[injectedErr{op, key}]; ok {
+		return err
+	}
+	if err, ok := m.errs[injectedErr{op, ""}]; ok {
+		return err
+	}
+	return nil
+}
+
+// bucket begins the call of op and returns the objects of bucket.
+func (m *Mock) bucket(ctx context.Context, op string, bucket *string, key string) (map[string]*mockObject, error) {
+	if err := m.begin(ctx, op, key); err != nil {
+		return nil, err
+	}
+
+	objects, ok := m.buckets[aws.ToString(bucket)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchBucket{
+			Message: aws.String("bucket " + aws.ToString(bucket) + " does not exist"),
+		})
+	}
+	return objects, nil
+}
+
+// object begins the call of op and returns the object key of bucket.
+func (m *Mock) object(ctx context.Context, op string, bucket, key *string) (*mockObject, error) {
+	objects, err := m.bucket(ctx, op, bucket, aws.ToString(key))
+	if err != nil {
+		return nil, err
+	}
+
+	o, ok := objects[aws.ToString(key)]
+	if !ok {
+		return nil, noSuchKey(aws.ToString(key))
+	}
+	return o, nil
+}
+
+// parseRange parses the Range header for an object of the given size and
+// returns the first and the last byte of the range.
+func parseRange(s string, size int64) (start, end int64, err error) {
+	spec, ok := strings.CutPrefix(s, "bytes=")
+	first, last, ok2 := strings.Cut(spec, "-")
+	if !ok || !ok2 || strings.Contains(spec, ",") {
+		return 0, 0, invalidRange(s)
+	}
+
+	switch {
+	case first == "":
+		// bytes=-n requests the last n bytes.
+		var n int64
+		if _, err := fmt.Sscanf(last, "%d", &n); err != nil || n <= 0 {
+			return 0, 0, invalidRange(s)
+		}
+		start, end = max(size-n, 0), size-1
+	default:
+		if _, err := fmt.Sscanf(first, "%d", &start); err != nil {
+			return 0, 0, invalidRange(s)
+		}
+
+		end = size - 1
+		if last != "" {
+			if _, err := fmt.Sscanf(last, "%d", &end); err != nil || end < start {
+				return 0, 0, invalidRange(s)
+			}
+			end = min(end, size-1)
+		}
+	}
+
+	if start >= size {
+		return 0, 0, invalidRange(s)
+	}
+	return start, end, nil
+}
+
+func parseCopySource(s string) (bucket, key string, err error) {
+	s, _, _ = strings.Cut(s, "?versionId=")
+	s, err = url.PathUnescape(strings.TrimPrefix(s, "/"))
+	if err != nil {
+		return "", "", responseError(http.StatusBadRequest, &smithy.GenericAPIError{
+			Code:    "InvalidArgument",
+			Message: "invalid copy source",
+		})
+	}
+
+	bucket, key, _ = strings.Cut(s, "/")
+	return bucket, key, nil
+}
+
+func noSuchKey(key string) error {
+	return responseError(http.StatusNotFound, &types.NoSuchKey{
+		Message: aws.String("key " + key + " does not exist"),
+	})
+}
+
+func preconditionFailed() error {
+	return responseError(http.StatusPreconditionFailed, &smithy.GenericAPIError{
+		Code:    "PreconditionFailed",
+		Message: "at least one of the preconditions you specified did not hold",
+	})
+}
+
+func invalidRange(r string) error {
+	return responseError(http.StatusRequestedRangeNotSatisfiable, &smithy.GenericAPIError{
+		Code:    "InvalidRange",
+		Message: "the requested range " + r + " is not satisfiable",
+	})
+}
+
+// responseError wraps err the same way as errors returned by the SDK.
+func responseError(status int, err error) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{
+				StatusCode: status,
+				Header:     http.Header{},
+			}},
+			Err: err,
+		},
+	}
+}
+
+func eTag(data []byte) string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(data))
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// now returns the current time truncated to seconds, which is the
+// precision of S3.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
@@ -0,0 +1,183 @@
+package s3mock_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+	"github.com/jszwec/s3fs/v2/s3mock"
+)
+
+func newMock(t *testing.T, files ...string) *s3mock.Mock {
+	t.Helper()
+
+	m := &s3mock.Mock{}
+	if _, err := m.CreateBucket(context.Background(), &s3.CreateBucketInput{Bucket: aws.String("bucket")}); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	fsys := s3fs.New(m, "bucket")
+	for _, f := range files {
+		if err := fsys.WriteFile(f, []byte("content of "+f), 0644); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+	}
+	return m
+}
+
+func TestMock(t *testing.T) {
+	files := []string{
+		"a.txt",
+		"dir/b.txt",
+		"dir/c.txt",
+		"dir/sub/d.txt",
+		"other/e.txt",
+	}
+
+	fsys := s3fs.New(newMock(t, files...), "bucket", s3fs.WithReadSeeker)
+	if err := fstest.TestFS(fsys, files...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockPagination(t *testing.T) {
+	files := make([]string, 2500)
+	for i := range files {
+		files[i] = "dir/" + string(rune('a'+i%26)) + "/" + string(rune('a'+i/26%26)) + string(rune('a'+i/676))
+	}
+
+	m := newMock(t, files...)
+	fsys := s3fs.New(m, "bucket")
+
+	des, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(des) != 26 {
+		t.Errorf("expected 26 entries; got %d", len(des))
+	}
+
+	if err := fs.WalkDir(fsys, ".", func(string, fs.DirEntry, error) error { return nil }); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	matches, err := fs.Glob(fsys, "dir/*/*")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(matches) != len(files) {
+		t.Errorf("expected %d matches; got %d", len(files), len(matches))
+	}
+}
+
+func TestMockNotExist(t *testing.T) {
+	fsys := s3fs.New(newMock(t), "bucket")
+
+	if _, err := fsys.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	if _, err := fs.ReadFile(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	if _, err := s3fs.New(newMock(t), "no-bucket").Stat("a.txt"); err == nil {
+		t.Error("expected an error for a missing bucket")
+	}
+}
+
+func TestMockInjectError(t *testing.T) {
+	m := newMock(t, "a.txt", "dir/b.txt", "dir/c.txt")
+	fsys := s3fs.New(m, "bucket")
+
+	injected := errors.New("injected")
+	m.InjectError("GetObject", "a.txt", injected)
+
+	if _, err := fs.ReadFile(fsys, "a.txt"); !errors.Is(err, injected) {
+		t.Errorf("want %v; got %v", injected, err)
+	}
+
+	if _, err := fs.ReadFile(fsys, "dir/b.txt"); err != nil {
+		t.Error("expected err to be nil; got ", err)
+	}
+
+	m.InjectError("GetObject", "a.txt", nil)
+
+	if _, err := fs.ReadFile(fsys, "a.txt"); err != nil {
+		t.Error("expected err to be nil; got ", err)
+	}
+
+	if n := m.CallCount("GetObject"); n != 3 {
+		t.Errorf("expected 3 GetObject calls; got %d", n)
+	}
+
+	m.InjectError("DeleteObjects", "dir/b.txt", injected)
+
+	if err := fsys.RemoveAll("dir"); err == nil {
+		t.Error("expected an error")
+	}
+
+	if _, err := fsys.Stat("dir/b.txt"); err != nil {
+		t.Error("expected dir/b.txt to exist; got ", err)
+	}
+
+	if _, err := fsys.Stat("dir/c.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected dir/c.txt to be deleted; got %v", err)
+	}
+}
+
+func TestMockRename(t *testing.T) {
+	m := newMock(t, "dir/a.txt", "dir/b c.txt")
+	fsys := s3fs.New(m, "bucket")
+
+	if err := fsys.Rename("dir", "new"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if err := fstest.TestFS(fsys, "new/a.txt", "new/b c.txt"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := fsys.Stat("dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestMockReadAt(t *testing.T) {
+	fsys := s3fs.New(newMock(t, "a.txt"), "bucket")
+
+	f, err := fsys.Open("a.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	defer f.Close()
+
+	p := make([]byte, 5)
+	if _, err := f.(io.ReaderAt).ReadAt(p, 11); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if string(p) != "a.txt" {
+		t.Errorf("want %q; got %q", "a.txt", p)
+	}
+}
+
+func TestMockCreateBucket(t *testing.T) {
+	m := newMock(t)
+
+	_, err := m.CreateBucket(context.Background(), &s3.CreateBucketInput{Bucket: aws.String("bucket")})
+
+	var re interface{ HTTPStatusCode() int }
+	if !errors.As(err, &re) || re.HTTPStatusCode() != http.StatusConflict {
+		t.Errorf("expected %d; got %v", http.StatusConflict, err)
+	}
+}
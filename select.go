@@ -0,0 +1,349 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Formats of SelectFormat.
+const (
+	FormatCSV     = "CSV"
+	FormatJSON    = "JSON"
+	FormatParquet = "Parquet"
+)
+
+// Compression types of SelectFormat.
+const (
+	SelectGzip  = "GZIP"
+	SelectBzip2 = "BZIP2"
+)
+
+// JSON types of SelectJSONType.
+const (
+	SelectJSONDocument = "DOCUMENT"
+	SelectJSONLines    = "LINES"
+)
+
+type selectObjectContentClient interface {
+	SelectObjectContent(ctx context.Context, params *s3.SelectObjectContentInput, optFns ...func(*s3.Options)) (*s3.SelectObjectContentOutput, error)
+}
+
+// SelectQuery is a query run by Select.
+type SelectQuery struct {
+	// Expression is the query, e.g.
+	// "SELECT s.name FROM S3Object s WHERE CAST(s.age AS INT) > 30".
+	Expression string
+
+	// ExpressionType is the language of Expression. The default is "SQL",
+	// which is the only one supported by S3.
+	ExpressionType string
+
+	// InputFormat is the format of the queried file.
+	InputFormat SelectFormat
+
+	// OutputFormat is the format of the result. Parquet is not supported.
+	OutputFormat SelectFormat
+}
+
+// SelectFormat describes the format of the input or the output of Select.
+type SelectFormat struct {
+	// Format is FormatCSV, FormatJSON or FormatParquet.
+	Format string
+
+	// FieldDelimiter separates the fields of CSV records. The default is
+	// ",".
+	FieldDelimiter string
+
+	// HasHeader reports whether the first line of a CSV file names the
+	// columns, so that they can be referenced by name in the query. It is
+	// only used for the input.
+	HasHeader bool
+
+	// QuoteCharacter quotes CSV fields that contain the delimiter. The
+	// default is '"'.
+	QuoteCharacter string
+
+	// Lines reports whether a JSON file contains an object per line
+	// instead of a single document. It is only used for the input.
+	Lines bool
+
+	// Compression is SelectGzip or SelectBzip2 if a CSV or JSON file is
+	// compressed. It is only used for the input.
+	Compression string
+}
+
+// SelectOpt configures the query run by SelectCSV, SelectJSON and
+// SelectParquet.
+type SelectOpt func(*SelectQuery)
+
+// SelectDelimiter sets the field delimiter of CSV input and output.
+func SelectDelimiter(delimiter string) SelectOpt {
+	return func(q *SelectQuery) {
+		q.InputFormat.FieldDelimiter = delimiter
+		q.OutputFormat.FieldDelimiter = delimiter
+	}
+}
+
+// SelectQuoteChar sets the quote character of CSV input and output.
+func SelectQuoteChar(quote string) SelectOpt {
+	return func(q *SelectQuery) {
+		q.InputFormat.QuoteCharacter = quote
+		q.OutputFormat.QuoteCharacter = quote
+	}
+}
+
+// SelectHeaderUse sets whether the first line of a CSV file names the
+// columns. SelectCSV uses it by default.
+func SelectHeaderUse(use bool) SelectOpt {
+	return func(q *SelectQuery) {
+		q.InputFormat.HasHeader = use
+	}
+}
+
+// SelectJSONType sets whether a JSON file is a single document
+// (SelectJSONDocument) or contains an object per line (SelectJSONLines,
+// the default of SelectJSON).
+func SelectJSONType(typ string) SelectOpt {
+	return func(q *SelectQuery) {
+		q.InputFormat.Lines = typ == SelectJSONLines
+	}
+}
+
+// SelectCompressionType sets the compression of the queried file; see
+// SelectFormat.Compression.
+func SelectCompressionType(compression string) SelectOpt {
+	return func(q *SelectQuery) {
+		q.InputFormat.Compression = compression
+	}
+}
+
+// NewCSVQuery returns a SQL query of a CSV file with a header line, whose
+// result is CSV with the same delimiter. An empty delimiter means ",".
+func NewCSVQuery(sql, delimiter string) SelectQuery {
+	format := SelectFormat{
+		Format:         FormatCSV,
+		FieldDelimiter: delimiter,
+		HasHeader:      true,
+	}
+
+	return SelectQuery{
+		Expression:     sql,
+		ExpressionType: string(types.ExpressionTypeSql),
+		InputFormat:    format,
+		OutputFormat:   format,
+	}
+}
+
+// NewJSONQuery returns a SQL query of a file with a JSON object per line,
+// whose result is JSON as well.
+func NewJSONQuery(sql string) SelectQuery {
+	return SelectQuery{
+		Expression:     sql,
+		ExpressionType: string(types.ExpressionTypeSql),
+		InputFormat:    SelectFormat{Format: FormatJSON, Lines: true},
+		OutputFormat:   SelectFormat{Format: FormatJSON},
+	}
+}
+
+// SelectCSV runs the SQL query sql against the named CSV file. The result
+// is CSV as well. See NewCSVQuery for the defaults and Select for details.
+func (f *S3FS) SelectCSV(ctx context.Context, name, sql string, opts ...SelectOpt) (io.ReadCloser, error) {
+	return f.Select(ctx, name, NewCSVQuery(sql, "").with(opts))
+}
+
+// SelectJSON runs the SQL query sql against the named JSON file. The
+// result is JSON as well. See NewJSONQuery for the defaults and Select
+// for details.
+func (f *S3FS) SelectJSON(ctx context.Context, name, sql string, opts ...SelectOpt) (io.ReadCloser, error) {
+	return f.Select(ctx, name, NewJSONQuery(sql).with(opts))
+}
+
+// SelectParquet runs the SQL query sql against the named Parquet file.
+// The result is JSON. See Select for details.
+func (f *S3FS) SelectParquet(ctx context.Context, name, sql string, opts ...SelectOpt) (io.ReadCloser, error) {
+	q := SelectQuery{
+		Expression:     sql,
+		ExpressionType: string(types.ExpressionTypeSql),
+		InputFormat:    SelectFormat{Format: FormatParquet},
+		OutputFormat:   SelectFormat{Format: FormatJSON},
+	}
+	return f.Select(ctx, name, q.with(opts))
+}
+
+func (q SelectQuery) with(opts []SelectOpt) SelectQuery {
+	for _, opt := range opts {
+		opt(&q)
+	}
+	return q
+}
+
+// Select runs q against the named file with S3 Select and returns the
+// result as it is streamed from S3, so that only the matching records are
+// transferred. The returned reader must be closed.
+//
+// If the stream ends before S3 reports the end of the result, Read fails
+// with io.ErrUnexpectedEOF.
+func (f *S3FS) Select(ctx context.Context, name string, q SelectQuery) (io.ReadCloser, error) {
+	r, err := f.selectObject(ctx, name, q)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "select",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return r, nil
+}
+
+func (f *S3FS) selectObject(ctx context.Context, name string, q SelectQuery) (io.ReadCloser, error) {
+	if !f.validPath(name) || name == "." {
+		return nil, fs.ErrInvalid
+	}
+
+	cl, ok := f.cl.(selectObjectContentClient)
+	if !ok {
+		return nil, errUnsupported("SelectObjectContent")
+	}
+
+	exprType := types.ExpressionType(q.ExpressionType)
+	if exprType == "" {
+		exprType = types.ExpressionTypeSql
+	}
+
+	// The event stream is read after the call returns, so its timeout
+	// lasts until the reader is closed.
+	ctx, cancel := f.withStreamTimeout(ctx, "SelectObjectContent")
+
+	out, err := call(f, ctx, cl.SelectObjectContent, &s3.SelectObjectContentInput{
+		Bucket:              &f.bucket,
+		Key:                 ptr(f.key(name)),
+		Expression:          &q.Expression,
+		ExpressionType:      exprType,
+		InputSerialization:  q.InputFormat.input(),
+		OutputSerialization: q.OutputFormat.output(),
+	})
+	if err != nil {
+		cancel()
+		if f.isNotFoundErr(err) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, err
+	}
+
+	return &selectReader{stream: out.GetStream(), cancel: cancel}, nil
+}
+
+func (s SelectFormat) input() *types.InputSerialization {
+	var in types.InputSerialization
+	switch s.Format {
+	case FormatCSV:
+		in.CSV = &types.CSVInput{
+			FieldDelimiter: s.fieldDelimiter(),
+			QuoteCharacter: s.quoteCharacter(),
+		}
+		if s.HasHeader {
+			in.CSV.FileHeaderInfo = types.FileHeaderInfoUse
+		}
+	case FormatJSON:
+		in.JSON = &types.JSONInput{Type: types.JSONTypeDocument}
+		if s.Lines {
+			in.JSON.Type = types.JSONTypeLines
+		}
+	case FormatParquet:
+		in.Parquet = &types.ParquetInput{}
+	}
+
+	if s.Compression != "" {
+		in.CompressionType = types.CompressionType(s.Compression)
+	}
+	return &in
+}
+
+func (s SelectFormat) output() *types.OutputSerialization {
+	var out types.OutputSerialization
+	switch s.Format {
+	case FormatCSV:
+		out.CSV = &types.CSVOutput{
+			FieldDelimiter: s.fieldDelimiter(),
+			QuoteCharacter: s.quoteCharacter(),
+		}
+	case FormatJSON:
+		out.JSON = &types.JSONOutput{}
+	}
+	return &out
+}
+
+func (s SelectFormat) fieldDelimiter() *string {
+	if s.FieldDelimiter == "" {
+		return nil
+	}
+	return &s.FieldDelimiter
+}
+
+func (s SelectFormat) quoteCharacter() *string {
+	if s.QuoteCharacter == "" {
+		return nil
+	}
+	return &s.QuoteCharacter
+}
+
+// selectStream is implemented by *s3.SelectObjectContentEventStream.
+type selectStream interface {
+	Events() <-chan types.SelectObjectContentEventStream
+	Close() error
+	Err() error
+}
+
+// selectReader reads the records of a Select result.
+type selectReader struct {
+	stream selectStream
+	cancel context.CancelFunc
+	buf    []byte
+	end    bool
+	err    error
+}
+
+func (r *selectReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.next()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next receives the next event of the stream.
+func (r *selectReader) next() {
+	ev, ok := <-r.stream.Events()
+	if !ok {
+		switch err := r.stream.Err(); {
+		case err != nil:
+			r.err = err
+		case !r.end:
+			r.err = io.ErrUnexpectedEOF
+		default:
+			r.err = io.EOF
+		}
+		return
+	}
+
+	switch ev := ev.(type) {
+	case *types.SelectObjectContentEventStreamMemberRecords:
+		r.buf = ev.Value.Payload
+	case *types.SelectObjectContentEventStreamMemberEnd:
+		r.end = true
+	}
+}
+
+func (r *selectReader) Close() error {
+	defer r.cancel()
+	return r.stream.Close()
+}
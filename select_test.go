@@ -0,0 +1,238 @@
+package s3fs_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestSelect(t *testing.T) {
+	type selectRequest struct {
+		Expression string
+		CSV        struct {
+			FileHeaderInfo string
+			FieldDelimiter string
+			QuoteCharacter string
+		} `xml:"InputSerialization>CSV"`
+		JSONType        string    `xml:"InputSerialization>JSON>Type"`
+		Parquet         *struct{} `xml:"InputSerialization>Parquet"`
+		CompressionType string    `xml:"InputSerialization>CompressionType"`
+		OutputJSON      *struct{} `xml:"OutputSerialization>JSON"`
+	}
+	var req selectRequest
+
+	records := []string{"a;1\n", "b;2\n"}
+	end := true
+
+	// delay is waited before every event is sent.
+	var delay time.Duration
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/test/data.csv" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+
+		req = selectRequest{}
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		for _, rec := range records {
+			if delay > 0 {
+				w.(http.Flusher).Flush()
+				time.Sleep(delay)
+			}
+			writeEvent(t, w, "Records", []byte(rec))
+		}
+		if end {
+			writeEvent(t, w, "End", nil)
+		}
+	}))
+	defer srv.Close()
+
+	cl := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: &srv.URL,
+		UsePathStyle: true,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "key", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("csv", func(t *testing.T) {
+		q := s3fs.NewCSVQuery("SELECT s.name, s.n FROM S3Object s", ";")
+
+		r, err := fsys.Select(context.Background(), "data.csv", q)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if want := strings.Join(records, ""); string(data) != want {
+			t.Errorf("want %q; got %q", want, data)
+		}
+
+		if req.Expression != q.Expression {
+			t.Errorf("want expression %q; got %q", q.Expression, req.Expression)
+		}
+
+		if req.CSV.FileHeaderInfo != "USE" || req.CSV.FieldDelimiter != ";" {
+			t.Errorf("unexpected CSV input: %+v", req.CSV)
+		}
+	})
+
+	t.Run("csv options", func(t *testing.T) {
+		r, err := fsys.SelectCSV(context.Background(), "data.csv", "SELECT * FROM S3Object",
+			s3fs.SelectDelimiter(";"),
+			s3fs.SelectQuoteChar("'"),
+			s3fs.SelectHeaderUse(false),
+			s3fs.SelectCompressionType(s3fs.SelectGzip),
+		)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer r.Close()
+
+		if _, err := io.ReadAll(r); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if req.CSV.FileHeaderInfo != "" || req.CSV.FieldDelimiter != ";" || req.CSV.QuoteCharacter != "'" {
+			t.Errorf("unexpected CSV input: %+v", req.CSV)
+		}
+
+		if req.CompressionType != "GZIP" {
+			t.Errorf("want compression GZIP; got %q", req.CompressionType)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		fixtures := []struct {
+			opts []s3fs.SelectOpt
+			typ  string
+		}{
+			{typ: "LINES"},
+			{opts: []s3fs.SelectOpt{s3fs.SelectJSONType(s3fs.SelectJSONDocument)}, typ: "DOCUMENT"},
+		}
+
+		for _, f := range fixtures {
+			r, err := fsys.SelectJSON(context.Background(), "data.csv", "SELECT * FROM S3Object", f.opts...)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if _, err := io.ReadAll(r); err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+			r.Close()
+
+			if req.JSONType != f.typ || req.OutputJSON == nil {
+				t.Errorf("want JSON type %q and JSON output; got %+v", f.typ, req)
+			}
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		r, err := fsys.SelectParquet(context.Background(), "data.csv", "SELECT * FROM S3Object")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer r.Close()
+
+		if _, err := io.ReadAll(r); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if req.Parquet == nil || req.OutputJSON == nil {
+			t.Errorf("expected Parquet input and JSON output; got %+v", req)
+		}
+	})
+
+	t.Run("no end", func(t *testing.T) {
+		end = false
+		defer func() { end = true }()
+
+		r, err := fsys.Select(context.Background(), "data.csv", s3fs.NewJSONQuery("SELECT * FROM S3Object"))
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer r.Close()
+
+		if _, err := io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("want %v; got %v", io.ErrUnexpectedEOF, err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		delay = 10 * time.Millisecond
+		defer func() { delay = 0 }()
+
+		fsys := s3fs.New(cl, "test", s3fs.WithOperationTimeouts(map[string]time.Duration{
+			"SelectObjectContent": time.Minute,
+		}))
+
+		r, err := fsys.Select(context.Background(), "data.csv", s3fs.NewCSVQuery("SELECT * FROM S3Object", ";"))
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if want := strings.Join(records, ""); string(data) != want {
+			t.Errorf("want %q; got %q", want, data)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		_, err := fsys.Select(context.Background(), "not-exist.csv", s3fs.NewCSVQuery("SELECT * FROM S3Object", ""))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient("data.csv"), "test")
+		_, err := fsys.Select(context.Background(), "data.csv", s3fs.NewCSVQuery("SELECT * FROM S3Object", ""))
+		if !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("want %v; got %v", errors.ErrUnsupported, err)
+		}
+	})
+}
+
+func writeEvent(t *testing.T, w io.Writer, eventType string, payload []byte) {
+	t.Helper()
+
+	var msg eventstream.Message
+	msg.Headers.Set(":message-type", eventstream.StringValue("event"))
+	msg.Headers.Set(":event-type", eventstream.StringValue(eventType))
+	msg.Payload = payload
+
+	if err := eventstream.NewEncoder().Encode(w, msg); err != nil {
+		t.Fatal(err)
+	}
+}
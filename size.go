@@ -0,0 +1,57 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// DirSize returns the total size in bytes of all files under the named
+// directory, including the files in its subdirectories, like du -sb.
+//
+// It lists all objects under the directory without a delimiter, which
+// takes one ListObjectsV2 call per 1000 objects.
+func (f *S3FS) DirSize(ctx context.Context, name string) (int64, error) {
+	size, err := f.dirSize(ctx, name)
+	if err != nil {
+		return 0, &fs.PathError{
+			Op:   "dirsize",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return size, nil
+}
+
+func (f *S3FS) dirSize(ctx context.Context, name string) (int64, error) {
+	if !f.validPath(name) {
+		return 0, fs.ErrInvalid
+	}
+
+	prefix := ""
+	if name != "." {
+		prefix = name + f.delim
+	}
+
+	var size, n int64
+	err := f.listAll(ctx, prefix, func(_ string, o types.Object) error {
+		size += derefInt64(o.Size)
+		n++
+		return nil
+	})
+	switch {
+	case err == nil:
+	case f.isNotFoundErr(err):
+		return 0, fs.ErrNotExist
+	case f.isPermissionErr(err):
+		return 0, fs.ErrPermission
+	default:
+		return 0, err
+	}
+
+	if n == 0 && name != "." {
+		return 0, fs.ErrNotExist
+	}
+	return size, nil
+}
@@ -0,0 +1,41 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestDirSize(t *testing.T) {
+	// every file of memClient contains "content".
+	cl := newMemClient("a.txt", "dir/b.txt", "dir/sub/c.txt", "dir2/d.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithPageSize(1))
+
+	fixtures := []struct {
+		desc string
+		name string
+		size int64
+		err  error
+	}{
+		{desc: "root", name: ".", size: 4 * 7},
+		{desc: "recursive", name: "dir", size: 2 * 7},
+		{desc: "not exist", name: "missing", err: fs.ErrNotExist},
+		{desc: "invalid", name: "/dir", err: fs.ErrInvalid},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.desc, func(t *testing.T) {
+			size, err := fsys.DirSize(context.Background(), f.name)
+			if !errors.Is(err, f.err) {
+				t.Fatalf("expected err to be %v; got %v", f.err, err)
+			}
+
+			if size != f.size {
+				t.Errorf("expected size %d; got %d", f.size, size)
+			}
+		})
+	}
+}
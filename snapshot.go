@@ -0,0 +1,207 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"testing/fstest"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	_ fs.ReadDirFS  = (*snapshotFS)(nil)
+	_ fs.ReadFileFS = (*snapshotFS)(nil)
+	_ fs.StatFS     = (*snapshotFS)(nil)
+)
+
+// Snapshot downloads all files under the directory prefix and returns them
+// as an in-memory filesystem rooted at prefix, which is not affected by
+// later changes to the bucket. It is meant for tools that need a stable
+// view of a tree of assets while they run.
+//
+// Files larger than maxSize are not downloaded. They exist in the snapshot
+// with empty content, but their FileInfo still reports the size of the
+// object. Files deleted after they were listed are left out. Names in the
+// snapshot always use "/" as the separator, even with WithDelimiter.
+func (f *S3FS) Snapshot(ctx context.Context, prefix string, maxSize int64) (fs.FS, error) {
+	snap, err := f.snapshot(ctx, prefix, maxSize)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "snapshot",
+			Path: prefix,
+			Err:  err,
+		}
+	}
+	return snap, nil
+}
+
+func (f *S3FS) snapshot(ctx context.Context, prefix string, maxSize int64) (*snapshotFS, error) {
+	if !f.validPath(prefix) {
+		return nil, fs.ErrInvalid
+	}
+
+	dir := ""
+	if prefix != "." {
+		dir = prefix + f.delim
+	}
+
+	fsys := f.WithContext(ctx)
+	snap := &snapshotFS{
+		files: make(fstest.MapFS),
+		sizes: make(map[string]int64),
+	}
+
+	var n int
+	err := f.listAll(ctx, dir, func(name string, o types.Object) error {
+		n++
+		rel := strings.ReplaceAll(strings.TrimPrefix(name, dir), f.delim, "/")
+		if !fs.ValidPath(rel) || rel == "." {
+			// a directory marker, or a key that is not a valid name.
+			return nil
+		}
+
+		file := &fstest.MapFile{
+			ModTime: derefTime(o.LastModified),
+			Sys:     metaFromObject(o),
+		}
+
+		if size := derefInt64(o.Size); size > maxSize {
+			snap.sizes[rel] = size
+		} else {
+			data, err := fsys.ReadFile(name)
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				return nil
+			case err != nil:
+				return err
+			}
+			file.Data = data
+		}
+
+		snap.files[rel] = file
+		return nil
+	})
+	switch {
+	case err == nil:
+	case f.isNotFoundErr(err):
+		return nil, fs.ErrNotExist
+	case f.isPermissionErr(err):
+		return nil, fs.ErrPermission
+	default:
+		return nil, err
+	}
+
+	if n == 0 && prefix != "." {
+		return nil, fs.ErrNotExist
+	}
+	return snap, nil
+}
+
+// snapshotFS is the filesystem returned by Snapshot. It is a fstest.MapFS
+// that reports the size of the files that were not downloaded.
+type snapshotFS struct {
+	files fstest.MapFS
+	sizes map[string]int64
+}
+
+func (s *snapshotFS) Open(name string) (fs.File, error) {
+	f, err := s.files.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if d, ok := f.(fs.ReadDirFile); ok {
+		return &snapshotDir{ReadDirFile: d, fsys: s, name: name}, nil
+	}
+
+	if size, ok := s.sizes[name]; ok {
+		return &snapshotFile{File: f, size: size}, nil
+	}
+	return f, nil
+}
+
+func (s *snapshotFS) ReadFile(name string) ([]byte, error) {
+	return s.files.ReadFile(name)
+}
+
+func (s *snapshotFS) Stat(name string) (fs.FileInfo, error) {
+	fi, err := s.files.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.info(name, fi), nil
+}
+
+func (s *snapshotFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	des, err := s.files.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.entries(name, des), nil
+}
+
+func (s *snapshotFS) info(name string, fi fs.FileInfo) fs.FileInfo {
+	if size, ok := s.sizes[name]; ok {
+		return sizedInfo{FileInfo: fi, size: size}
+	}
+	return fi
+}
+
+// entries replaces the entries of the directory name for files that were
+// not downloaded.
+func (s *snapshotFS) entries(name string, des []fs.DirEntry) []fs.DirEntry {
+	for i, de := range des {
+		if size, ok := s.sizes[path.Join(name, de.Name())]; ok {
+			des[i] = sizedEntry{DirEntry: de, size: size}
+		}
+	}
+	return des
+}
+
+type snapshotDir struct {
+	fs.ReadDirFile
+	fsys *snapshotFS
+	name string
+}
+
+func (d *snapshotDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	des, err := d.ReadDirFile.ReadDir(n)
+	return d.fsys.entries(d.name, des), err
+}
+
+type snapshotFile struct {
+	fs.File
+	size int64
+}
+
+func (f *snapshotFile) Stat() (fs.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return sizedInfo{FileInfo: fi, size: f.size}, nil
+}
+
+// sizedInfo reports size instead of the size of the wrapped FileInfo.
+type sizedInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (fi sizedInfo) Size() int64 { return fi.size }
+
+type sizedEntry struct {
+	fs.DirEntry
+	size int64
+}
+
+func (de sizedEntry) Info() (fs.FileInfo, error) {
+	fi, err := de.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return sizedInfo{FileInfo: fi, size: de.size}, nil
+}
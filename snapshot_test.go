@@ -0,0 +1,64 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestSnapshot(t *testing.T) {
+	cl := newMemClient("dir/a.txt", "dir/sub/b.txt", "other.txt")
+	cl.objects["dir/big.bin"] = strings.Repeat("x", 100)
+
+	fsys := s3fs.New(cl, "test")
+
+	snap, err := fsys.Snapshot(context.Background(), "dir", 10)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if err := fstest.TestFS(snap, "a.txt", "sub/b.txt", "big.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the snapshot does not change with the bucket.
+	cl.objects["dir/a.txt"] = "changed"
+	cl.objects["dir/new.txt"] = "new"
+
+	data, err := fs.ReadFile(snap, "a.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	if _, err := fs.Stat(snap, "new.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	data, err = fs.ReadFile(snap, "big.bin")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("expected no content for a file larger than maxSize; got %d bytes", len(data))
+	}
+
+	fi, err := fs.Stat(snap, "big.bin")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+	if fi.Size() != 100 {
+		t.Errorf("want size 100; got %d", fi.Size())
+	}
+
+	if _, err := fsys.Snapshot(context.Background(), "missing", 10); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
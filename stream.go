@@ -0,0 +1,110 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// StreamDir lists the named directory like ReadDir, but sends the entries
+// to the returned channel as every ListObjectsV2 page arrives, so at most
+// one page of entries is kept in memory.
+//
+// Entries are sorted within every page, but not across pages: S3 lists
+// "a.txt" before the directory "a/", so a directory can be sent after
+// a file that sorts after it by name.
+//
+// The error channel receives at most one error. Both channels are closed
+// once all entries were sent or the listing failed. If the caller stops
+// receiving before that, it must cancel ctx to stop the listing; the
+// error channel then receives ctx.Err().
+func (f *S3FS) StreamDir(ctx context.Context, name string) (<-chan fs.DirEntry, <-chan error) {
+	var (
+		entries = make(chan fs.DirEntry)
+		errc    = make(chan error, 1)
+	)
+
+	go func() {
+		defer close(errc)
+		defer close(entries)
+
+		if err := f.WithContext(ctx).streamDir(ctx, name, entries); err != nil {
+			errc <- err
+		}
+	}()
+
+	return entries, errc
+}
+
+func (f *S3FS) streamDir(ctx context.Context, name string, entries chan<- fs.DirEntry) error {
+	d, err := f.openDir(name)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+	defer d.Close()
+
+	for {
+		des, err := d.(*dir).nextPage()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		for _, de := range des {
+			select {
+			case entries <- de:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// ReadDirFiltered is like ReadDir, but only returns the entries for which
+// include returns true. include is called as every ListObjectsV2 page
+// arrives, so entries that are not included are never buffered. The
+// entries passed to include are the ones ReadDir would return; their Info
+// is available without additional S3 calls.
+func (f *S3FS) ReadDirFiltered(name string, include func(fs.DirEntry) bool) ([]fs.DirEntry, error) {
+	d, err := f.openDir(name)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+	defer d.Close()
+
+	des := []fs.DirEntry{}
+	for {
+		page, err := d.(*dir).nextPage()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		for _, de := range page {
+			if include(de) {
+				des = append(des, de)
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	sort.Slice(des, func(i, j int) bool {
+		return des[i].Name() < des[j].Name()
+	})
+	return des, nil
+}
@@ -0,0 +1,113 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestStreamDir(t *testing.T) {
+	var files []string
+	for i := 0; i < 10; i++ {
+		files = append(files, fmt.Sprintf("dir/%02d.txt", i), fmt.Sprintf("dir/%02d/file.txt", i))
+	}
+
+	cl := newMemClient(files...)
+	fsys := s3fs.New(cl, "test", s3fs.WithPageSize(3))
+
+	t.Run("all entries", func(t *testing.T) {
+		want, err := fs.ReadDir(fsys, "dir")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		var got []fs.DirEntry
+		entries, errc := fsys.StreamDir(context.Background(), "dir")
+		for de := range entries {
+			got = append(got, de)
+		}
+
+		if err := <-errc; err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		sort.Slice(got, func(i, j int) bool { return got[i].Name() < got[j].Name() })
+
+		if len(got) != 20 || !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v; got %v", want, got)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		entries, errc := fsys.StreamDir(context.Background(), "missing")
+		for de := range entries {
+			t.Errorf("unexpected entry %s", de.Name())
+		}
+
+		err := <-errc
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected ErrNotExist; got %v", err)
+		}
+
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Op != "readdir" || pathErr.Path != "missing" {
+			t.Errorf("expected readdir PathError; got %#v", err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		entries, errc := fsys.StreamDir(ctx, "dir")
+		if de := <-entries; de == nil || de.Name() != "00" {
+			t.Fatalf("expected the first entry to be 00; got %v", de)
+		}
+		cancel()
+
+		for range entries {
+		}
+
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled; got %v", err)
+		}
+	})
+}
+
+func TestReadDirFiltered(t *testing.T) {
+	cl := newMemClient("dir/a.txt", "dir/b.csv", "dir/c.txt", "dir/d.tmp", "dir/sub/e.txt", "dir/z.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithPageSize(2))
+
+	all, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	var want []fs.DirEntry
+	for _, de := range all {
+		if de.IsDir() || path.Ext(de.Name()) == ".txt" {
+			want = append(want, de)
+		}
+	}
+
+	got, err := fsys.ReadDirFiltered("dir", func(de fs.DirEntry) bool {
+		return de.IsDir() || path.Ext(de.Name()) == ".txt"
+	})
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(got) != 4 || !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	if _, err := fsys.ReadDirFiltered("missing", func(fs.DirEntry) bool { return true }); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist; got %v", err)
+	}
+}
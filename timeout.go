@@ -0,0 +1,75 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"maps"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithOperationTimeouts limits the duration of every attempt of an S3 call
+// by the name of its operation, e.g. "HeadObject", "GetObject",
+// "ListObjectsV2" or "PutObject". Operations without a timeout are only
+// limited by the context of the fs. A timeout never extends a deadline
+// that is already set on the context.
+//
+// The timeouts of GetObject and SelectObjectContent include reading the
+// body of the file or the result of the query, which is stopped once they
+// expire.
+func WithOperationTimeouts(timeouts map[string]time.Duration) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.timeouts = maps.Clone(timeouts)
+	})
+}
+
+// withTimeout returns ctx limited by the timeout of op. The returned
+// cancel func is nil if op has no timeout.
+func (f *S3FS) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	if ctx.Value(streamTimeoutKey{}) != nil {
+		return ctx, nil
+	}
+
+	d, ok := f.timeouts[op]
+	if !ok || d <= 0 {
+		return ctx, nil
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// streamTimeoutKey marks a context that is already limited by the timeout
+// of its operation; see withStreamTimeout.
+type streamTimeoutKey struct{}
+
+// withStreamTimeout is like withTimeout, but it is used by the callers of
+// operations whose results are streamed after the call returns, and which
+// cannot be wrapped by releaseOnClose. The returned cancel func must be
+// called once the stream is closed.
+func (f *S3FS) withStreamTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	ctx, cancel := f.withTimeout(ctx, op)
+	if cancel == nil {
+		cancel = func() {}
+	}
+	return context.WithValue(ctx, streamTimeoutKey{}, true), cancel
+}
+
+// releaseOnClose calls cancel once the body of out, if it has one, is
+// closed, or immediately otherwise.
+func releaseOnClose(out any, cancel context.CancelFunc) {
+	if out, ok := out.(*s3.GetObjectOutput); ok && out != nil && out.Body != nil {
+		out.Body = &cancelCloser{ReadCloser: out.Body, cancel: cancel}
+		return
+	}
+	cancel()
+}
+
+type cancelCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
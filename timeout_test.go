@@ -0,0 +1,79 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWithOperationTimeouts(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		cl := &blockingClient{memClient: newMemClient("file.txt")}
+		fsys := s3fs.New(cl, "test", s3fs.WithOperationTimeouts(map[string]time.Duration{
+			"HeadObject": 10 * time.Millisecond,
+		}))
+
+		if _, err := fsys.Stat("file.txt"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("parent deadline", func(t *testing.T) {
+		cl := &blockingClient{memClient: newMemClient("file.txt")}
+		fsys := s3fs.New(cl, "test", s3fs.WithOperationTimeouts(map[string]time.Duration{
+			"HeadObject": time.Hour,
+		}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := fsys.WithContext(ctx).Stat("file.txt"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("body", func(t *testing.T) {
+		cl := &blockingClient{memClient: newMemClient("file.txt")}
+		fsys := s3fs.New(cl, "test", s3fs.WithOperationTimeouts(map[string]time.Duration{
+			"GetObject": time.Hour,
+		}))
+
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := cl.getCtx.Err(); err != nil {
+			t.Errorf("expected the context to be alive while reading; got %v", err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if err := cl.getCtx.Err(); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the context to be released on Close; got %v", err)
+		}
+	})
+}
+
+// blockingClient blocks HeadObject calls until their context is done, and
+// records the context of the last GetObject call.
+type blockingClient struct {
+	*memClient
+	getCtx context.Context
+}
+
+func (c *blockingClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *blockingClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.getCtx = ctx
+	return c.memClient.GetObject(ctx, in, optFns...)
+}
@@ -0,0 +1,50 @@
+package s3fs
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracer traces every S3 call with t. The spans are named after the
+// operation, e.g. "s3fs.GetObject", and are children of the span in the
+// context set with WithContext. Failed calls are recorded as errors,
+// including calls for files that do not exist.
+func WithTracer(t trace.Tracer) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.tracer = t
+	})
+}
+
+func (f *S3FS) startSpan(ctx context.Context, op, bucket, key string) (context.Context, trace.Span) {
+	if f.tracer == nil {
+		return ctx, nil
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("aws.operation", op),
+		attribute.String("aws.s3.bucket", bucket),
+	}
+	if key != "" {
+		attrs = append(attrs, attribute.String("aws.s3.key", key))
+	}
+
+	return f.tracer.Start(ctx, "s3fs."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
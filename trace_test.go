@@ -0,0 +1,117 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+	"testing"
+
+	"github.com/jszwec/s3fs/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestWithTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	fsys := s3fs.New(newMemClient("dir/file.txt"), "test", s3fs.WithTracer(tracer))
+
+	parent, parentSpan := tracer.Start(context.Background(), "parent")
+
+	if _, err := fs.ReadFile(fsys.WithContext(parent), "dir/file.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if _, err := fsys.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	spans := tracer.ended()
+	if len(spans) < 2 {
+		t.Fatalf("expected at least 2 spans; got %d", len(spans))
+	}
+
+	get := spans[0]
+	if get.name != "s3fs.GetObject" {
+		t.Errorf("want s3fs.GetObject; got %s", get.name)
+	}
+
+	if get.parent != parentSpan {
+		t.Error("expected the span to be a child of the context span")
+	}
+
+	want := map[attribute.Key]string{
+		"aws.operation": "GetObject",
+		"aws.s3.bucket": "test",
+		"aws.s3.key":    "dir/file.txt",
+	}
+	for k, v := range want {
+		if got := get.attrs[k]; got != v {
+			t.Errorf("%s: want %q; got %q", k, v, got)
+		}
+	}
+
+	if get.status != codes.Unset || get.err != nil {
+		t.Errorf("expected no error; got %v %v", get.status, get.err)
+	}
+
+	head := spans[1]
+	if head.name != "s3fs.HeadObject" {
+		t.Errorf("want s3fs.HeadObject; got %s", head.name)
+	}
+
+	if head.status != codes.Error || head.err == nil {
+		t.Errorf("expected the error to be recorded; got %v %v", head.status, head.err)
+	}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+
+	s := &recordingSpan{
+		tracer: t,
+		name:   name,
+		parent: trace.SpanFromContext(ctx),
+		attrs:  make(map[attribute.Key]string),
+	}
+	for _, kv := range cfg.Attributes() {
+		s.attrs[kv.Key] = kv.Value.Emit()
+	}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+func (t *recordingTracer) ended() []*recordingSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*recordingSpan{}, t.spans...)
+}
+
+type recordingSpan struct {
+	noop.Span
+
+	tracer *recordingTracer
+	name   string
+	parent trace.Span
+	attrs  map[attribute.Key]string
+	status codes.Code
+	err    error
+}
+
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) { s.err = err }
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordingSpan) End(...trace.SpanEndOption) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
@@ -0,0 +1,169 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// defaultTrashPrefix is the directory of soft-deleted files when
+// WithTrashPrefix is not used.
+const defaultTrashPrefix = ".trash/"
+
+// trashTimeFormat is the format of the deletion time in trash paths. It
+// has a fixed width and no ':', so that it can be parsed regardless of the
+// delimiter.
+const trashTimeFormat = "20060102T150405.000000000Z"
+
+// WithTrashPrefix sets the directory SoftDelete moves files to. The
+// default is ".trash/". It is relative to the prefix of the fs.
+func WithTrashPrefix(p string) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.trashPrefix = p
+	})
+}
+
+// TrashedObject is a file deleted with SoftDelete.
+type TrashedObject struct {
+	// Path is the name of the file in the trash, which can be passed to
+	// RestoreFromTrash.
+	Path string
+
+	// OriginalPath is the name of the file before it was deleted.
+	OriginalPath string
+
+	// DeletedAt is the time SoftDelete was called.
+	DeletedAt time.Time
+
+	// Size is the size of the file in bytes.
+	Size int64
+}
+
+// SoftDelete moves the named file or directory to the trash directory
+// set with WithTrashPrefix, instead of deleting it permanently, so that it
+// can be restored with RestoreFromTrash. The files are moved to
+// trash/<time>/<name>, where time is the current UTC time in the
+// "20060102T150405.000000000Z" format.
+//
+// Like Rename, SoftDelete copies and deletes every object, so it is not
+// atomic.
+func (f *S3FS) SoftDelete(ctx context.Context, name string) error {
+	if err := f.softDelete(ctx, name); err != nil {
+		return &fs.PathError{
+			Op:   "softdelete",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+func (f *S3FS) softDelete(ctx context.Context, name string) error {
+	if !f.validPath(name) || name == "." {
+		return fs.ErrInvalid
+	}
+
+	trash := f.trashDir()
+	if name == trash || strings.HasPrefix(name, trash+f.delim) {
+		return fs.ErrInvalid
+	}
+
+	_, err := f.rename(ctx, name, trash+f.delim+time.Now().UTC().Format(trashTimeFormat)+f.delim+name)
+	return err
+}
+
+// ListTrash lists all files deleted with SoftDelete that were not
+// restored.
+func (f *S3FS) ListTrash(ctx context.Context) ([]TrashedObject, error) {
+	trash := f.trashDir()
+
+	var objects []TrashedObject
+	err := f.listAll(ctx, trash+f.delim, func(name string, o types.Object) error {
+		deletedAt, orig, ok := f.parseTrashPath(name)
+		if !ok {
+			return nil
+		}
+
+		objects = append(objects, TrashedObject{
+			Path:         name,
+			OriginalPath: orig,
+			DeletedAt:    deletedAt,
+			Size:         derefInt64(o.Size),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "listtrash",
+			Path: trash,
+			Err:  err,
+		}
+	}
+	return objects, nil
+}
+
+// RestoreFromTrash moves the file or directory trashedPath back to its
+// original name, overwriting the files that exist under it. trashedPath
+// is either TrashedObject.Path, or trash/<time>/<name> of a directory
+// deleted with SoftDelete.
+func (f *S3FS) RestoreFromTrash(ctx context.Context, trashedPath string) error {
+	if err := f.restoreFromTrash(ctx, trashedPath); err != nil {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: trashedPath,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+func (f *S3FS) restoreFromTrash(ctx context.Context, trashedPath string) error {
+	if !f.validPath(trashedPath) {
+		return fs.ErrInvalid
+	}
+
+	_, orig, ok := f.parseTrashPath(trashedPath)
+	if !ok {
+		return fs.ErrInvalid
+	}
+
+	_, err := f.rename(ctx, trashedPath, orig)
+	return err
+}
+
+// parseTrashPath returns the deletion time and the original name of the
+// file name in the trash.
+func (f *S3FS) parseTrashPath(name string) (time.Time, string, bool) {
+	rest, ok := strings.CutPrefix(name, f.trashDir()+f.delim)
+	if !ok {
+		return time.Time{}, "", false
+	}
+
+	if len(rest) < len(trashTimeFormat) {
+		return time.Time{}, "", false
+	}
+
+	ts, rest := rest[:len(trashTimeFormat)], rest[len(trashTimeFormat):]
+	orig, ok := strings.CutPrefix(rest, f.delim)
+	if !ok || !f.validPath(orig) {
+		return time.Time{}, "", false
+	}
+
+	t, err := time.Parse(trashTimeFormat, ts)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return t, orig, true
+}
+
+// trashDir returns the name of the trash directory.
+func (f *S3FS) trashDir() string {
+	p := f.trashPrefix
+	if p == "" {
+		p = defaultTrashPrefix
+	}
+	return strings.Trim(p, f.delim)
+}
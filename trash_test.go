@@ -0,0 +1,138 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+
+	cl := newMemClient("a.txt", "dir/b.txt", "dir/c.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithTrashPrefix("bin/"))
+
+	before := time.Now().UTC()
+
+	if err := fsys.SoftDelete(ctx, "a.txt"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if err := fsys.SoftDelete(ctx, "dir"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/c.txt"} {
+		if _, ok := cl.objects[key]; ok {
+			t.Errorf("expected %s to be deleted", key)
+		}
+	}
+
+	trashed, err := fsys.ListTrash(ctx)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	sort.Slice(trashed, func(i, j int) bool { return trashed[i].OriginalPath < trashed[j].OriginalPath })
+
+	var orig []string
+	for _, o := range trashed {
+		orig = append(orig, o.OriginalPath)
+
+		if !strings.HasPrefix(o.Path, "bin/") || !strings.HasSuffix(o.Path, "/"+o.OriginalPath) {
+			t.Errorf("unexpected trash path %s", o.Path)
+		}
+
+		if o.DeletedAt.Before(before.Truncate(time.Second)) || o.Size != int64(len("content")) {
+			t.Errorf("unexpected trashed object %+v", o)
+		}
+	}
+
+	if want := "a.txt dir/b.txt dir/c.txt"; strings.Join(orig, " ") != want {
+		t.Fatalf("want %s; got %v", want, orig)
+	}
+
+	if err := fsys.RestoreFromTrash(ctx, trashed[0].Path); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	// restore the whole directory.
+	dir := strings.TrimSuffix(trashed[1].Path, "/b.txt")
+	if err := fsys.RestoreFromTrash(ctx, dir); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(cl.objects) != 3 {
+		t.Errorf("expected 3 objects; got %v", cl.objects)
+	}
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/c.txt"} {
+		if cl.objects[key] != "content" {
+			t.Errorf("expected %s to be restored", key)
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		if err := fsys.SoftDelete(ctx, "bin/x"); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("expected ErrInvalid; got %v", err)
+		}
+
+		if err := fsys.SoftDelete(ctx, "missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected ErrNotExist; got %v", err)
+		}
+
+		if err := fsys.RestoreFromTrash(ctx, "a.txt"); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("expected ErrInvalid; got %v", err)
+		}
+	})
+}
+
+func TestSoftDeleteDelimiter(t *testing.T) {
+	ctx := context.Background()
+
+	cl := newMemClient("a.txt", "dir:b.txt")
+	fsys := s3fs.New(cl, "test", s3fs.WithDelimiter(":"))
+
+	before := time.Now().UTC()
+
+	for _, name := range []string{"a.txt", "dir"} {
+		if err := fsys.SoftDelete(ctx, name); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+	}
+
+	trashed, err := fsys.ListTrash(ctx)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	sort.Slice(trashed, func(i, j int) bool { return trashed[i].OriginalPath < trashed[j].OriginalPath })
+
+	var orig []string
+	for _, o := range trashed {
+		orig = append(orig, o.OriginalPath)
+
+		if o.DeletedAt.Before(before.Truncate(time.Second)) {
+			t.Errorf("unexpected deletion time %v", o.DeletedAt)
+		}
+
+		if err := fsys.RestoreFromTrash(ctx, o.Path); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+	}
+
+	if want := "a.txt dir:b.txt"; strings.Join(orig, " ") != want {
+		t.Fatalf("want %s; got %v", want, orig)
+	}
+
+	for _, key := range []string{"a.txt", "dir:b.txt"} {
+		if cl.objects[key] != "content" {
+			t.Errorf("expected %s to be restored", key)
+		}
+	}
+}
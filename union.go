@@ -0,0 +1,169 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+var (
+	_ fs.ReadDirFS = (*unionFS)(nil)
+	_ fs.StatFS    = (*unionFS)(nil)
+)
+
+// NewUnionFS returns a filesystem that merges fses. Files are served by
+// the first of fses in which they exist, so earlier filesystems override
+// later ones; this also applies if a name is a file in one filesystem and a
+// directory in another. Directories list the entries of every filesystem
+// in which they exist, with entries of earlier filesystems replacing the
+// entries of later ones with the same name.
+//
+// It can be used e.g. to compose an fstest.MapFS with local overrides and
+// an *S3FS used in production.
+func NewUnionFS(fses ...fs.FS) fs.FS {
+	return &unionFS{fses: fses}
+}
+
+type unionFS struct {
+	fses []fs.FS
+}
+
+func (u *unionFS) Open(name string) (fs.File, error) {
+	for _, fsys := range u.fses {
+		f, err := fsys.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		if fi.IsDir() {
+			return &unionDir{File: f, fsys: u, name: name}, nil
+		}
+		return f, nil
+	}
+
+	return nil, &fs.PathError{
+		Op:   "open",
+		Path: name,
+		Err:  fs.ErrNotExist,
+	}
+}
+
+func (u *unionFS) Stat(name string) (fs.FileInfo, error) {
+	fi, _, err := u.stat(name)
+	return fi, err
+}
+
+// stat returns the file info of name from the first filesystem in which it
+// exists, and the index of that filesystem.
+func (u *unionFS) stat(name string) (fs.FileInfo, int, error) {
+	for i, fsys := range u.fses {
+		fi, err := fs.Stat(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return fi, i, err
+	}
+
+	return nil, 0, &fs.PathError{
+		Op:   "stat",
+		Path: name,
+		Err:  fs.ErrNotExist,
+	}
+}
+
+func (u *unionFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fi, i, err := u.stat(name)
+	if err != nil {
+		if pathErr, ok := err.(*fs.PathError); ok {
+			err = pathErr.Err
+		}
+		return nil, &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if !fi.IsDir() {
+		return nil, &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  errNotDir,
+		}
+	}
+
+	var (
+		names = make(map[string]bool)
+		des   []fs.DirEntry
+	)
+	for j, fsys := range u.fses[i:] {
+		entries, err := fs.ReadDir(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			// name is hidden by the directory of an earlier filesystem,
+			// if it is a file here.
+			if fi, serr := fs.Stat(fsys, name); j > 0 && serr == nil && !fi.IsDir() {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, de := range entries {
+			if !names[de.Name()] {
+				names[de.Name()] = true
+				des = append(des, de)
+			}
+		}
+	}
+
+	if des == nil {
+		des = []fs.DirEntry{}
+	}
+
+	sort.Slice(des, func(i, j int) bool {
+		return des[i].Name() < des[j].Name()
+	})
+	return des, nil
+}
+
+// unionDir is a directory opened with unionFS. Its entries are read with
+// unionFS.ReadDir on the first call of ReadDir.
+type unionDir struct {
+	fs.File
+	fsys *unionFS
+	name string
+	des  []fs.DirEntry
+	read bool
+}
+
+func (d *unionDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		des, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.des, d.read = des, true
+	}
+
+	des := d.des
+	if n > 0 {
+		if len(des) == 0 {
+			return nil, io.EOF
+		}
+		des = des[:min(n, len(des))]
+	}
+	d.des = d.des[len(des):]
+	return des, nil
+}
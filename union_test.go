@@ -0,0 +1,97 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestUnionFS(t *testing.T) {
+	first := fstest.MapFS{
+		"a.txt":         {Data: []byte("first")},
+		"x":             {Data: []byte("first")},
+		"d/first.txt":   {Data: []byte("first")},
+		"dir/first.txt": {Data: []byte("first")},
+	}
+	second := fstest.MapFS{
+		"a.txt":          {Data: []byte("second")},
+		"b.txt":          {Data: []byte("second")},
+		"x/hidden.txt":   {Data: []byte("second")},
+		"d":              {Data: []byte("second")},
+		"dir/second.txt": {Data: []byte("second")},
+	}
+	remote := s3fs.New(newMemClient("b.txt", "c.txt", "dir/remote.txt"), "test")
+
+	fsys := s3fs.NewUnionFS(first, second, remote)
+
+	if err := fstest.TestFS(fsys, "a.txt", "b.txt", "c.txt", "x", "d/first.txt", "dir/first.txt", "dir/second.txt", "dir/remote.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []struct {
+		name string
+		want string
+	}{
+		{name: "a.txt", want: "first"},
+		{name: "b.txt", want: "second"},
+		{name: "c.txt", want: "content"},
+		{name: "x", want: "first"},
+		{name: "dir/remote.txt", want: "content"},
+	}
+
+	for _, f := range fixtures {
+		data, err := fs.ReadFile(fsys, f.name)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != f.want {
+			t.Errorf("%s: want %q; got %q", f.name, f.want, data)
+		}
+	}
+
+	dirs := []struct {
+		name string
+		want []string
+	}{
+		{name: ".", want: []string{"a.txt", "b.txt", "c.txt", "d/", "dir/", "x"}},
+		{name: "d", want: []string{"first.txt"}},
+		{name: "dir", want: []string{"first.txt", "remote.txt", "second.txt"}},
+	}
+
+	for _, d := range dirs {
+		des, err := fs.ReadDir(fsys, d.name)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		var names []string
+		for _, de := range des {
+			name := de.Name()
+			if de.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+
+		if !slices.Equal(names, d.want) {
+			t.Errorf("%s: want %v; got %v", d.name, d.want, names)
+		}
+	}
+
+	if _, err := fs.ReadDir(fsys, "x"); err == nil {
+		t.Error("expected an error reading a file overriding a directory")
+	}
+
+	if _, err := fs.Stat(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist; got %v", err)
+	}
+
+	if _, err := fs.ReadDir(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist; got %v", err)
+	}
+}
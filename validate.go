@@ -0,0 +1,42 @@
+package s3fs
+
+import (
+	"context"
+	"testing/fstest"
+	"time"
+)
+
+// ValidateFS checks that fsys behaves like a correct fs.FS by running
+// fstest.TestFS against it. knownFiles lists files that are expected to
+// exist in the bucket. It is useful in integration test suites and
+// deployment smoke tests.
+//
+// fstest.TestFS lists and reads every file, so it should be run on small
+// buckets or prefixes only. All S3 calls are made with ctx; if ctx is done
+// before the validation finishes, the remaining calls fail and ctx.Err()
+// is returned.
+//
+// Some failures point at the S3 backend rather than a bug in this package:
+//
+//   - ModTime mismatches between ReadDir and Stat: some S3 compatible
+//     stores (e.g. MinIO) return modification times with sub-second
+//     precision from ListObjectsV2 but not from GetObject or HeadObject.
+//   - Missing or unexpected entries when objects are modified
+//     concurrently, since listings are not atomic.
+//   - Objects with keys that are not valid fs paths (e.g. "a//b" or
+//     "dir/") cannot be represented and are reported as inconsistent.
+func ValidateFS(ctx context.Context, fsys *S3FS, knownFiles ...string) error {
+	err := fstest.TestFS(fsys.WithContext(ctx), knownFiles...)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// ValidateFSWithTimeout is like ValidateFS but gives up after timeout.
+func ValidateFSWithTimeout(ctx context.Context, timeout time.Duration, fsys *S3FS, knownFiles ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return ValidateFS(ctx, fsys, knownFiles...)
+}
@@ -0,0 +1,72 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestValidateFS(t *testing.T) {
+	files := []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "z.txt"}
+	fsys := s3fs.New(newMemClient(files...), "test")
+
+	if err := s3fs.ValidateFSWithTimeout(context.Background(), time.Minute, fsys, files...); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+}
+
+func TestValidateFSCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	files := []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "z.txt"}
+	cl := &cancelingListClient{memClient: newMemClient(files...), cancel: cancel}
+
+	if err := s3fs.ValidateFS(ctx, s3fs.New(cl, "test"), files...); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want %v; got %v", context.Canceled, err)
+	}
+
+	// ValidateFS must not return before the validation stops.
+	calls := cl.calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	if n := cl.calls.Load(); n != calls {
+		t.Errorf("expected no S3 calls after ValidateFS returned; got %d", n-calls)
+	}
+}
+
+// cancelingListClient calls cancel after the first listing and counts
+// all calls, including the ones made with a canceled context, which are
+// slowed down so that a leaked validation keeps making them.
+type cancelingListClient struct {
+	*memClient
+	cancel context.CancelFunc
+	calls  atomic.Int64
+}
+
+func (c *cancelingListClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.add(ctx)
+	defer c.cancel()
+	return c.memClient.ListObjectsV2(ctx, in, optFns...)
+}
+
+func (c *cancelingListClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.add(ctx)
+	return c.memClient.GetObject(ctx, in, optFns...)
+}
+
+func (c *cancelingListClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.add(ctx)
+	return c.memClient.HeadObject(ctx, in, optFns...)
+}
+
+func (c *cancelingListClient) add(ctx context.Context) {
+	c.calls.Add(1)
+	if ctx.Err() != nil {
+		time.Sleep(time.Millisecond)
+	}
+}
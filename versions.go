@@ -0,0 +1,358 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// VersionInfo describes a single version of an object or a delete marker
+// in a versioned bucket.
+type VersionInfo struct {
+	Key            string
+	VersionID      string
+	LastModified   time.Time
+	Size           int64
+	ETag           string
+	IsLatest       bool
+	IsDeleteMarker bool
+	StorageClass   string
+}
+
+type listObjectVersionsClient interface {
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+}
+
+// OpenVersion opens the version versionID of the named file in a
+// versioned bucket, e.g. to read a historical snapshot of it. Seeking and
+// ReadAt read the same version.
+//
+// If versionID refers to a delete marker, the returned error wraps
+// fs.ErrNotExist.
+func (f *S3FS) OpenVersion(name, versionID string) (fs.File, error) {
+	if !f.validPath(name) || name == "." || versionID == "" {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	out, err := call(f, f.ctx, f.cl.GetObject, &s3.GetObjectInput{
+		Bucket:       &f.bucket,
+		Key:          ptr(f.key(name)),
+		VersionId:    &versionID,
+		ChecksumMode: f.checksumMode(),
+	})
+	if err != nil {
+		// S3 responds with 405 Method Not Allowed to GET requests for
+		// delete markers.
+		if f.isNotFoundErr(err) || hasStatusCode(err, http.StatusMethodNotAllowed) {
+			err = fs.ErrNotExist
+		}
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	meta := metaFromGetObject(out)
+	meta.VersionID = versionID
+
+	fi := &fileInfo{
+		name:    name,
+		size:    derefInt64(out.ContentLength),
+		modTime: derefTime(out.LastModified),
+		delim:   f.delim,
+		sys:     meta,
+	}
+
+	file := &file{
+		fsys:       f,
+		name:       name,
+		ReadCloser: f.progressBody(f.limitRead(f.checksumBody(name, out)), out.ContentLength),
+		stat:       func() (fs.FileInfo, error) { return fi, nil },
+		size:       fi.size,
+		eTag:       derefString(out.ETag),
+		versionID:  &versionID,
+	}
+
+	if err := f.decompressFile(file, out.ContentEncoding); err != nil {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if !f.readSeeker {
+		return fileNoSeek{file}, nil
+	}
+	return file, nil
+}
+
+// ListVersions returns all versions and delete markers of the named file,
+// sorted from the newest to the oldest. It fails with fs.ErrNotExist if
+// the file has no versions.
+//
+// Unlike ListObjectVersionsIterator, it lists a single file, so versions of
+// other files whose names start with name are skipped.
+func (f *S3FS) ListVersions(ctx context.Context, name string) ([]VersionInfo, error) {
+	vs, err := f.listVersions(ctx, name)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "listversions",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return vs, nil
+}
+
+func (f *S3FS) listVersions(ctx context.Context, name string) ([]VersionInfo, error) {
+	if !f.validPath(name) || name == "." {
+		return nil, fs.ErrInvalid
+	}
+
+	cl, ok := f.cl.(listObjectVersionsClient)
+	if !ok {
+		return nil, errUnsupported("ListObjectVersions")
+	}
+
+	var (
+		key                        = f.key(name)
+		vs                         []VersionInfo
+		keyMarker, versionIDMarker *string
+	)
+	for {
+		out, err := call(f, ctx, cl.ListObjectVersions, &s3.ListObjectVersionsInput{
+			Bucket:          &f.bucket,
+			Prefix:          &key,
+			Delimiter:       &f.delim,
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range mergeVersions(out) {
+			if v.Key == key {
+				v.Key = name
+				vs = append(vs, v)
+			}
+		}
+
+		if out.IsTruncated == nil || !*out.IsTruncated {
+			break
+		}
+		keyMarker, versionIDMarker = out.NextKeyMarker, out.NextVersionIdMarker
+	}
+
+	if len(vs) == 0 {
+		return nil, fs.ErrNotExist
+	}
+
+	sort.SliceStable(vs, func(i, j int) bool {
+		return vs[i].LastModified.After(vs[j].LastModified)
+	})
+	return vs, nil
+}
+
+// DeleteVersion permanently deletes the version versionID of the named
+// file. Deleting a delete marker restores the previous version of the
+// file.
+func (f *S3FS) DeleteVersion(ctx context.Context, name, versionID string) error {
+	if err := f.deleteVersion(ctx, name, versionID); err != nil {
+		return &fs.PathError{
+			Op:   "deleteversion",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+func (f *S3FS) deleteVersion(ctx context.Context, name, versionID string) error {
+	if !f.validPath(name) || name == "." || versionID == "" {
+		return fs.ErrInvalid
+	}
+
+	cl, ok := f.cl.(deleteObjectClient)
+	if !ok {
+		return errUnsupported("DeleteObject")
+	}
+
+	defer f.invalidate(name, false)
+
+	_, err := call(f, ctx, cl.DeleteObject, &s3.DeleteObjectInput{
+		Bucket:    &f.bucket,
+		Key:       ptr(f.key(name)),
+		VersionId: &versionID,
+	})
+	if f.isNotFoundErr(err) {
+		return fs.ErrNotExist
+	}
+	return err
+}
+
+// VersionIterator lazily enumerates object versions and delete markers.
+// Pages are fetched from S3 only when the buffered entries are exhausted,
+// so memory usage stays bounded regardless of the number of versions.
+//
+// Entries are returned in the order S3 lists them: by key, and for each key
+// from the newest to the oldest version, with delete markers interleaved
+// according to their LastModified time.
+type VersionIterator struct {
+	ctx    context.Context
+	fsys   *S3FS
+	prefix string
+
+	keyMarker       *string
+	versionIDMarker *string
+	buf             []VersionInfo
+	cur             VersionInfo
+	done            bool
+	err             error
+}
+
+// ListObjectVersionsIterator returns an iterator over all versions of the
+// objects whose names start with prefix. Unlike in fs paths, prefix does
+// not have to end at a directory boundary.
+//
+// The Client has to implement ListObjectVersions; otherwise Err reports
+// an error wrapping errors.ErrUnsupported.
+func (f *S3FS) ListObjectVersionsIterator(ctx context.Context, prefix string) *VersionIterator {
+	return &VersionIterator{
+		ctx:    ctx,
+		fsys:   f,
+		prefix: f.prefix + prefix,
+	}
+}
+
+// Next advances the iterator to the next entry. It returns false when there
+// are no more entries or an error occurred.
+func (it *VersionIterator) Next() bool {
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		if err := it.fetch(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				it.err = err
+			}
+			it.done = true
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Entry returns the current entry.
+func (it *VersionIterator) Entry() VersionInfo { return it.cur }
+
+// Err returns the first error encountered by the iterator.
+func (it *VersionIterator) Err() error { return it.err }
+
+// Close stops the iteration. Calling Next after Close returns false.
+func (it *VersionIterator) Close() error {
+	it.done = true
+	it.buf = nil
+	return nil
+}
+
+func (it *VersionIterator) fetch() error {
+	cl, ok := it.fsys.cl.(listObjectVersionsClient)
+	if !ok {
+		return errUnsupported("ListObjectVersions")
+	}
+
+	out, err := call(it.fsys, it.ctx, cl.ListObjectVersions, &s3.ListObjectVersionsInput{
+		Bucket:          &it.fsys.bucket,
+		Prefix:          &it.prefix,
+		KeyMarker:       it.keyMarker,
+		VersionIdMarker: it.versionIDMarker,
+	})
+	if err != nil {
+		return err
+	}
+
+	it.buf = mergeVersions(out)
+	for i := range it.buf {
+		it.buf[i].Key = strings.TrimPrefix(it.buf[i].Key, it.fsys.prefix)
+	}
+	it.keyMarker = out.NextKeyMarker
+	it.versionIDMarker = out.NextVersionIdMarker
+
+	if out.IsTruncated == nil || !*out.IsTruncated {
+		it.done = true
+	}
+
+	if it.done && len(it.buf) == 0 {
+		return io.EOF
+	}
+	return nil
+}
+
+// mergeVersions merges versions and delete markers of a single page.
+// Both lists are already sorted by key and then by LastModified in
+// descending order, so a linear merge keeps that order.
+func mergeVersions(out *s3.ListObjectVersionsOutput) []VersionInfo {
+	vs := make([]VersionInfo, 0, len(out.Versions)+len(out.DeleteMarkers))
+
+	var i, j int
+	for i < len(out.Versions) || j < len(out.DeleteMarkers) {
+		if j == len(out.DeleteMarkers) {
+			vs = append(vs, objectVersionInfo(out.Versions[i]))
+			i++
+			continue
+		}
+
+		dm := deleteMarkerInfo(out.DeleteMarkers[j])
+		if i < len(out.Versions) {
+			v := objectVersionInfo(out.Versions[i])
+			if v.Key < dm.Key || (v.Key == dm.Key && !v.LastModified.Before(dm.LastModified)) {
+				vs = append(vs, v)
+				i++
+				continue
+			}
+		}
+
+		vs = append(vs, dm)
+		j++
+	}
+	return vs
+}
+
+func objectVersionInfo(v types.ObjectVersion) VersionInfo {
+	return VersionInfo{
+		Key:          derefString(v.Key),
+		VersionID:    derefString(v.VersionId),
+		LastModified: derefTime(v.LastModified),
+		Size:         derefInt64(v.Size),
+		ETag:         derefString(v.ETag),
+		IsLatest:     derefBool(v.IsLatest),
+		StorageClass: string(v.StorageClass),
+	}
+}
+
+func deleteMarkerInfo(dm types.DeleteMarkerEntry) VersionInfo {
+	return VersionInfo{
+		Key:            derefString(dm.Key),
+		VersionID:      derefString(dm.VersionId),
+		LastModified:   derefTime(dm.LastModified),
+		IsLatest:       derefBool(dm.IsLatest),
+		IsDeleteMarker: true,
+	}
+}
@@ -0,0 +1,283 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestListObjectVersionsIterator(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cl := &versionsClient{
+		outs: []s3.ListObjectVersionsOutput{
+			{
+				Versions: []types.ObjectVersion{
+					{Key: ptr("file.txt"), VersionId: ptr("v3"), IsLatest: ptr(true), LastModified: ptr(t0.Add(3 * time.Hour)), Size: ptr[int64](3)},
+				},
+				DeleteMarkers: []types.DeleteMarkerEntry{
+					{Key: ptr("file.txt"), VersionId: ptr("dm"), IsLatest: ptr(false), LastModified: ptr(t0.Add(2 * time.Hour))},
+				},
+				IsTruncated:         ptr(true),
+				NextKeyMarker:       ptr("file.txt"),
+				NextVersionIdMarker: ptr("dm"),
+			},
+			{
+				Versions: []types.ObjectVersion{
+					{Key: ptr("file.txt"), VersionId: ptr("v2"), IsLatest: ptr(false), LastModified: ptr(t0.Add(time.Hour)), Size: ptr[int64](2)},
+					{Key: ptr("file.txt"), VersionId: ptr("v1"), IsLatest: ptr(false), LastModified: ptr(t0), Size: ptr[int64](1)},
+				},
+				IsTruncated: ptr(false),
+			},
+		},
+	}
+
+	it := s3fs.New(cl, "test").ListObjectVersionsIterator(context.Background(), "file.txt")
+	defer it.Close()
+
+	type entry struct {
+		id             string
+		isLatest       bool
+		isDeleteMarker bool
+	}
+
+	var got []entry
+	for it.Next() {
+		v := it.Entry()
+		got = append(got, entry{v.VersionID, v.IsLatest, v.IsDeleteMarker})
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	expected := []entry{
+		{"v3", true, false},
+		{"dm", false, true},
+		{"v2", false, false},
+		{"v1", false, false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("want %v; got %v", expected, got)
+	}
+
+	if len(cl.markers) != 2 || cl.markers[1] != "file.txt/dm" {
+		t.Errorf("expected second page to continue from file.txt/dm; got %v", cl.markers)
+	}
+
+	t.Run("unsupported client", func(t *testing.T) {
+		it := s3fs.New(&mockClient{}, "test").ListObjectVersionsIterator(context.Background(), "")
+		if it.Next() {
+			t.Fatal("expected Next to return false")
+		}
+
+		if !errors.Is(it.Err(), errors.ErrUnsupported) {
+			t.Errorf("want %v; got %v", errors.ErrUnsupported, it.Err())
+		}
+	})
+}
+
+type versionsClient struct {
+	s3fs.Client
+	outs    []s3.ListObjectVersionsOutput
+	markers []string
+}
+
+func (c *versionsClient) ListObjectVersions(ctx context.Context, in *s3.ListObjectVersionsInput, _ ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	var marker string
+	if in.KeyMarker != nil {
+		marker = *in.KeyMarker + "/" + *in.VersionIdMarker
+	}
+	c.markers = append(c.markers, marker)
+
+	out := c.outs[0]
+	c.outs = c.outs[1:]
+	return &out, nil
+}
+
+func TestListVersions(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cl := &versionsClient{
+		outs: []s3.ListObjectVersionsOutput{
+			{
+				Versions: []types.ObjectVersion{
+					{Key: ptr("file.txt"), VersionId: ptr("v2"), IsLatest: ptr(false), LastModified: ptr(t0.Add(time.Hour))},
+				},
+				DeleteMarkers: []types.DeleteMarkerEntry{
+					{Key: ptr("file.txt"), VersionId: ptr("dm"), IsLatest: ptr(true), LastModified: ptr(t0.Add(2 * time.Hour))},
+				},
+				IsTruncated:         ptr(true),
+				NextKeyMarker:       ptr("file.txt"),
+				NextVersionIdMarker: ptr("v2"),
+			},
+			{
+				Versions: []types.ObjectVersion{
+					{Key: ptr("file.txt"), VersionId: ptr("v1"), IsLatest: ptr(false), LastModified: ptr(t0)},
+					{Key: ptr("file.txt.bak"), VersionId: ptr("b1"), IsLatest: ptr(true), LastModified: ptr(t0.Add(3 * time.Hour))},
+				},
+				IsTruncated: ptr(false),
+			},
+		},
+	}
+
+	vs, err := s3fs.New(cl, "test").ListVersions(context.Background(), "file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	var ids []string
+	for _, v := range vs {
+		if v.Key != "file.txt" {
+			t.Errorf("unexpected key %q", v.Key)
+		}
+		ids = append(ids, v.VersionID)
+	}
+
+	if expected := []string{"dm", "v2", "v1"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("want %v; got %v", expected, ids)
+	}
+
+	if !vs[0].IsDeleteMarker || !vs[0].IsLatest {
+		t.Errorf("expected the latest version to be a delete marker; got %+v", vs[0])
+	}
+
+	cl = &versionsClient{outs: []s3.ListObjectVersionsOutput{{IsTruncated: ptr(false)}}}
+	if _, err := s3fs.New(cl, "test").ListVersions(context.Background(), "file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestDeleteVersion(t *testing.T) {
+	cl := &deleteVersionClient{memClient: newMemClient("file.txt")}
+	fsys := s3fs.New(cl, "test")
+
+	if err := fsys.DeleteVersion(context.Background(), "file.txt", "v1"); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if expected := []string{"file.txt/v1"}; !reflect.DeepEqual(cl.deleted, expected) {
+		t.Errorf("want %v; got %v", expected, cl.deleted)
+	}
+
+	if err := fsys.DeleteVersion(context.Background(), "file.txt", ""); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+	}
+}
+
+type deleteVersionClient struct {
+	*memClient
+	deleted []string
+}
+
+func (c *deleteVersionClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.deleted = append(c.deleted, *in.Key+"/"+*in.VersionId)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestOpenVersion(t *testing.T) {
+	cl := &versionedClient{
+		memClient: newMemClient("config.json"),
+		versions: map[string]string{
+			"v1": "old",
+			"v2": "older",
+		},
+		deleteMarkers: map[string]bool{"v3": true},
+	}
+
+	for _, opts := range [][]s3fs.Option{nil, {s3fs.WithReadSeeker}} {
+		fsys := s3fs.New(cl, "test", opts...)
+
+		f, err := fsys.OpenVersion("config.json", "v1")
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != "old" {
+			t.Errorf("want %q; got %q", "old", data)
+		}
+
+		p := make([]byte, 2)
+		if _, err := f.(io.ReaderAt).ReadAt(p, 1); err != nil || string(p) != "ld" {
+			t.Errorf("want %q; got %q (%v)", "ld", p, err)
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if m, ok := fi.Sys().(*s3fs.S3ObjectMeta); !ok || m.VersionID != "v1" {
+			t.Errorf("expected version v1; got %+v", fi.Sys())
+		}
+		f.Close()
+
+		if _, err := fsys.OpenVersion("config.json", "v3"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		if _, err := fsys.OpenVersion("config.json", "v4"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		calls := cl.versionCalls
+		if _, err := fsys.OpenVersion("config.json", ""); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+
+		if cl.versionCalls != calls {
+			t.Errorf("expected no GetObject calls; got %d", cl.versionCalls-calls)
+		}
+	}
+}
+
+// versionedClient serves old versions of objects from versions and
+// responds to delete markers like S3 does.
+type versionedClient struct {
+	*memClient
+	versions      map[string]string
+	deleteMarkers map[string]bool
+	versionCalls  int
+}
+
+func (c *versionedClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if in.VersionId == nil {
+		return c.memClient.GetObject(ctx, in, optFns...)
+	}
+
+	c.versionCalls++
+
+	versionID := *in.VersionId
+	if c.deleteMarkers[versionID] {
+		return nil, responseError(http.StatusMethodNotAllowed)
+	}
+
+	data, ok := c.versions[versionID]
+	if !ok {
+		return nil, responseError(http.StatusNotFound)
+	}
+
+	cl := newMemClient()
+	cl.objects[*in.Key] = data
+
+	out, err := cl.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.VersionId = &versionID
+	return out, nil
+}
@@ -0,0 +1,279 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WalkDir walks the file tree rooted at root like fs.WalkDir, calling fn
+// for each file and directory in the same order, but it lists the whole
+// tree with a single recursive listing instead of one listing per
+// directory.
+//
+// The listing is read completely before fn is called for any entry
+// other than root, so memory use grows with the number of objects under
+// root. Errors from the listing are passed to fn with the root directory.
+func (f *S3FS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	fi, err := f.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+		if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+			return nil
+		}
+		return err
+	}
+
+	rootEntry := fs.FileInfoToDirEntry(fi)
+	if err := fn(root, rootEntry, nil); err != nil || !fi.IsDir() {
+		if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := f.walkEntries(root)
+	if err != nil {
+		err = fn(root, rootEntry, &fs.PathError{Op: "readdir", Path: root, Err: err})
+		if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+			return nil
+		}
+		return err
+	}
+
+	// skip is the prefix of the entries skipped because fn returned
+	// fs.SkipDir.
+	var skip string
+	for _, e := range entries {
+		if skip != "" && strings.HasPrefix(e.name, skip) {
+			continue
+		}
+		skip = ""
+
+		err := fn(e.name, e, nil)
+		switch {
+		case err == nil:
+		case errors.Is(err, fs.SkipAll):
+			return nil
+		case errors.Is(err, fs.SkipDir):
+			if e.IsDir() {
+				skip = e.name + f.delim
+				continue
+			}
+
+			// Skip the remaining files in the parent directory.
+			parent := f.parent(e.name)
+			if parent == root {
+				return nil
+			}
+			skip = parent + f.delim
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// walkEntries lists all files under the directory root and returns them
+// together with their parent directories, in the order of fs.WalkDir.
+func (f *S3FS) walkEntries(root string) ([]dirEntry, error) {
+	prefix := ""
+	if root != "." {
+		prefix = root + f.delim
+	}
+
+	var (
+		entries []dirEntry
+		dirs    = make(map[string]bool)
+	)
+	err := f.listAll(f.ctx, prefix, func(name string, o types.Object) error {
+		isDir := strings.HasSuffix(name, f.delim)
+		name = strings.TrimSuffix(name, f.delim)
+		if !f.validPath(name) || name == root {
+			return nil
+		}
+
+		for dir := f.parent(name); dir != root && !dirs[dir]; dir = f.parent(dir) {
+			dirs[dir] = true
+		}
+
+		if isDir {
+			// a directory marker, e.g. created by the S3 console.
+			dirs[name] = true
+			return nil
+		}
+
+		entries = append(entries, f.fileEntry(name, o))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for dir := range dirs {
+		entries = append(entries, dirEntry{
+			fileInfo: fileInfo{
+				name:  dir,
+				mode:  fs.ModeDir,
+				delim: f.delim,
+			},
+		})
+	}
+
+	// fs.WalkDir visits the entries of every directory sorted by name,
+	// which is not the order of the keys, e.g. "a.txt" < "a/b.txt".
+	sort.Slice(entries, func(i, j int) bool {
+		return walkLess(entries[i].name, entries[j].name, f.delim)
+	})
+	return entries, nil
+}
+
+// walkLess compares a and b element by element, so that every directory
+// sorts before its contents, and its contents sort by name.
+func walkLess(a, b, delim string) bool {
+	for {
+		ae, arest, aok := strings.Cut(a, delim)
+		be, brest, bok := strings.Cut(b, delim)
+		if ae != be {
+			return ae < be
+		}
+
+		if !aok || !bok {
+			return !aok && bok
+		}
+		a, b = arest, brest
+	}
+}
+
+// parent returns the directory that contains name.
+func (f *S3FS) parent(name string) string {
+	if i := strings.LastIndex(name, f.delim); i >= 0 {
+		return name[:i]
+	}
+	return "."
+}
+
+// WalkDirN walks the file tree rooted at root like fs.WalkDir, calling fn
+// for each file and directory in the same order, but it lists up to workers
+// directories concurrently. It is meant for wide trees, e.g. one directory
+// per day, where WalkDir would still have to read the whole tree first.
+//
+// Once a directory is read, all its subdirectories are listed in the
+// background, even the ones that fn later skips with fs.SkipDir, while fn
+// is called for the entries in order. The listings that are still running
+// are canceled when WalkDirN returns.
+func (f *S3FS) WalkDirN(ctx context.Context, root string, fn fs.WalkDirFunc, workers int) error {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &walker{
+		fsys: f.WithContext(ctx),
+		ctx:  ctx,
+		fn:   fn,
+		sem:  make(chan struct{}, workers),
+	}
+
+	fi, err := w.fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		var l *listing
+		if fi.IsDir() {
+			l = w.list(root)
+		}
+		err = w.walk(root, fs.FileInfoToDirEntry(fi), l)
+	}
+
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walker walks a tree for WalkDirN.
+type walker struct {
+	fsys *S3FS
+	ctx  context.Context
+	fn   fs.WalkDirFunc
+	sem  chan struct{}
+}
+
+// listing is the result of reading a directory in the background. done is
+// closed once des and err are set.
+type listing struct {
+	des  []fs.DirEntry
+	err  error
+	done chan struct{}
+}
+
+// list starts reading the directory name once one of the workers is free.
+func (w *walker) list(name string) *listing {
+	l := &listing{done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+
+		select {
+		case w.sem <- struct{}{}:
+		case <-w.ctx.Done():
+			l.err = w.ctx.Err()
+			return
+		}
+		defer func() { <-w.sem }()
+
+		l.des, l.err = w.fsys.ReadDir(name)
+	}()
+	return l
+}
+
+// walk is fs.WalkDir for the entry d of name, whose listing is l if it is
+// a directory.
+func (w *walker) walk(name string, d fs.DirEntry, l *listing) error {
+	if err := w.fn(name, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	<-l.done
+	if l.err != nil {
+		if err := w.fn(name, d, l.err); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	subs := make([]*listing, len(l.des))
+	for i, de := range l.des {
+		if de.IsDir() {
+			subs[i] = w.list(w.join(name, de.Name()))
+		}
+	}
+
+	for i, de := range l.des {
+		if err := w.walk(w.join(name, de.Name()), de, subs[i]); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) join(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return dir + w.fsys.delim + name
+}
@@ -0,0 +1,176 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWalkDir(t *testing.T) {
+	files := []string{
+		"a.txt",
+		"a/b.txt",
+		"a/c/d.txt",
+		"a-b/e.txt",
+		"dir/f.txt",
+		"dir/g.txt",
+		"dir/sub/h.txt",
+		"z.txt",
+	}
+
+	mapfs := fstest.MapFS{}
+	for _, f := range files {
+		mapfs[f] = &fstest.MapFile{Data: []byte("content")}
+	}
+
+	walk := func(walkDir func(string, fs.WalkDirFunc) error, root string, skip map[string]error) ([]string, error) {
+		var visited []string
+		err := walkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			visited = append(visited, fmt.Sprintf("%s:%v", path, d.IsDir()))
+			return skip[path]
+		})
+		return visited, err
+	}
+
+	fixtures := []struct {
+		desc string
+		root string
+		skip map[string]error
+	}{
+		{desc: "all", root: "."},
+		{desc: "subdir", root: "dir"},
+		{desc: "file", root: "a.txt"},
+		{desc: "skip dir", root: ".", skip: map[string]error{"a": fs.SkipDir}},
+		{desc: "skip file", root: ".", skip: map[string]error{"dir/f.txt": fs.SkipDir}},
+		{desc: "skip root file", root: ".", skip: map[string]error{"a.txt": fs.SkipDir}},
+		{desc: "skip all", root: ".", skip: map[string]error{"a-b/e.txt": fs.SkipAll}},
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.desc, func(t *testing.T) {
+			cl := newMemClient(files...)
+			fsys := s3fs.New(cl, "test")
+
+			expected, _ := walk(func(root string, fn fs.WalkDirFunc) error {
+				return fs.WalkDir(mapfs, root, fn)
+			}, fixture.root, fixture.skip)
+
+			calls := cl.count("ListObjectsV2")
+
+			got, err := walk(fsys.WalkDir, fixture.root, fixture.skip)
+			if err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("want %v; got %v", expected, got)
+			}
+
+			if n := cl.count("ListObjectsV2") - calls; n > 2 {
+				t.Errorf("expected at most 2 ListObjectsV2 calls; got %d", n)
+			}
+
+			got, err = walk(func(root string, fn fs.WalkDirFunc) error {
+				return fsys.WalkDirN(context.Background(), root, fn, 3)
+			}, fixture.root, fixture.skip)
+			if err != nil {
+				t.Fatal("WalkDirN: expected err to be nil; got ", err)
+			}
+
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("WalkDirN: want %v; got %v", expected, got)
+			}
+		})
+	}
+
+	t.Run("not exist", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(files...), "test")
+
+		err := fsys.WalkDir("missing", func(path string, d fs.DirEntry, err error) error {
+			return err
+		})
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(files...), "test")
+
+		errStop := errors.New("stop")
+		err := fsys.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+			if path == "dir" {
+				return errStop
+			}
+			return err
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("want %v; got %v", errStop, err)
+		}
+	})
+}
+
+func TestWalkDirNWorkers(t *testing.T) {
+	var files []string
+	for i := 0; i < 20; i++ {
+		files = append(files, fmt.Sprintf("logs/%02d/file.txt", i))
+	}
+
+	cl := &inFlightClient{memClient: newMemClient(files...)}
+	fsys := s3fs.New(cl, "test")
+
+	var visited []string
+	err := fsys.WalkDirN(context.Background(), "logs", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		return nil
+	}, 4)
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if len(visited) != 41 {
+		t.Errorf("want 41 entries; got %d", len(visited))
+	}
+
+	if peak := cl.max.Load(); peak > 4 || peak < 2 {
+		t.Errorf("expected between 2 and 4 concurrent listings; got %d", peak)
+	}
+}
+
+// inFlightClient records the largest number of concurrent ListObjectsV2
+// calls.
+type inFlightClient struct {
+	*memClient
+	n, max atomic.Int32
+}
+
+func (c *inFlightClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	n := c.n.Add(1)
+	defer c.n.Add(-1)
+
+	for {
+		peak := c.max.Load()
+		if n <= peak || c.max.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	return c.memClient.ListObjectsV2(ctx, in, optFns...)
+}
@@ -0,0 +1,241 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var _ WriteFS = (*S3FS)(nil)
+
+// WriteFS is a filesystem that can create and overwrite files.
+type WriteFS interface {
+	fs.FS
+
+	// WriteFile writes data to the named file, replacing it if it exists.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+
+	// OpenForWrite opens the named file for writing. The file is not
+	// created until the returned writer is closed.
+	OpenForWrite(name string) (io.WriteCloser, error)
+}
+
+type putObjectClient interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// WithACL sets the canned ACL applied to objects written with this fs.
+func WithACL(acl types.ObjectCannedACL) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.acl = acl
+	})
+}
+
+// WithSSEKMS encrypts objects written and copied with this fs using the
+// AWS KMS key keyID. An empty keyID uses the AWS managed key of the bucket.
+func WithSSEKMS(keyID string) Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.sse = sseOptions{
+			algorithm: types.ServerSideEncryptionAwsKms,
+			kmsKeyID:  keyID,
+		}
+	})
+}
+
+// WithSSES3 encrypts objects written and copied with this fs using keys
+// managed by S3 (AES256).
+func WithSSES3() Option {
+	return optionFunc(func(fsys *S3FS) {
+		fsys.sse = sseOptions{algorithm: types.ServerSideEncryptionAes256}
+	})
+}
+
+// sseOptions is the server-side encryption applied to PutObject and
+// CopyObject calls.
+type sseOptions struct {
+	algorithm types.ServerSideEncryption
+	kmsKeyID  string
+}
+
+func (o sseOptions) keyID() *string {
+	if o.kmsKeyID == "" {
+		return nil
+	}
+	return &o.kmsKeyID
+}
+
+// WriteFile implements WriteFS. It uploads data with a single PutObject
+// call. The Content-Type is derived from the extension of name.
+//
+// perm is ignored, because S3 has no file modes. Access to the object
+// has to be configured with WithACL or with a bucket policy.
+func (f *S3FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := f.putObject(f.ctx, name, data); err != nil {
+		return &fs.PathError{
+			Op:   "write",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// OpenForWrite implements WriteFS. Bytes written to the returned writer are
+// buffered in memory and uploaded with PutObject when it is closed.
+//
+// Once more than the threshold set with WithMultipartThreshold is written,
+// the file is uploaded with a multipart upload instead: parts are uploaded
+// concurrently while writing, and the upload is completed on Close. If
+// any part fails, the upload is aborted and Write or Close return the
+// error.
+func (f *S3FS) OpenForWrite(name string) (io.WriteCloser, error) {
+	if !f.validPath(name) || name == "." {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if _, ok := f.cl.(putObjectClient); !ok {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  errUnsupported("PutObject"),
+		}
+	}
+
+	return &writeFile{fsys: f, name: name}, nil
+}
+
+func (f *S3FS) putObject(ctx context.Context, name string, data []byte, optFns ...func(*s3.Options)) error {
+	if !f.validPath(name) || name == "." {
+		return fs.ErrInvalid
+	}
+
+	cl, ok := f.cl.(putObjectClient)
+	if !ok {
+		return errUnsupported("PutObject")
+	}
+
+	defer f.invalidate(name, false)
+
+	_, err := call(f, ctx, cl.PutObject, &s3.PutObjectInput{
+		Bucket:        &f.bucket,
+		Key:           ptr(f.key(name)),
+		Body:          f.uploadProgressBody(f.limitWrite(ctx, bytes.NewReader(data)), int64(len(data))),
+		ContentLength: ptr(int64(len(data))),
+		ContentType:   ptr(contentType(name)),
+		ACL:           f.acl,
+
+		ServerSideEncryption: f.sse.algorithm,
+		SSEKMSKeyId:          f.sse.keyID(),
+	}, optFns...)
+	return err
+}
+
+func contentType(name string) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+type writeFile struct {
+	fsys   *S3FS
+	name   string
+	buf    bytes.Buffer
+	upload *multipartUpload
+	err    error
+	closed bool
+}
+
+func (w *writeFile) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, &fs.PathError{
+			Op:   "write",
+			Path: w.name,
+			Err:  fs.ErrClosed,
+		}
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.buf.Write(p)
+
+	if err := w.flush(); err != nil {
+		w.err = &fs.PathError{
+			Op:   "write",
+			Path: w.name,
+			Err:  err,
+		}
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// flush starts a multipart upload once the buffer exceeds the threshold
+// and sends every complete part.
+func (w *writeFile) flush() error {
+	if w.upload == nil {
+		if !w.fsys.shouldUseMultipart(int64(w.buf.Len())) {
+			return nil
+		}
+
+		u, err := w.fsys.createMultipartUpload(w.name)
+		if err != nil {
+			return err
+		}
+		w.upload = u
+	}
+
+	size := w.fsys.uploadPartSize()
+	for w.buf.Len() >= size {
+		if err := w.upload.send(w.buf.Next(size)); err != nil {
+			return w.upload.abort(err)
+		}
+	}
+	return nil
+}
+
+func (w *writeFile) Close() error {
+	if w.closed {
+		return &fs.PathError{
+			Op:   "close",
+			Path: w.name,
+			Err:  fs.ErrClosed,
+		}
+	}
+	w.closed = true
+
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.upload != nil {
+		if err := w.upload.complete(w.buf.Bytes()); err != nil {
+			return &fs.PathError{
+				Op:   "write",
+				Path: w.name,
+				Err:  err,
+			}
+		}
+		return nil
+	}
+
+	if err := w.fsys.putObject(w.fsys.ctx, w.name, w.buf.Bytes()); err != nil {
+		return &fs.PathError{
+			Op:   "write",
+			Path: w.name,
+			Err:  err,
+		}
+	}
+	return nil
+}
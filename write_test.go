@@ -0,0 +1,149 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jszwec/s3fs/v2"
+)
+
+func TestWriteFile(t *testing.T) {
+	cl := newMemClient()
+	fsys := s3fs.New(cl, "test")
+
+	fixtures := []struct {
+		name        string
+		contentType string
+	}{
+		{name: "dir/data.json", contentType: "application/json"},
+		{name: "data", contentType: "application/octet-stream"},
+	}
+
+	for _, fixture := range fixtures {
+		if err := fsys.WriteFile(fixture.name, []byte("data"), 0644); err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		data, err := fs.ReadFile(fsys, fixture.name)
+		if err != nil {
+			t.Fatal("expected err to be nil; got ", err)
+		}
+
+		if string(data) != "data" {
+			t.Errorf("want %q; got %q", "data", data)
+		}
+
+		if ct := cl.contentTypes[fixture.name]; ct != fixture.contentType {
+			t.Errorf("%s: want content type %q; got %q", fixture.name, fixture.contentType, ct)
+		}
+	}
+
+	if err := fsys.WriteFile("../x", nil, 0644); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+	}
+
+	unsupported := s3fs.New(struct{ s3fs.Client }{cl}, "test")
+	if err := unsupported.WriteFile("x", nil, 0644); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("want %v; got %v", errors.ErrUnsupported, err)
+	}
+}
+
+func TestOpenForWrite(t *testing.T) {
+	cl := newMemClient()
+	fsys := s3fs.New(cl, "test")
+
+	w, err := fsys.OpenForWrite("file.txt")
+	if err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	io.WriteString(w, "con")
+	io.WriteString(w, "tent")
+
+	if _, err := fs.Stat(fsys, "file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected file not to exist before Close; got %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal("expected err to be nil; got ", err)
+	}
+
+	if cl.objects["file.txt"] != "content" {
+		t.Errorf("want %q; got %q", "content", cl.objects["file.txt"])
+	}
+
+	if n := cl.count("PutObject"); n != 1 {
+		t.Errorf("expected 1 PutObject call; got %d", n)
+	}
+
+	if _, err := io.WriteString(w, "more"); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("want %v; got %v", fs.ErrClosed, err)
+	}
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	fixtures := []struct {
+		desc      string
+		opt       s3fs.Option
+		algorithm types.ServerSideEncryption
+		keyID     string
+	}{
+		{desc: "none"},
+		{desc: "sse-s3", opt: s3fs.WithSSES3(), algorithm: types.ServerSideEncryptionAes256},
+		{desc: "sse-kms", opt: s3fs.WithSSEKMS("key"), algorithm: types.ServerSideEncryptionAwsKms, keyID: "key"},
+		{desc: "sse-kms default key", opt: s3fs.WithSSEKMS(""), algorithm: types.ServerSideEncryptionAwsKms},
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.desc, func(t *testing.T) {
+			cl := &sseClient{memClient: newMemClient()}
+
+			var opts []s3fs.Option
+			if fixture.opt != nil {
+				opts = append(opts, fixture.opt)
+			}
+			fsys := s3fs.New(cl, "test", opts...)
+
+			if err := fsys.WriteFile("file.txt", []byte("data"), 0644); err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			if err := fsys.Rename("file.txt", "new.txt"); err != nil {
+				t.Fatal("expected err to be nil; got ", err)
+			}
+
+			want := []string{
+				string(fixture.algorithm) + "|" + fixture.keyID,
+				string(fixture.algorithm) + "|" + fixture.keyID,
+			}
+			if !reflect.DeepEqual(cl.sse, want) {
+				t.Errorf("want %q; got %q", want, cl.sse)
+			}
+		})
+	}
+}
+
+// sseClient records the server-side encryption of PutObject and CopyObject
+// calls.
+type sseClient struct {
+	*memClient
+	sse []string
+}
+
+func (c *sseClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.sse = append(c.sse, string(in.ServerSideEncryption)+"|"+aws.ToString(in.SSEKMSKeyId))
+	return c.memClient.PutObject(ctx, in, optFns...)
+}
+
+func (c *sseClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	c.sse = append(c.sse, string(in.ServerSideEncryption)+"|"+aws.ToString(in.SSEKMSKeyId))
+	return c.memClient.CopyObject(ctx, in, optFns...)
+}